@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"os"
+	"time"
+
+	"github.com/PraveenPrabhuT/rds/internal/clipboard"
+	"github.com/spf13/cobra"
+)
+
+var (
+	clipboardBackend string
+	clipboardAfter   int
+)
+
+// clipboardClearCmd is started detached by --copy when clipboard.clear_after
+// is configured. It waits, then clears the clipboard if it still holds the
+// copied text.
+var clipboardClearCmd = &cobra.Command{
+	Use:    "clipboard-clear",
+	Short:  "Clear the clipboard after a delay (used internally by --copy)",
+	Hidden: true,
+	Args:   cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		time.Sleep(time.Duration(clipboardAfter) * time.Second)
+		return clipboard.Clear(clipboardBackend, os.Getenv(clipboard.ChecksumEnv))
+	},
+}
+
+func init() {
+	clipboardClearCmd.Flags().StringVar(&clipboardBackend, "backend", "", "Clipboard backend to clear")
+	clipboardClearCmd.Flags().IntVar(&clipboardAfter, "after", 0, "Seconds to wait before clearing")
+	rootCmd.AddCommand(clipboardClearCmd)
+}
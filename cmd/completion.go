@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/PraveenPrabhuT/rds/internal/core"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/spf13/cobra"
+)
+
+// completeInstances returns a ValidArgsFunction that completes the first
+// positional argument with cached RDS instance IDs. Read replicas are
+// skipped when primaryOnly is set.
+func completeInstances(primaryOnly bool) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(c *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		ctx := context.Background()
+		rFlag, _ := c.Flags().GetString("region")
+
+		opts := []func(*config.LoadOptions) error{
+			config.WithSharedConfigProfile(awsProfile),
+		}
+		if rFlag != "" {
+			opts = append(opts, config.WithRegion(rFlag))
+		} else if envRegion := os.Getenv("AWS_REGION"); envRegion != "" {
+			opts = append(opts, config.WithRegion(envRegion))
+		} else {
+			opts = append(opts, config.WithRegion(defaultAWSRegion))
+		}
+
+		cfg, err := config.LoadDefaultConfig(ctx, opts...)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+
+		instances, err := core.GetInstancesWithCache(ctx, cfg, awsProfile)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+
+		var completions []string
+		for _, inst := range instances {
+			if primaryOnly && inst.SourceID != "" {
+				continue
+			}
+			if strings.HasPrefix(inst.ID, toComplete) {
+				completions = append(completions, fmt.Sprintf("%s\t%s", inst.ID, inst.Size))
+			}
+		}
+		return completions, cobra.ShellCompDirectiveNoFileComp
+	}
+}
@@ -1,25 +1,25 @@
 package cmd
 
 import (
-	"context"
 	"fmt"
-	"os"
 	"strings"
 
 	"github.com/PraveenPrabhuT/rds/internal/connect"
-	"github.com/PraveenPrabhuT/rds/internal/core"
-	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/spf13/cobra"
 )
 
 var (
-	lastConnected bool
-	connectHost   string
-	connectPort   int
-	connectDB     string
-	connectURL    string
-	showJDBC      bool
-	copyJDBC      bool
+	lastConnected      bool
+	connectHost        string
+	connectPort        int
+	connectDB          string
+	connectURL         string
+	connectAs          string
+	connectEnvPassword bool
+	connectClient      string
+	connectReadOnly    bool
+	showJDBC           bool
+	copyJDBC           bool
 )
 
 var connectCmd = &cobra.Command{
@@ -30,7 +30,25 @@ and establishes a connection using pgcli, psql, or a native Go fallback.
 It requires an active Pritunl VPN connection matching the AWS Profile.
 
 Supports connecting by instance name, RDS host endpoint, or JDBC URL.
-Credentials are always resolved from Secrets Manager automatically.`,
+Credentials are always resolved from Secrets Manager automatically.
+
+Use --client to pick pgcli, psql, usql or the native client explicitly (a
+per-profile default can be set under profiles.<name>.client in the config
+file). Arguments after -- are passed to the client, and the client's exit
+code becomes the exit code of rds.
+
+Profiles marked read_only in the config file (or --read-only) start sessions
+with default_transaction_read_only=on, through PGOPTIONS for psql/pgcli. On
+protected profiles with writes allowed, the native client asks for the
+database name before DROP, TRUNCATE and DELETE/UPDATE without WHERE.
+
+Sessions are labelled with the target's environment, taken from the
+instance's environment (or env) tag or profiles.<name>.environment, e.g.
+[PROD ackoprod/pricing-db]. The label colors the native prompt and is
+added to psql (via a generated PSQLRC) and pgcli prompts. Colors and
+warnings per environment are set under environments in the config file;
+prod sessions print a banner with the AWS identity and target first.
+Set NO_COLOR to disable colors.`,
 	Example: `  # Interactive selection
   rds connect
 
@@ -43,6 +61,9 @@ Credentials are always resolved from Secrets Manager automatically.`,
   # Connect to a specific database
   rds connect my-instance --db myapp
 
+  # Connect as the read-only user created by 'rds db create'
+  rds connect my-instance --db pricing --as ro_v1
+
   # Connect by RDS host endpoint
   rds connect --host my-rds.abc.ap-south-1.rds.amazonaws.com
 
@@ -52,10 +73,18 @@ Credentials are always resolved from Secrets Manager automatically.`,
   # JDBC URL with private IP: pass instance id if DNS cannot map the IP
   rds connect --url 'jdbc:postgresql://172.31.x.x:5432/db' my-rds-instance-id
 
+  # Run a single query with psql and forward its flags
+  rds connect my-instance --client psql -- -c 'select 1' --csv
+
   # Get JDBC URL for app config and copy to clipboard
   rds connect my-instance --jdbc --copy`,
-	Args: cobra.MaximumNArgs(1),
-	Run:  runConnect,
+	Args: func(c *cobra.Command, args []string) error {
+		if n := instanceArgCount(c, args); n > 1 {
+			return fmt.Errorf("accepts at most 1 instance, received %d", n)
+		}
+		return nil
+	},
+	Run: runConnect,
 }
 
 func init() {
@@ -64,46 +93,17 @@ func init() {
 	connectCmd.Flags().IntVar(&connectPort, "port", 5432, "PostgreSQL port")
 	connectCmd.Flags().StringVarP(&connectDB, "db", "d", "postgres", "Database name to connect to")
 	connectCmd.Flags().StringVar(&connectURL, "url", "", "JDBC URL to connect (jdbc:postgresql://host[:port][/database])")
+	connectCmd.Flags().StringVar(&connectAs, "as", "", "Connect as a user provisioned by 'db create' (e.g. ro_v1, rw_v2, migration); requires --db")
+	connectCmd.Flags().BoolVar(&connectEnvPassword, "env-password", false, "Pass the password via PGPASSWORD instead of a temporary PGPASSFILE")
+	connectCmd.Flags().StringVar(&connectClient, "client", "", "Client to launch: "+strings.Join(connect.Clients, "|")+" (default from config, else auto)")
+	connectCmd.Flags().BoolVar(&connectReadOnly, "read-only", false, readOnlyUsage)
 	connectCmd.Flags().BoolVar(&showJDBC, "jdbc", false, "Print JDBC URL after resolving credentials")
-	connectCmd.Flags().BoolVar(&copyJDBC, "copy", false, "Copy JDBC URL to clipboard (use with --jdbc)")
-
-	connectCmd.ValidArgsFunction = func(c *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-		if len(args) > 0 {
-			return nil, cobra.ShellCompDirectiveNoFileComp
-		}
-
-		ctx := context.Background()
-		rFlag, _ := c.Flags().GetString("region")
-
-		opts := []func(*config.LoadOptions) error{
-			config.WithSharedConfigProfile(awsProfile),
-		}
-		if rFlag != "" {
-			opts = append(opts, config.WithRegion(rFlag))
-		} else if envRegion := os.Getenv("AWS_REGION"); envRegion != "" {
-			opts = append(opts, config.WithRegion(envRegion))
-		} else {
-			opts = append(opts, config.WithRegion(defaultAWSRegion))
-		}
-
-		cfg, err := config.LoadDefaultConfig(ctx, opts...)
-		if err != nil {
-			return nil, cobra.ShellCompDirectiveError
-		}
-
-		instances, err := core.GetInstancesWithCache(ctx, cfg, awsProfile)
-		if err != nil {
-			return nil, cobra.ShellCompDirectiveError
-		}
+	connectCmd.Flags().BoolVar(&copyJDBC, "copy", false, "Copy JDBC URL to clipboard (use with --jdbc; backend and auto-clear set in config)")
 
-		var completions []string
-		for _, inst := range instances {
-			if strings.HasPrefix(inst.ID, toComplete) {
-				completions = append(completions, fmt.Sprintf("%s\t%s", inst.ID, inst.Size))
-			}
-		}
-		return completions, cobra.ShellCompDirectiveNoFileComp
-	}
+	connectCmd.RegisterFlagCompletionFunc("client", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return connect.Clients, cobra.ShellCompDirectiveNoFileComp
+	})
+	connectCmd.ValidArgsFunction = completeInstances(false)
 
 	rootCmd.AddCommand(connectCmd)
 }
@@ -121,13 +121,19 @@ func runConnect(c *cobra.Command, args []string) {
 		Port:          connectPort,
 		DB:            connectDB,
 		JDBCURL:       connectURL,
+		As:            connectAs,
+		EnvPassword:   connectEnvPassword,
+		Client:        connectClient,
+		ReadOnly:      readOnlyFlag(c, connectReadOnly),
 		ShowJDBC:      showJDBC,
 		CopyJDBC:      copyJDBC,
 		Args:          args,
 	}
+	if dash := c.ArgsLenAtDash(); dash >= 0 {
+		opts.Args, opts.ClientArgs = args[:dash], args[dash:]
+	}
 
 	if err := connect.Run(ctx, opts); err != nil {
-		fmt.Printf("❌ %v\n", err)
-		os.Exit(1)
+		exitWithError(err)
 	}
 }
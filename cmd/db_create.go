@@ -1,26 +1,41 @@
 package cmd
 
 import (
-	"context"
 	"fmt"
 	"os"
 	"strings"
 
-	"github.com/PraveenPrabhuT/rds/internal/core"
 	"github.com/PraveenPrabhuT/rds/internal/createdb"
-	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/spf13/cobra"
 )
 
 var (
 	createHost          string
 	createPort          int
-	createSchema        string
+	createSchemas       []string
 	createDefaultDB     string
 	migrationConnLimit  int
 	rwConnLimit         int
 	roConnLimit         int
+	createTemplate      string
+	createExtensions    []string
+	createShowTemplate  bool
 	createDryRun        bool
+	createPlan          bool
+	createResume        string
+	createRollback      bool
+	createYes           bool
+	createStoreSecrets  bool
+	createNoStore       bool
+	createOutput        string
+	createShowPasswords bool
+	createEmit          []string
+	createEmitKeys      []string
+	createSecretStore   string
+	createTeam          string
+	createKMSKeyID      string
+	createSecretDesc    string
+	createPerUserSecret bool
 	createForce         bool
 )
 
@@ -35,6 +50,80 @@ a standard set of users and permissions matching the organization's playbook:
   - Read-write users (v1 and v2 with role inheritance)
   - IAM database user for AWS IAM authentication
 
+--template replaces this layout with a YAML role template declaring each
+role's suffix, connection limit, login method (password or iam), database
+and schema privileges, privilege set for tables, sequences and functions,
+inherited roles and default privileges. --show-template prints the built-in
+template as a starting point. Templates can also declare extensions to
+create, database parameters (ALTER DATABASE ... SET) and per-role
+parameters (ALTER ROLE ... IN DATABASE ... SET), such as statement_timeout
+and search_path; --extension adds extensions to any template. Extensions
+the instance does not offer (pg_available_extensions) are reported before
+anything is changed.
+
+--schema (repeatable) provisions several schemas instead of public. Each is
+created owned by the migration user, and every role gets its privilege set,
+default privileges and IAM grants in each of them. A template's schemas list
+can give a role another privilege set in one schema, or none:
+
+  schemas:
+    - name: app
+    - name: audit
+      privileges: {ro_v1: none, rw_v1: read-only}
+
+The summary and --dry-run print the resulting access matrix of users and
+schemas.
+
+The instance is inspected first (databases, roles, memberships, database and
+schema ACLs, object grants and default privileges) and only the missing or
+different statements are applied, so rerunning create completes a partially
+provisioned database. --plan prints these changes without applying them.
+Existing users keep their passwords.
+
+Each run is journaled under the cache directory (db-runs/<run-id>.json, no
+passwords). If a step fails, --resume <run-id> continues it with the recorded
+instance, database and template; users the failed run created get new
+passwords, since theirs were never shown or stored. --rollback-on-failure
+instead offers to drop what the run created: the users' privileges and
+objects, the users in reverse order, then the database.
+
+Passwords are sent to the server only as SCRAM-SHA-256 verifiers computed
+locally, so they never appear in server logs; --dry-run output and error
+messages redact them too.
+
+The database and schema names must be valid PostgreSQL identifiers (letters,
+digits, _ and $, not starting with a digit or pg_) and every derived
+username must fit in 63 bytes; all identifiers are quoted in the generated
+SQL.
+
+For CI and automation: --yes answers every question (including storing the
+credentials, unless --no-store-secrets), --store-secrets/--no-store-secrets
+decide storage without asking, and --output json prints a report of the
+plan, step results, created users and secret ARN to stdout while progress
+goes to stderr. The report includes passwords only with --show-passwords.
+When stdin is not a terminal, create never prompts: it fails up front if
+the instance (--host), database name or confirmation is not given.
+
+Credentials are stored in the <db>/<instance>/psql secret as a
+{"username": "password"} map; when it already exists, the new passwords are
+added to it as a new version. Secrets are tagged with db, instance and
+--team, and encrypted with --kms-key-id when given. --per-user-secrets also
+stores each user in <db>/<instance>/users/<username> in the JSON shape of
+RDS-managed secrets (username, password, host, port, dbname, engine).
+
+--emit KIND=PATH (repeatable) also writes the new credentials to a file,
+readable only by you:
+
+  k8s-secret       Kubernetes Secret manifest with the passwords
+  external-secret  ExternalSecret manifest reading the passwords from the
+                   stored Secrets Manager secret (needs the secret stored)
+  dotenv           KEY=value lines
+  json             a JSON object of the same keys
+
+The keys are DB_HOST, DB_PORT, DB_NAME and <PREFIX>_USERNAME and
+<PREFIX>_PASSWORD per user, where PREFIX is the upper-cased template role
+name (MIGRATION, RO_V1, ...) unless --emit-key ROLE=PREFIX renames it.
+
 Superuser credentials are fetched automatically from AWS Secrets Manager.
 Read replicas are filtered out from the instance picker.`,
 	Example: `  # Interactive instance and database name selection
@@ -49,8 +138,35 @@ Read replicas are filtered out from the instance picker.`,
   # Dry run to preview SQL without executing
   rds db create Pricing --dry-run
 
-  # Use custom schema and connection limits
-  rds db create Pricing --schema app --migration-conn-limit 20`,
+  # Show what differs from the template on the live instance
+  rds db create Pricing --plan
+
+  # Use a custom schema and connection limits
+  rds db create Pricing --schema app --migration-conn-limit 20
+
+  # Provision several schemas with the same roles
+  rds db create Pricing -s app -s audit -s reporting
+
+  # Continue a run that failed halfway
+  rds db create --resume 20261018-142501-a1b2c3
+
+  # Undo a run if any step fails
+  rds db create Pricing --rollback-on-failure
+
+  # Run from a pipeline and capture the result
+  rds db create Pricing --host my-rds.abc.ap-south-1.rds.amazonaws.com \
+    --yes --store-secrets --output json > result.json
+
+  # Write a Kubernetes ExternalSecret and a local .env file
+  rds db create Pricing --store-secrets --emit external-secret=pricing-es.yaml \
+    --emit dotenv=.env --emit-key migration=PRICING_OWNER
+
+  # Install extensions as part of provisioning
+  rds db create Pricing --extension pg_stat_statements --extension pgcrypto
+
+  # Provision the roles declared in a template
+  rds db create --show-template > roles.yaml
+  rds db create Pricing --template roles.yaml`,
 	Args: cobra.MaximumNArgs(1),
 	Run:  runDBCreate,
 }
@@ -58,59 +174,87 @@ Read replicas are filtered out from the instance picker.`,
 func init() {
 	dbCreateCmd.Flags().StringVar(&createHost, "host", "", "RDS host endpoint (bypasses instance picker)")
 	dbCreateCmd.Flags().IntVar(&createPort, "port", 5432, "PostgreSQL port")
-	dbCreateCmd.Flags().StringVarP(&createSchema, "schema", "s", "public", "Target schema for privilege grants")
+	dbCreateCmd.Flags().StringArrayVarP(&createSchemas, "schema", "s", nil, "Schema to provision, owned by the migration user (repeatable; default public)")
 	dbCreateCmd.Flags().StringVar(&createDefaultDB, "default-db", "postgres", "Initial database for user creation")
 	dbCreateCmd.Flags().IntVar(&migrationConnLimit, "migration-conn-limit", 10, "Connection limit for migration user")
 	dbCreateCmd.Flags().IntVar(&rwConnLimit, "rw-conn-limit", 10, "Connection limit for read-write users")
 	dbCreateCmd.Flags().IntVar(&roConnLimit, "ro-conn-limit", 10, "Connection limit for read-only users")
+	dbCreateCmd.Flags().StringVar(&createTemplate, "template", "", "YAML role template (default: built-in template)")
+	dbCreateCmd.Flags().StringArrayVar(&createExtensions, "extension", nil, "Create this extension in the database (repeatable)")
+	dbCreateCmd.Flags().BoolVar(&createShowTemplate, "show-template", false, "Print the built-in role template and exit")
 	dbCreateCmd.Flags().BoolVar(&createDryRun, "dry-run", false, "Print SQL statements without executing")
+	dbCreateCmd.Flags().BoolVar(&createPlan, "plan", false, "Show the changes needed on the instance without applying them")
+	dbCreateCmd.Flags().StringVar(&createResume, "resume", "", "Resume the failed run with this ID")
+	dbCreateCmd.Flags().BoolVar(&createRollback, "rollback-on-failure", false, "Offer to drop what the run created if a step fails")
+	dbCreateCmd.Flags().BoolVarP(&createYes, "yes", "y", false, "Answer yes to every question")
+	dbCreateCmd.Flags().BoolVar(&createStoreSecrets, "store-secrets", false, "Store the new credentials in AWS Secrets Manager without asking")
+	dbCreateCmd.Flags().BoolVar(&createNoStore, "no-store-secrets", false, "Do not store the new credentials in AWS Secrets Manager")
+	dbCreateCmd.Flags().StringVarP(&createOutput, "output", "o", createdb.OutputText, "Output format: "+strings.Join(createdb.OutputFormats, "|"))
+	dbCreateCmd.Flags().BoolVar(&createShowPasswords, "show-passwords", false, "Include the new passwords in --output json")
+	dbCreateCmd.Flags().StringVar(&createTeam, "team", "", "Team tag of the stored secrets")
+	dbCreateCmd.Flags().StringVar(&createKMSKeyID, "kms-key-id", "", "KMS key ID, ARN or alias to encrypt the stored secrets with")
+	dbCreateCmd.Flags().StringVar(&createSecretDesc, "secret-description", "", "Description of the stored secret (default: generated)")
+	dbCreateCmd.Flags().BoolVar(&createPerUserSecret, "per-user-secrets", false, "Also store each user in its own RDS-shaped secret")
+	dbCreateCmd.Flags().StringArrayVar(&createEmit, "emit", nil, "Write the new credentials as KIND=PATH: "+strings.Join(createdb.EmitKinds, "|")+" (repeatable)")
+	dbCreateCmd.Flags().StringArrayVar(&createEmitKeys, "emit-key", nil, "Key prefix of a template role in --emit output as ROLE=PREFIX (repeatable)")
+	dbCreateCmd.Flags().StringVar(&createSecretStore, "secret-store", createdb.DefaultSecretStore, "ClusterSecretStore of --emit external-secret manifests")
 	dbCreateCmd.Flags().BoolVarP(&createForce, "force", "f", false, "Skip existing database/users instead of failing")
 
-	dbCreateCmd.ValidArgsFunction = func(c *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-		if len(args) > 0 {
-			return nil, cobra.ShellCompDirectiveNoFileComp
-		}
-
-		ctx := context.Background()
-		rFlag, _ := c.Flags().GetString("region")
-
-		loadOpts := []func(*config.LoadOptions) error{
-			config.WithSharedConfigProfile(awsProfile),
+	for _, limit := range []string{"migration-conn-limit", "rw-conn-limit", "ro-conn-limit"} {
+		// Custom templates set conn_limit per role.
+		dbCreateCmd.MarkFlagsMutuallyExclusive("template", limit)
+	}
+	dbCreateCmd.MarkFlagsMutuallyExclusive("plan", "dry-run")
+	dbCreateCmd.MarkFlagsMutuallyExclusive("store-secrets", "no-store-secrets")
+	for _, secret := range []string{"team", "kms-key-id", "secret-description", "per-user-secrets"} {
+		dbCreateCmd.MarkFlagsMutuallyExclusive("no-store-secrets", secret)
+	}
+	// The dry run SQL is for reading; --plan reports the changes as JSON.
+	dbCreateCmd.MarkFlagsMutuallyExclusive("output", "dry-run")
+	for _, recorded := range []string{"template", "extension", "host", "port", "schema", "default-db", "dry-run"} {
+		// A resumed run uses the settings in its journal.
+		dbCreateCmd.MarkFlagsMutuallyExclusive("resume", recorded)
+	}
+	dbCreateCmd.RegisterFlagCompletionFunc("resume", completeFailedRuns)
+	dbCreateCmd.RegisterFlagCompletionFunc("output", func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+		return createdb.OutputFormats, cobra.ShellCompDirectiveNoFileComp
+	})
+	dbCreateCmd.RegisterFlagCompletionFunc("emit", func(_ *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if strings.Contains(toComplete, "=") {
+			return nil, cobra.ShellCompDirectiveDefault
 		}
-		if rFlag != "" {
-			loadOpts = append(loadOpts, config.WithRegion(rFlag))
-		} else if envRegion := os.Getenv("AWS_REGION"); envRegion != "" {
-			loadOpts = append(loadOpts, config.WithRegion(envRegion))
-		} else {
-			loadOpts = append(loadOpts, config.WithRegion(defaultAWSRegion))
+		kinds := make([]string, len(createdb.EmitKinds))
+		for i, k := range createdb.EmitKinds {
+			kinds[i] = k + "="
 		}
+		return kinds, cobra.ShellCompDirectiveNoSpace
+	})
+	dbCreateCmd.MarkFlagFilename("template", "yaml", "yml")
 
-		cfg, err := config.LoadDefaultConfig(ctx, loadOpts...)
-		if err != nil {
-			return nil, cobra.ShellCompDirectiveError
-		}
+	// The argument is the new database's name: nothing to complete.
+	dbCreateCmd.ValidArgsFunction = cobra.NoFileCompletions
 
-		instances, err := core.GetInstancesWithCache(ctx, cfg, awsProfile)
-		if err != nil {
-			return nil, cobra.ShellCompDirectiveError
-		}
+	dbCmd.AddCommand(dbCreateCmd)
+}
 
-		var completions []string
-		for _, inst := range instances {
-			if inst.SourceID != "" {
-				continue
-			}
-			if strings.HasPrefix(inst.ID, toComplete) {
-				completions = append(completions, fmt.Sprintf("%s\t%s", inst.ID, inst.Size))
-			}
+// completeFailedRuns offers the IDs of runs that can be resumed.
+func completeFailedRuns(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	runs, _ := createdb.ListJournals()
+	var ids []string
+	for _, r := range runs {
+		if r.Status == createdb.RunFailed || r.Status == createdb.RunRunning {
+			ids = append(ids, r.ID+"\t"+r.Database+" on "+r.Instance)
 		}
-		return completions, cobra.ShellCompDirectiveNoFileComp
 	}
-
-	dbCmd.AddCommand(dbCreateCmd)
+	return ids, cobra.ShellCompDirectiveNoFileComp
 }
 
 func runDBCreate(c *cobra.Command, args []string) {
+	if createShowTemplate {
+		os.Stdout.Write(createdb.DefaultTemplateYAML())
+		return
+	}
+
 	ctx := c.Context()
 	region := resolveRegion(awsRegion)
 
@@ -125,18 +269,42 @@ func runDBCreate(c *cobra.Command, args []string) {
 		DBName:             dbName,
 		Host:               createHost,
 		Port:               createPort,
-		Schema:             createSchema,
+		Schemas:            createSchemas,
 		DefaultDB:          createDefaultDB,
+		Template:           createTemplate,
+		Extensions:         createExtensions,
 		MigrationConnLimit: migrationConnLimit,
 		RWConnLimit:        rwConnLimit,
 		ROConnLimit:        roConnLimit,
 		DryRun:             createDryRun,
+		Plan:               createPlan,
+		Resume:             createResume,
+		RollbackOnFailure:  createRollback,
+		Yes:                createYes,
+		Output:             createOutput,
+		ShowPasswords:      createShowPasswords,
+		Emit:               createEmit,
+		EmitKeys:           createEmitKeys,
+		SecretStore:        createSecretStore,
 		Force:              createForce,
-		Args:               args,
+		Secrets: createdb.SecretOptions{
+			Team:        createTeam,
+			KMSKeyID:    createKMSKeyID,
+			Description: createSecretDesc,
+			PerUser:     createPerUserSecret,
+		},
+	}
+
+	switch {
+	case createStoreSecrets:
+		opts.StoreSecrets = &createStoreSecrets
+	case createNoStore:
+		no := false
+		opts.StoreSecrets = &no
 	}
 
 	if err := createdb.Run(ctx, opts); err != nil {
-		fmt.Printf("❌ %v\n", err)
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
 		os.Exit(1)
 	}
 }
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/PraveenPrabhuT/rds/internal/connect"
+	"github.com/spf13/cobra"
+)
+
+var (
+	envLast    bool
+	envHost    string
+	envPort    int
+	envDB      string
+	envURL     string
+	envAs      string
+	envShell   string
+	envSSLMode string
+	envUnset   bool
+)
+
+var envCmd = &cobra.Command{
+	Use:   "env [rds-identifier]",
+	Short: "Print shell export statements for an RDS PostgreSQL instance",
+	Long: `Env resolves the instance and credentials the same way connect does and
+prints statements that export PGHOST, PGPORT, PGUSER, PGPASSWORD, PGDATABASE,
+PGSSLMODE and DATABASE_URL. Evaluate the output to set up the current shell.
+
+The shell syntax defaults to the one in $SHELL (bash, zsh or fish).
+Use --unset to print the statements that remove the variables again.`,
+	Example: `  # Point the current shell at the pricing database
+  eval "$(rds env my-instance --db pricing)"
+
+  # Same, as the read-only user, for fish
+  rds env my-instance --db pricing --as ro_v1 --shell fish | source
+
+  # Reuse the last instance or a JDBC URL
+  eval "$(rds env -l)"
+  eval "$(rds env --url 'jdbc:postgresql://my-db.internal.example.com/myapp')"
+
+  # Clean up
+  eval "$(rds env --unset)"`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  runEnv,
+}
+
+func init() {
+	envCmd.Flags().BoolVarP(&envLast, "last", "l", false, "Use the last used RDS instance")
+	envCmd.Flags().StringVar(&envHost, "host", "", "RDS host endpoint (bypasses instance picker)")
+	envCmd.Flags().IntVar(&envPort, "port", 5432, "PostgreSQL port")
+	envCmd.Flags().StringVarP(&envDB, "db", "d", "postgres", "Database name")
+	envCmd.Flags().StringVar(&envURL, "url", "", "JDBC URL (jdbc:postgresql://host[:port][/database])")
+	envCmd.Flags().StringVar(&envAs, "as", "", "Use a user provisioned by 'db create' (e.g. ro_v1, rw_v2, migration); requires --db")
+	envCmd.Flags().StringVar(&envShell, "shell", connect.DetectShell(os.Getenv("SHELL")), "Shell syntax: "+strings.Join(connect.Shells, "|"))
+	envCmd.Flags().StringVar(&envSSLMode, "sslmode", connect.DefaultSSLMode, "Value for PGSSLMODE")
+	envCmd.Flags().BoolVar(&envUnset, "unset", false, "Print statements that unset the variables")
+
+	envCmd.RegisterFlagCompletionFunc("shell", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return connect.Shells, cobra.ShellCompDirectiveNoFileComp
+	})
+	envCmd.ValidArgsFunction = completeInstances(false)
+
+	rootCmd.AddCommand(envCmd)
+}
+
+func runEnv(c *cobra.Command, args []string) {
+	opts := connect.EnvOptions{
+		Options: connect.Options{
+			Profile:       awsProfile,
+			Region:        resolveRegion(awsRegion),
+			LastConnected: envLast,
+			Host:          envHost,
+			Port:          envPort,
+			DB:            envDB,
+			JDBCURL:       envURL,
+			As:            envAs,
+			Args:          args,
+		},
+		Shell:   envShell,
+		SSLMode: envSSLMode,
+		Unset:   envUnset,
+	}
+
+	if err := connect.PrintEnv(c.Context(), opts); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,21 @@
+package cmd
+
+import (
+	"testing"
+)
+
+func TestEnvCommandRegistered(t *testing.T) {
+	c, _, err := rootCmd.Find([]string{"env"})
+	if err != nil {
+		t.Fatalf("rootCmd.Find('env'): %v", err)
+	}
+	if c == nil {
+		t.Fatal("env command not found under root")
+	}
+	if c.Use != "env [rds-identifier]" {
+		t.Errorf("env.Use: got %q", c.Use)
+	}
+	if c.Flags().Lookup("unset") == nil {
+		t.Error("env should have an --unset flag")
+	}
+}
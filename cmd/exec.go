@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/PraveenPrabhuT/rds/internal/connect"
+	"github.com/spf13/cobra"
+)
+
+var (
+	execLast        bool
+	execHost        string
+	execPort        int
+	execDB          string
+	execAs          string
+	execSSLMode     string
+	execDatabaseURL bool
+	execEnvPassword bool
+	execReadOnly    bool
+)
+
+var execCmd = &cobra.Command{
+	Use:   "exec [rds-identifier] -- <command...>",
+	Short: "Run a command with PG* environment variables for an RDS instance",
+	Long: `Exec resolves the instance and credentials the same way connect does, then
+runs the given command with PGHOST, PGPORT, PGUSER, PGDATABASE and PGSSLMODE
+set (and DATABASE_URL with --database-url). The password is provided through
+a temporary 0600 PGPASSFILE that is removed when the command exits; use
+--env-password to set PGPASSWORD instead. DATABASE_URL carries the password
+only with --env-password; otherwise libpq clients read it from the
+PGPASSFILE.
+
+The command gets the terminal like pgcli/psql do, and its exit code becomes
+the exit code of rds.`,
+	Example: `  # Dump a database
+  rds exec my-instance --db pricing -- pg_dump -Fc -f pricing.dump
+
+  # Benchmark as the read-write user
+  rds exec my-instance --db pricing --as rw_v1 -- pgbench -c 4 -T 60
+
+  # Run migrations that read DATABASE_URL
+  rds exec -l --db pricing --as migration --database-url -- make migrate`,
+	Args: func(c *cobra.Command, args []string) error {
+		dash := c.ArgsLenAtDash()
+		if dash < 0 || dash == len(args) {
+			return errors.New("requires a command after --")
+		}
+		if dash > 1 {
+			return fmt.Errorf("accepts at most 1 instance before --, received %d", dash)
+		}
+		return nil
+	},
+	Run: runExec,
+}
+
+func init() {
+	execCmd.Flags().BoolVarP(&execLast, "last", "l", false, "Use the last used RDS instance")
+	execCmd.Flags().StringVar(&execHost, "host", "", "RDS host endpoint (bypasses instance picker)")
+	execCmd.Flags().IntVar(&execPort, "port", 5432, "PostgreSQL port")
+	execCmd.Flags().StringVarP(&execDB, "db", "d", "postgres", "Database name")
+	execCmd.Flags().StringVar(&execAs, "as", "", "Use a user provisioned by 'db create' (e.g. ro_v1, rw_v2, migration); requires --db")
+	execCmd.Flags().StringVar(&execSSLMode, "sslmode", connect.DefaultSSLMode, "Value for PGSSLMODE")
+	execCmd.Flags().BoolVar(&execEnvPassword, "env-password", false, "Pass the password via PGPASSWORD (and in DATABASE_URL) instead of a temporary PGPASSFILE")
+	execCmd.Flags().BoolVar(&execReadOnly, "read-only", false, readOnlyUsage)
+	execCmd.Flags().BoolVar(&execDatabaseURL, "database-url", false, "Also set DATABASE_URL (libpq URI, with the password only with --env-password)")
+
+	execCmd.ValidArgsFunction = completeInstances(false)
+
+	rootCmd.AddCommand(execCmd)
+}
+
+func runExec(c *cobra.Command, args []string) {
+	dash := c.ArgsLenAtDash()
+
+	opts := connect.ExecOptions{
+		Options: connect.Options{
+			Profile:       awsProfile,
+			Region:        resolveRegion(awsRegion),
+			LastConnected: execLast,
+			Host:          execHost,
+			Port:          execPort,
+			DB:            execDB,
+			As:            execAs,
+			EnvPassword:   execEnvPassword,
+			ReadOnly:      readOnlyFlag(c, execReadOnly),
+			Args:          args[:dash],
+		},
+		Command:     args[dash:],
+		SSLMode:     execSSLMode,
+		DatabaseURL: execDatabaseURL,
+	}
+
+	if err := connect.Exec(c.Context(), opts); err != nil {
+		exitWithError(err)
+	}
+}
@@ -0,0 +1,18 @@
+package cmd
+
+import (
+	"testing"
+)
+
+func TestExecCommandRegistered(t *testing.T) {
+	c, _, err := rootCmd.Find([]string{"exec"})
+	if err != nil {
+		t.Fatalf("rootCmd.Find('exec'): %v", err)
+	}
+	if c == nil {
+		t.Fatal("exec command not found under root")
+	}
+	if c.Use != "exec [rds-identifier] -- <command...>" {
+		t.Errorf("exec.Use: got %q", c.Use)
+	}
+}
@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/PraveenPrabhuT/rds/internal/connect"
+	"github.com/PraveenPrabhuT/rds/internal/query"
+	"github.com/PraveenPrabhuT/rds/internal/render"
+	"github.com/spf13/cobra"
+)
+
+var (
+	queryLast     bool
+	queryHost     string
+	queryPort     int
+	queryDB       string
+	queryAs       string
+	queryCommand  string
+	queryFile     string
+	queryFormat   string
+	queryNoHeader bool
+	queryAll      bool
+	queryFilters  []string
+	queryEachDB   bool
+	queryParallel int
+	queryReadOnly bool
+)
+
+var queryCmd = &cobra.Command{
+	Use:   "query [rds-identifier]",
+	Short: "Run SQL against an RDS PostgreSQL instance and print the results",
+	Long: `Query resolves the instance and credentials the same way connect does, runs
+the SQL given with -c or -f over a direct connection (no client binary needed)
+and writes the results to stdout.
+
+Output formats:
+  table     psql-style aligned table (buffered to compute column widths)
+  csv       RFC 4180 CSV, NULL as an empty field
+  tsv       PostgreSQL COPY text format, NULL as \N
+  json      a JSON array of objects per result set
+  jsonl     one JSON object per line
+  markdown  GitHub-flavored Markdown table
+
+Every format except table streams rows as they arrive, so large result sets
+are not held in memory. Several statements in one -c or -f run as a single
+implicit transaction unless the SQL contains BEGIN/COMMIT. Command tags of
+statements that return no rows go to stderr. rds exits non-zero if any
+statement fails.
+
+Fan-out: --all runs the SQL on every instance, --filter on the instances
+matching a regular expression on the ID or tag:KEY[=VALUE] (repeatable, all
+must match), and --each-db on every database of each instance. Targets run
+concurrently (--parallel), rows are merged into one output with "instance"
+and "database" columns prepended, and failing targets are listed at the end
+without stopping the others.
+
+Read-only profiles (or --read-only) run the SQL in a read-only session. On
+protected profiles with writes allowed, DROP, TRUNCATE and DELETE/UPDATE
+without WHERE need the database name typed on the terminal.`,
+	Example: `  # Ad-hoc query as the read-only user
+  rds query my-instance --db pricing --as ro_v1 -c "select count(*) from prices"
+
+  # Export a table to CSV
+  rds query my-instance --db pricing -c "select * from prices" -o csv > prices.csv
+
+  # Run a script from stdin and get JSON Lines
+  cat report.sql | rds query -l --db pricing -f - -o jsonl
+
+  # max_connections on every production instance
+  rds query --filter tag:env=prod -c "show max_connections"
+
+  # Which databases have a given table
+  rds query --all --each-db -o csv -c "select to_regclass('public.outbox') is not null as has_outbox"`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  runQuery,
+}
+
+func init() {
+	queryCmd.Flags().BoolVarP(&queryLast, "last", "l", false, "Use the last used RDS instance")
+	queryCmd.Flags().StringVar(&queryHost, "host", "", "RDS host endpoint (bypasses instance picker)")
+	queryCmd.Flags().IntVar(&queryPort, "port", 5432, "PostgreSQL port")
+	queryCmd.Flags().StringVarP(&queryDB, "db", "d", "postgres", "Database name")
+	queryCmd.Flags().StringVar(&queryAs, "as", "", "Use a user provisioned by 'db create' (e.g. ro_v1, rw_v2, migration); requires --db")
+	queryCmd.Flags().StringVarP(&queryCommand, "command", "c", "", "SQL to run")
+	queryCmd.Flags().StringVarP(&queryFile, "file", "f", "", "File with SQL to run (- for stdin)")
+	queryCmd.Flags().StringVarP(&queryFormat, "format", "o", "table", "Output format: "+strings.Join(render.Formats, "|"))
+	queryCmd.Flags().BoolVar(&queryNoHeader, "no-header", false, "Omit the column header (and the table row count)")
+
+	queryCmd.Flags().BoolVar(&queryReadOnly, "read-only", false, readOnlyUsage)
+	queryCmd.Flags().BoolVar(&queryAll, "all", false, "Run on every instance")
+	queryCmd.Flags().StringArrayVar(&queryFilters, "filter", nil, "Run on instances whose ID matches a regex, or tag:KEY[=VALUE] (repeatable)")
+	queryCmd.Flags().BoolVar(&queryEachDB, "each-db", false, "Run on every database of each instance")
+	queryCmd.Flags().IntVar(&queryParallel, "parallel", query.DefaultParallel, "Targets to query concurrently when fanning out")
+
+	queryCmd.MarkFlagsMutuallyExclusive("command", "file")
+	queryCmd.MarkFlagsMutuallyExclusive("all", "last")
+	queryCmd.MarkFlagsMutuallyExclusive("all", "host")
+	queryCmd.MarkFlagsOneRequired("command", "file")
+	queryCmd.RegisterFlagCompletionFunc("format", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return render.Formats, cobra.ShellCompDirectiveNoFileComp
+	})
+	queryCmd.ValidArgsFunction = completeInstances(false)
+
+	rootCmd.AddCommand(queryCmd)
+}
+
+func runQuery(c *cobra.Command, args []string) {
+	opts := query.Options{
+		Options: connect.Options{
+			Profile:       awsProfile,
+			Region:        resolveRegion(awsRegion),
+			LastConnected: queryLast,
+			Host:          queryHost,
+			Port:          queryPort,
+			DB:            queryDB,
+			As:            queryAs,
+			ReadOnly:      readOnlyFlag(c, queryReadOnly),
+			Args:          args,
+		},
+		Command:  queryCommand,
+		File:     queryFile,
+		Format:   queryFormat,
+		NoHeader: queryNoHeader,
+		All:      queryAll,
+		Filters:  queryFilters,
+		EachDB:   queryEachDB,
+		Parallel: queryParallel,
+	}
+
+	if err := query.Run(c.Context(), opts); err != nil {
+		exitWithError(err)
+	}
+}
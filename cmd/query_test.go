@@ -0,0 +1,23 @@
+package cmd
+
+import (
+	"testing"
+)
+
+func TestQueryCommandRegistered(t *testing.T) {
+	c, _, err := rootCmd.Find([]string{"query"})
+	if err != nil {
+		t.Fatalf("rootCmd.Find('query'): %v", err)
+	}
+	if c == nil || c.Name() != "query" {
+		t.Fatal("query command not found under root")
+	}
+	for _, name := range []string{"command", "file", "format", "no-header", "as", "db"} {
+		if c.Flags().Lookup(name) == nil {
+			t.Errorf("query --%s flag missing", name)
+		}
+	}
+	if f := c.Flags().Lookup("format"); f.DefValue != "table" {
+		t.Errorf("query --format default: got %q", f.DefValue)
+	}
+}
@@ -1,8 +1,10 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 
@@ -36,6 +38,42 @@ func Execute() {
 	}
 }
 
+// exitWithError exits with the exit code of a child process when err comes
+// from one (so `rds exec`/`rds connect` are transparent to scripts), or prints
+// err and exits 1 otherwise. A child killed by a signal counts as a failure.
+func exitWithError(err error) {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if code := exitErr.ExitCode(); code > 0 {
+			os.Exit(code)
+		}
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+	os.Exit(1)
+}
+
+// readOnlyFlag returns the --read-only value when it was given, or nil to
+// keep the profile default from the config file.
+func readOnlyFlag(c *cobra.Command, value bool) *bool {
+	if !c.Flags().Changed("read-only") {
+		return nil
+	}
+	return &value
+}
+
+// readOnlyUsage is the shared help text of --read-only.
+const readOnlyUsage = "Start a read-only session (default from profiles.<name>.read_only; --read-only=false allows writes)"
+
+// instanceArgCount returns the number of positional arguments before "--",
+// i.e. the ones naming an instance rather than being passed through.
+func instanceArgCount(c *cobra.Command, args []string) int {
+	if dash := c.ArgsLenAtDash(); dash >= 0 {
+		return dash
+	}
+	return len(args)
+}
+
 const defaultAWSRegion = "ap-south-1"
 
 // resolveRegion returns the effective AWS region: flag > AWS_REGION env > default (ap-south-1).
@@ -0,0 +1,21 @@
+package cmd
+
+import (
+	"testing"
+)
+
+func TestURLCommandRegistered(t *testing.T) {
+	c, _, err := rootCmd.Find([]string{"url"})
+	if err != nil {
+		t.Fatalf("rootCmd.Find('url'): %v", err)
+	}
+	if c == nil {
+		t.Fatal("url command not found under root")
+	}
+	if c.Use != "url [rds-identifier]" {
+		t.Errorf("url.Use: got %q", c.Use)
+	}
+	if f := c.Flags().Lookup("format"); f == nil || f.DefValue != "jdbc" {
+		t.Errorf("url --format flag: got %+v", f)
+	}
+}
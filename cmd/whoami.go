@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/PraveenPrabhuT/rds/internal/whoami"
+	"github.com/spf13/cobra"
+)
+
+var whoamiCmd = &cobra.Command{
+	Use:   "whoami",
+	Short: "Show the AWS identity and the permissions rds needs",
+	Long: `Whoami prints the STS caller identity for the selected profile, the
+resolved region and home region (used for Secrets Manager lookups), and the
+Pritunl VPN mapped to the profile.
+
+It then runs IAM policy simulation for every action the tool relies on and
+reports whether each one is allowed or denied:
+
+  - rds:DescribeDBInstances
+  - secretsmanager:GetSecretValue
+  - secretsmanager:CreateSecret
+  - rds-db:connect`,
+	Example: `  # Check the current profile before running db create
+  rds whoami
+
+  # Check another profile and region
+  rds whoami -p ackoprod -r ap-southeast-1`,
+	Args: cobra.NoArgs,
+	Run:  runWhoami,
+}
+
+func init() {
+	rootCmd.AddCommand(whoamiCmd)
+}
+
+func runWhoami(c *cobra.Command, args []string) {
+	opts := whoami.Options{
+		Profile: awsProfile,
+		Region:  resolveRegion(awsRegion),
+	}
+
+	if err := whoami.Run(c.Context(), opts); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(1)
+	}
+}
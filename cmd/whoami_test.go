@@ -0,0 +1,18 @@
+package cmd
+
+import (
+	"testing"
+)
+
+func TestWhoamiCommandRegistered(t *testing.T) {
+	c, _, err := rootCmd.Find([]string{"whoami"})
+	if err != nil {
+		t.Fatalf("rootCmd.Find('whoami'): %v", err)
+	}
+	if c == nil {
+		t.Fatal("whoami command not found under root")
+	}
+	if c.Use != "whoami" {
+		t.Errorf("whoami.Use: got %q", c.Use)
+	}
+}
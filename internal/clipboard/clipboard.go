@@ -0,0 +1,198 @@
+package clipboard
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/PraveenPrabhuT/rds/internal/core"
+)
+
+// Backends lists the supported clipboard backends (besides "auto").
+var Backends = []string{"pbcopy", "wl-copy", "xclip", "xsel", "osc52"}
+
+// ChecksumEnv carries the SHA-256 of the copied text to the clear process so
+// it only clears the clipboard if the user has not copied something else.
+const ChecksumEnv = "RDS_CLIPBOARD_SHA256"
+
+type tool struct {
+	copy  []string
+	paste []string
+	clear []string // nil: copy an empty string
+}
+
+var tools = map[string]tool{
+	"pbcopy":  {copy: []string{"pbcopy"}, paste: []string{"pbpaste"}},
+	"wl-copy": {copy: []string{"wl-copy"}, paste: []string{"wl-paste", "--no-newline"}, clear: []string{"wl-copy", "--clear"}},
+	"xclip":   {copy: []string{"xclip", "-selection", "clipboard"}, paste: []string{"xclip", "-selection", "clipboard", "-o"}},
+	"xsel":    {copy: []string{"xsel", "--clipboard", "--input"}, paste: []string{"xsel", "--clipboard", "--output"}, clear: []string{"xsel", "--clipboard", "--clear"}},
+}
+
+// Copy places text on the clipboard using the configured backend (or the
+// autodetected one) and, when settings.ClearAfter is set, schedules a
+// detached process that clears it again. It returns the backend used.
+func Copy(text string, settings core.ClipboardConfig) (string, error) {
+	backend, err := Resolve(settings.Backend)
+	if err != nil {
+		return "", err
+	}
+	if err := write(backend, text); err != nil {
+		return backend, err
+	}
+	if settings.ClearAfter > 0 {
+		if err := scheduleClear(backend, settings.ClearAfter, Checksum(text)); err != nil {
+			return backend, fmt.Errorf("schedule clipboard clear: %w", err)
+		}
+	}
+	return backend, nil
+}
+
+// Resolve validates a configured backend name, autodetecting when it is
+// empty or "auto".
+func Resolve(name string) (string, error) {
+	if name == "" || name == "auto" {
+		return detect(runtime.GOOS, os.Getenv, hasBinary), nil
+	}
+	for _, b := range Backends {
+		if b == name {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("unknown clipboard backend %q (want auto, %s)", name, strings.Join(Backends, ", "))
+}
+
+// detect picks a backend: OSC 52 over SSH without a forwarded display,
+// otherwise the native tool for the platform/display server, and OSC 52 as
+// the last resort.
+func detect(goos string, getenv func(string) string, has func(string) bool) string {
+	wayland := getenv("WAYLAND_DISPLAY") != ""
+	x11 := getenv("DISPLAY") != ""
+	ssh := getenv("SSH_TTY") != "" || getenv("SSH_CONNECTION") != ""
+
+	if ssh && !wayland && !x11 {
+		return "osc52"
+	}
+	if goos == "darwin" && has("pbcopy") {
+		return "pbcopy"
+	}
+	if wayland && has("wl-copy") {
+		return "wl-copy"
+	}
+	if x11 && has("xclip") {
+		return "xclip"
+	}
+	if x11 && has("xsel") {
+		return "xsel"
+	}
+	return "osc52"
+}
+
+func hasBinary(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
+
+// Checksum returns the hex SHA-256 of text.
+func Checksum(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+func write(backend, text string) error {
+	if backend == "osc52" {
+		return writeOSC52(text)
+	}
+	t := tools[backend]
+	cmd := exec.Command(t.copy[0], t.copy[1:]...)
+	cmd.Stdin = strings.NewReader(text)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %w", backend, err)
+	}
+	return nil
+}
+
+func read(backend string) (string, error) {
+	t := tools[backend]
+	out, err := exec.Command(t.paste[0], t.paste[1:]...).Output()
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", backend, err)
+	}
+	return string(out), nil
+}
+
+// Clear empties the clipboard. When checksum is non-empty and the backend
+// can read the clipboard back, it is only cleared if its content still
+// matches checksum.
+func Clear(backend, checksum string) error {
+	if backend == "osc52" {
+		return writeOSC52("")
+	}
+	t, ok := tools[backend]
+	if !ok {
+		return fmt.Errorf("unknown clipboard backend %q", backend)
+	}
+	if checksum != "" {
+		current, err := read(backend)
+		if err == nil && Checksum(current) != checksum {
+			return nil
+		}
+	}
+	if t.clear == nil {
+		return write(backend, "")
+	}
+	return exec.Command(t.clear[0], t.clear[1:]...).Run()
+}
+
+// osc52Sequence builds the OSC 52 "set clipboard" escape, wrapped in a DCS
+// passthrough when running inside tmux.
+func osc52Sequence(text string, tmux bool) string {
+	seq := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + "\a"
+	if tmux {
+		seq = "\x1bPtmux;" + strings.ReplaceAll(seq, "\x1b", "\x1b\x1b") + "\x1b\\"
+	}
+	return seq
+}
+
+// writeOSC52 sends the escape to the controlling terminal so it works even
+// when stdout is redirected; the terminal emulator on the user's machine
+// sets its clipboard, which is what makes this work over SSH.
+func writeOSC52(text string) error {
+	var w io.Writer = os.Stderr
+	if tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0); err == nil {
+		defer tty.Close()
+		w = tty
+	}
+	_, err := io.WriteString(w, osc52Sequence(text, os.Getenv("TMUX") != ""))
+	return err
+}
+
+// scheduleClear re-executes the rds binary as a detached, hidden
+// "clipboard-clear" process that outlives the current command.
+func scheduleClear(backend string, seconds int, checksum string) error {
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(self, "clipboard-clear", "--backend", backend, "--after", strconv.Itoa(seconds))
+	cmd.Env = append(os.Environ(), ChecksumEnv+"="+checksum)
+	if backend == "osc52" {
+		// The detached process has no controlling terminal, so hand it the
+		// current one as stderr for writeOSC52 to fall back on.
+		if tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0); err == nil {
+			defer tty.Close()
+			cmd.Stderr = tty
+		}
+	}
+	detach(cmd)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	return cmd.Process.Release()
+}
@@ -0,0 +1,83 @@
+package clipboard
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func envFrom(m map[string]string) func(string) string {
+	return func(k string) string { return m[k] }
+}
+
+func hasOnly(names ...string) func(string) bool {
+	return func(n string) bool {
+		for _, name := range names {
+			if name == n {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name string
+		goos string
+		env  map[string]string
+		bins []string
+		want string
+	}{
+		{"macOS", "darwin", nil, []string{"pbcopy"}, "pbcopy"},
+		{"macOS over SSH", "darwin", map[string]string{"SSH_TTY": "/dev/ttys001"}, []string{"pbcopy"}, "osc52"},
+		{"Wayland", "linux", map[string]string{"WAYLAND_DISPLAY": "wayland-0"}, []string{"wl-copy", "xclip"}, "wl-copy"},
+		{"X11 xclip", "linux", map[string]string{"DISPLAY": ":0"}, []string{"xclip", "xsel"}, "xclip"},
+		{"X11 xsel only", "linux", map[string]string{"DISPLAY": ":0"}, []string{"xsel"}, "xsel"},
+		{"SSH with X forwarding", "linux", map[string]string{"SSH_CONNECTION": "1 2 3 4", "DISPLAY": "localhost:10.0"}, []string{"xclip"}, "xclip"},
+		{"headless SSH", "linux", map[string]string{"SSH_CONNECTION": "1 2 3 4"}, []string{"xclip"}, "osc52"},
+		{"no tools", "linux", map[string]string{"DISPLAY": ":0"}, nil, "osc52"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := detect(tt.goos, envFrom(tt.env), hasOnly(tt.bins...))
+			if got != tt.want {
+				t.Errorf("detect: got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	if got, err := Resolve("xsel"); err != nil || got != "xsel" {
+		t.Errorf("Resolve(xsel): got %q, %v", got, err)
+	}
+	if _, err := Resolve("clip.exe"); err == nil {
+		t.Error("Resolve(clip.exe): expected error for unknown backend")
+	}
+	if got, err := Resolve("auto"); err != nil || got == "" {
+		t.Errorf("Resolve(auto): got %q, %v", got, err)
+	}
+}
+
+func TestOSC52Sequence(t *testing.T) {
+	got := osc52Sequence("secret", false)
+	want := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte("secret")) + "\a"
+	if got != want {
+		t.Errorf("osc52Sequence: got %q, want %q", got, want)
+	}
+
+	tmux := osc52Sequence("secret", true)
+	if !strings.HasPrefix(tmux, "\x1bPtmux;\x1b\x1b]52;c;") || !strings.HasSuffix(tmux, "\x1b\\") {
+		t.Errorf("osc52Sequence (tmux): got %q", tmux)
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	if Checksum("a") == Checksum("b") {
+		t.Error("Checksum: different inputs produced the same checksum")
+	}
+	if len(Checksum("")) != 64 {
+		t.Errorf("Checksum: got length %d, want 64", len(Checksum("")))
+	}
+}
@@ -0,0 +1,14 @@
+//go:build unix
+
+package clipboard
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// detach starts cmd in its own session so it survives the parent exiting
+// and does not receive the terminal's SIGINT/SIGHUP.
+func detach(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
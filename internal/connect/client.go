@@ -0,0 +1,65 @@
+package connect
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/PraveenPrabhuT/rds/internal/core"
+)
+
+// Clients lists the values accepted by --client ("auto" keeps the default
+// pgcli > psql > native preference order).
+var Clients = []string{"auto", "pgcli", "psql", "usql", "native"}
+
+// selectClient returns the client to launch and its path ("" for native).
+// An explicitly requested client must be installed; auto falls back to the
+// native client when neither pgcli nor psql is found.
+func selectClient(preferred string, lookPath func(string) (string, error)) (string, string, error) {
+	switch preferred {
+	case "", "auto":
+		for _, name := range []string{"pgcli", "psql"} {
+			if path, err := lookPath(name); err == nil {
+				return name, path, nil
+			}
+		}
+		return "native", "", nil
+	case "native":
+		return "native", "", nil
+	case "pgcli", "psql", "usql":
+		path, err := lookPath(preferred)
+		if err != nil {
+			return "", "", fmt.Errorf("client %q not found in PATH", preferred)
+		}
+		return preferred, path, nil
+	}
+	return "", "", fmt.Errorf("unknown client %q (want one of %s)", preferred, strings.Join(Clients, ", "))
+}
+
+// resolveClientPreference applies the per-profile default from the config
+// file when no client was given on the command line. An unreadable config
+// file is reported and the auto preference order is used.
+func resolveClientPreference(flag, profile string) string {
+	if flag != "" {
+		return flag
+	}
+	cfg, err := core.LoadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  %v (using the default client)\n", err)
+		return ""
+	}
+	return cfg.Profile(profile).Client
+}
+
+// buildClientArgs returns the arguments for the given client followed by the
+// user's pass-through arguments. usql only understands URLs, so it gets a
+// password-less URI and reads the password from PGPASSFILE/PGPASSWORD.
+func buildClientArgs(client string, inst core.InstanceInfo, creds core.RDSCreds, dbname string, extra []string) []string {
+	var args []string
+	if client == "usql" {
+		args = []string{formatURI("postgres", ConnParams{Host: inst.Host, Port: inst.Port, User: creds.Username, DB: dbname}, sslQuery(DefaultSSLMode))}
+	} else {
+		args = buildConnectArgs(inst, creds, dbname)
+	}
+	return append(args, extra...)
+}
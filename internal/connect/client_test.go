@@ -0,0 +1,75 @@
+package connect
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/PraveenPrabhuT/rds/internal/core"
+)
+
+func fakeLookPath(installed ...string) func(string) (string, error) {
+	return func(name string) (string, error) {
+		for _, n := range installed {
+			if n == name {
+				return "/usr/bin/" + name, nil
+			}
+		}
+		return "", errors.New("not found")
+	}
+}
+
+func TestSelectClient(t *testing.T) {
+	tests := []struct {
+		name      string
+		preferred string
+		installed []string
+		want      string
+		wantErr   bool
+	}{
+		{"auto prefers pgcli", "", []string{"psql", "pgcli"}, "pgcli", false},
+		{"auto falls back to psql", "auto", []string{"psql"}, "psql", false},
+		{"auto falls back to native", "", nil, "native", false},
+		{"explicit psql", "psql", []string{"pgcli", "psql"}, "psql", false},
+		{"explicit usql", "usql", []string{"usql"}, "usql", false},
+		{"explicit native", "native", []string{"pgcli"}, "native", false},
+		{"explicit missing", "usql", []string{"psql"}, "", true},
+		{"unknown", "dbeaver", nil, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := selectClient(tt.preferred, fakeLookPath(tt.installed...))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("selectClient(%q): err %v, wantErr %v", tt.preferred, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("selectClient(%q): got %q, want %q", tt.preferred, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildClientArgs_PassThrough(t *testing.T) {
+	inst := core.InstanceInfo{Host: "db.example.com", Port: 5432}
+	creds := core.RDSCreds{Username: "admin", Password: "secret"}
+
+	args := buildClientArgs("psql", inst, creds, "pricing", []string{"-c", "select 1", "--csv"})
+	want := []string{"-h", "db.example.com", "-p", "5432", "-U", "admin", "-d", "pricing", "-c", "select 1", "--csv"}
+	if len(args) != len(want) {
+		t.Fatalf("buildClientArgs: got %v, want %v", args, want)
+	}
+	for i := range want {
+		if args[i] != want[i] {
+			t.Errorf("buildClientArgs[%d]: got %q, want %q", i, args[i], want[i])
+		}
+	}
+}
+
+func TestBuildClientArgs_Usql(t *testing.T) {
+	inst := core.InstanceInfo{Host: "db.example.com", Port: 5432}
+	creds := core.RDSCreds{Username: "admin", Password: "secret"}
+
+	args := buildClientArgs("usql", inst, creds, "pricing", nil)
+	if len(args) != 1 || args[0] != "postgres://admin@db.example.com:5432/pricing?sslmode=require" {
+		t.Errorf("buildClientArgs(usql): got %v", args)
+	}
+}
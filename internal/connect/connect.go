@@ -17,102 +17,43 @@ type Options struct {
 	Port          int
 	DB            string
 	JDBCURL       string
-	ShowJDBC      bool
-	CopyJDBC      bool
-	Args          []string
+	As            string
+	EnvPassword   bool
+	Client        string
+	ClientArgs    []string
+	// ReadOnly overrides the profile's read_only setting when non-nil.
+	ReadOnly *bool
+	ShowJDBC bool
+	CopyJDBC bool
+	Args     []string
 }
 
-// Run performs optional VPN check (if Pritunl CLI is present), instance selection, credential fetch, and launches pgcli/psql or native client.
+// Run performs optional VPN check (if Pritunl CLI is present), instance selection, credential fetch, and launches
+// the requested client (pgcli, psql, usql or native; pgcli > psql > native by default). ClientArgs are forwarded
+// to external clients, whose non-zero exit status is returned as an *exec.ExitError.
 func Run(ctx context.Context, opts Options) error {
-	if err := core.CheckVPNWithPritunl(opts.Profile); err != nil {
-		fmt.Printf("⚠️  VPN check: %v (continuing anyway)\n", err)
-	}
-
-	cfg, homeRegion, err := core.LoadAWSConfig(ctx, opts.Profile, opts.Region)
+	preference := resolveClientPreference(opts.Client, opts.Profile)
+	client, path, err := selectClient(preference, exec.LookPath)
 	if err != nil {
 		return err
 	}
-
-	instances, err := core.GetInstancesWithCache(ctx, cfg, opts.Profile)
-	if err != nil {
-		return fmt.Errorf("fetch instances: %w", err)
-	}
-
-	var selected core.InstanceInfo
-	var selectErr error
-	var connectHost string
-	var connectPort int32
-	dbname := opts.DB
-	if dbname == "" {
-		dbname = "postgres"
-	}
-
-	if opts.JDBCURL != "" {
-		urlHost, urlPort, urlDB, parseErr := ParseJDBCURL(opts.JDBCURL)
-		if parseErr != nil {
-			return fmt.Errorf("parse JDBC URL: %w", parseErr)
-		}
-
-		connectHost = urlHost
-		connectPort = int32(urlPort)
-		dbname = urlDB
-
-		resolved, err := ResolveCNAME(urlHost)
-		if err != nil {
-			return fmt.Errorf("resolve CNAME for %s: %w", urlHost, err)
-		}
-
-		selected, selectErr = core.FindInstanceByEndpoint(instances, resolved)
-		if selectErr != nil {
-			// Private IPs in JDBC URLs often do not match DNS resolution of RDS
-			// hostnames from a laptop (different network path than VPC).
-			if len(opts.Args) > 0 {
-				selected, selectErr = core.FindByName(instances, opts.Args[0])
-				if selectErr != nil {
-					return fmt.Errorf("could not map JDBC host %q to an RDS endpoint, and instance %q: %w", urlHost, opts.Args[0], selectErr)
-				}
-			} else {
-				fmt.Printf("⚠️  Could not map JDBC host %q to an RDS endpoint (typical for private IPs). Pick the instance to load Secrets Manager credentials.\n", urlHost)
-				selected, selectErr = core.PickWithFuzzyFinder(instances)
-				if selectErr != nil {
-					return fmt.Errorf("selection: %w", selectErr)
-				}
-			}
-		}
-	} else {
-		switch {
-		case opts.Host != "":
-			selected, selectErr = core.FindInstanceByEndpoint(instances, opts.Host)
-		case len(opts.Args) > 0:
-			selected, selectErr = core.FindByName(instances, opts.Args[0])
-		case opts.LastConnected:
-			selected, selectErr = core.LoadLastConnected(instances, opts.Profile)
-		default:
-			selected, selectErr = core.PickWithFuzzyFinder(instances)
-		}
-
-		if selectErr != nil {
-			return fmt.Errorf("selection: %w", selectErr)
-		}
-
-		connectHost = selected.Host
-		connectPort = selected.Port
-	}
-
-	if opts.Port != 0 {
-		connectPort = int32(opts.Port)
+	if client == "native" && len(opts.ClientArgs) > 0 {
+		return fmt.Errorf("client arguments after -- need an external client (pgcli, psql or usql)")
 	}
 
-	creds, err := core.GetRDSCredentials(ctx, cfg, selected, homeRegion)
+	target, err := Resolve(ctx, opts)
 	if err != nil {
-		return fmt.Errorf("secrets: %w", err)
+		return err
 	}
 
-	core.SaveLastID(selected.ID, opts.Profile)
-	fmt.Printf("\n🚀 Target: %s [%s]\n", selected.ID, connectHost)
+	fmt.Printf("\n🚀 Target: %s [%s]\n", target.Instance.ID, target.Host)
+	safety := ResolveSafety(opts.ReadOnly, opts.Profile)
+	printSafety(safety)
+	style := resolveStyle(opts.Profile, target.Instance)
+	printBanner(ctx, style, opts.Profile, target, safety)
 
 	if opts.ShowJDBC {
-		jdbcURL := BuildJDBCURL(connectHost, connectPort, creds.Username, creds.Password, dbname)
+		jdbcURL := BuildJDBCURL(target.Host, target.Port, target.Creds.Username, target.Creds.Password, target.DB)
 		fmt.Printf("\n📋 JDBC URL:\n%s\n\n", jdbcURL)
 		if opts.CopyJDBC {
 			copyToClipboard(jdbcURL)
@@ -120,23 +61,22 @@ func Run(ctx context.Context, opts Options) error {
 	}
 
 	connInfo := core.InstanceInfo{
-		ID:   selected.ID,
-		Host: connectHost,
-		Port: connectPort,
+		ID:   target.Instance.ID,
+		Host: target.Host,
+		Port: target.Port,
 	}
 
-	if path, err := exec.LookPath("pgcli"); err == nil {
+	switch client {
+	case "native":
+		if preference == "" || preference == "auto" {
+			fmt.Println("⚠️  No binary clients found. Launching Native Fallback...")
+		}
+		return runNativeConnect(target.Host, target.Port, target.Creds.Username, target.Creds.Password, target.DB, safety, style)
+	case "pgcli":
 		fmt.Println("✨ Launching pgcli...")
-		executeExternal(path, connInfo, creds, dbname)
-		return nil
-	}
-	if path, err := exec.LookPath("psql"); err == nil {
-		fmt.Println("📂 Launching psql...")
-		executeExternal(path, connInfo, creds, dbname)
-		return nil
+	default:
+		fmt.Printf("📂 Launching %s...\n", client)
 	}
-
-	fmt.Println("⚠️  No binary clients found. Launching Native Fallback...")
-	runNativeConnect(connectHost, connectPort, creds.Username, creds.Password, dbname)
-	return nil
+	args := buildClientArgs(client, connInfo, target.Creds, target.DB, opts.ClientArgs)
+	return executeExternal(client, path, args, connInfo, target.Creds, target.DB, opts.EnvPassword, safety, style)
 }
@@ -0,0 +1,90 @@
+package connect
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Shells lists the shell syntaxes supported by PrintEnv.
+var Shells = []string{"bash", "zsh", "fish"}
+
+// envNames are the variables PrintEnv sets (and unsets with Unset).
+var envNames = []string{"PGHOST", "PGPORT", "PGUSER", "PGPASSWORD", "PGDATABASE", "PGSSLMODE", "DATABASE_URL"}
+
+// EnvOptions configures an `rds env` run.
+type EnvOptions struct {
+	Options
+	Shell   string
+	SSLMode string
+	Unset   bool
+}
+
+// PrintEnv prints shell statements that export the PG* variables and
+// DATABASE_URL for the resolved target, meant for eval "$(rds env ...)".
+// With Unset it prints the statements that remove them instead, without
+// resolving anything.
+func PrintEnv(ctx context.Context, opts EnvOptions) error {
+	if !validShell(opts.Shell) {
+		return fmt.Errorf("unsupported shell %q (want one of %s)", opts.Shell, strings.Join(Shells, ", "))
+	}
+	if opts.Unset {
+		fmt.Println(renderUnset(opts.Shell, envNames))
+		return nil
+	}
+	if err := ValidateSSLMode(opts.SSLMode); err != nil {
+		return err
+	}
+
+	target, err := Resolve(ctx, opts.Options)
+	if err != nil {
+		return err
+	}
+	fmt.Println(renderExports(opts.Shell, connEnv(target.ConnParams(opts.SSLMode), true)))
+	return nil
+}
+
+// DetectShell maps a $SHELL path to one of Shells, defaulting to bash.
+func DetectShell(shellPath string) string {
+	name := filepath.Base(shellPath)
+	if validShell(name) {
+		return name
+	}
+	return "bash"
+}
+
+func validShell(shell string) bool {
+	for _, s := range Shells {
+		if s == shell {
+			return true
+		}
+	}
+	return false
+}
+
+func renderExports(shell string, vars []EnvVar) string {
+	lines := make([]string, 0, len(vars))
+	for _, v := range vars {
+		if shell == "fish" {
+			lines = append(lines, fmt.Sprintf("set -gx %s %s;", v.Name, fishQuote(v.Value)))
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("export %s=%s;", v.Name, shellQuote(v.Value)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func renderUnset(shell string, names []string) string {
+	if shell == "fish" {
+		return fmt.Sprintf("set -e %s;", strings.Join(names, " "))
+	}
+	return fmt.Sprintf("unset %s;", strings.Join(names, " "))
+}
+
+// fishQuote single-quotes v for fish, where only \ and ' are special inside
+// single quotes.
+func fishQuote(v string) string {
+	r := strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+	return "'" + r.Replace(v) + "'"
+}
@@ -0,0 +1,57 @@
+package connect
+
+import (
+	"testing"
+)
+
+func TestRenderExports_Bash(t *testing.T) {
+	vars := []EnvVar{{"PGHOST", "db.example.com"}, {"PGPASSWORD", "it's $ecret"}}
+	got := renderExports("bash", vars)
+	want := "export PGHOST=db.example.com;\nexport PGPASSWORD='it'\\''s $ecret';"
+	if got != want {
+		t.Errorf("renderExports(bash):\n  got  %q\n  want %q", got, want)
+	}
+	if zsh := renderExports("zsh", vars); zsh != want {
+		t.Errorf("renderExports(zsh): got %q, want bash syntax", zsh)
+	}
+}
+
+func TestRenderExports_Fish(t *testing.T) {
+	vars := []EnvVar{{"PGHOST", "db.example.com"}, {"PGPASSWORD", `it's a\b`}}
+	got := renderExports("fish", vars)
+	want := "set -gx PGHOST 'db.example.com';\nset -gx PGPASSWORD 'it\\'s a\\\\b';"
+	if got != want {
+		t.Errorf("renderExports(fish):\n  got  %q\n  want %q", got, want)
+	}
+}
+
+func TestRenderUnset(t *testing.T) {
+	names := []string{"PGHOST", "DATABASE_URL"}
+	if got := renderUnset("bash", names); got != "unset PGHOST DATABASE_URL;" {
+		t.Errorf("renderUnset(bash): got %q", got)
+	}
+	if got := renderUnset("fish", names); got != "set -e PGHOST DATABASE_URL;" {
+		t.Errorf("renderUnset(fish): got %q", got)
+	}
+}
+
+func TestDetectShell(t *testing.T) {
+	tests := map[string]string{
+		"/usr/local/bin/fish": "fish",
+		"/bin/zsh":            "zsh",
+		"/bin/bash":           "bash",
+		"/bin/sh":             "bash",
+		"":                    "bash",
+	}
+	for in, want := range tests {
+		if got := DetectShell(in); got != want {
+			t.Errorf("DetectShell(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestPrintEnv_UnsupportedShell(t *testing.T) {
+	if err := PrintEnv(t.Context(), EnvOptions{Shell: "powershell", Unset: true}); err == nil {
+		t.Fatal("PrintEnv: expected error for unsupported shell")
+	}
+}
@@ -0,0 +1,153 @@
+package connect
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/PraveenPrabhuT/rds/internal/core"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// sessionStyle is the environment labelling of one session.
+type sessionStyle struct {
+	env core.Environment
+	// label is "[PROD profile/instance]", or empty for an unclassified
+	// target.
+	label string
+}
+
+// resolveStyle classifies the target from the config file and the
+// instance's tags.
+func resolveStyle(profile string, inst core.InstanceInfo) sessionStyle {
+	cfg, err := core.LoadConfig()
+	if err != nil {
+		cfg = core.Config{}
+	}
+	env := cfg.Environment(profile, inst.Tags)
+	if env.Name == "" {
+		return sessionStyle{}
+	}
+	return sessionStyle{env: env, label: fmt.Sprintf("[%s %s/%s]", env.Label(), profile, inst.ID)}
+}
+
+// colorEnabled honours the NO_COLOR convention.
+func colorEnabled() bool {
+	return os.Getenv("NO_COLOR") == ""
+}
+
+// ansi returns the SGR code for the label, or "" when colors are off.
+func (st sessionStyle) ansi() string {
+	if !colorEnabled() {
+		return ""
+	}
+	return st.env.ANSI()
+}
+
+// colored returns text wrapped in the environment's color.
+func (st sessionStyle) colored(text string) string {
+	if code := st.ansi(); code != "" {
+		return "\x1b[" + code + "m" + text + "\x1b[0m"
+	}
+	return text
+}
+
+// psqlrcContent sources the user's own psqlrc (PSQLRC replaces it) and then
+// prefixes the label to whatever PROMPT1/PROMPT2 it left in place.
+func psqlrcContent(st sessionStyle, userRC string) string {
+	var b strings.Builder
+	if userRC != "" {
+		fmt.Fprintf(&b, "\\i '%s'\n", psqlQuote(userRC))
+	}
+	label := strings.ReplaceAll(st.label, "%", "%%")
+	if code := st.ansi(); code != "" {
+		label = "%[%033[" + code + "m%]" + label + "%[%033[0m%]"
+	}
+	fmt.Fprintf(&b, "\\set PROMPT1 '%s ' :PROMPT1\n", psqlQuote(label))
+	fmt.Fprintf(&b, "\\set PROMPT2 '%s ' :PROMPT2\n", psqlQuote(label))
+	return b.String()
+}
+
+func psqlQuote(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s)
+}
+
+// userPsqlrc returns the psqlrc psql would have read, if it exists.
+func userPsqlrc() string {
+	path := os.Getenv("PSQLRC")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		path = filepath.Join(home, ".psqlrc")
+	}
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	return path
+}
+
+// promptSetup labels the prompt of an external client: psql gets a
+// generated PSQLRC and pgcli a --prompt argument (pgcli prompts cannot be
+// colored from the command line). It returns the updated environment, the
+// arguments to put before the connection arguments and a cleanup for the
+// generated files.
+func promptSetup(client string, st sessionStyle, env []string) ([]string, []string, func(), error) {
+	if st.label == "" {
+		return env, nil, func() {}, nil
+	}
+	switch client {
+	case "psql":
+		dir, err := os.MkdirTemp(os.Getenv("XDG_RUNTIME_DIR"), "rds-psqlrc-")
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("create psqlrc dir: %w", err)
+		}
+		path := filepath.Join(dir, "psqlrc")
+		if err := os.WriteFile(path, []byte(psqlrcContent(st, userPsqlrc())), 0600); err != nil {
+			os.RemoveAll(dir)
+			return nil, nil, nil, fmt.Errorf("write psqlrc: %w", err)
+		}
+		return append(withoutVars(env, "PSQLRC"), "PSQLRC="+path), nil, func() { os.RemoveAll(dir) }, nil
+	case "pgcli":
+		return env, []string{"--prompt", st.label + ` \u@\h:\d> `}, func() {}, nil
+	}
+	return env, nil, func() {}, nil
+}
+
+// printBanner warns on stderr before a session starts in an environment
+// configured to warn (prod by default), showing who is connecting to what.
+func printBanner(ctx context.Context, st sessionStyle, profile string, target *Target, safety Safety) {
+	if !st.env.Warn {
+		return
+	}
+	rule := strings.Repeat("━", 20)
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, st.colored(fmt.Sprintf("%s 🚨 %s 🚨 %s", rule, st.env.Label(), rule)))
+	fmt.Fprintf(os.Stderr, "   Identity : %s\n", callerIdentity(ctx, target.Config))
+	fmt.Fprintf(os.Stderr, "   Profile  : %s\n", profile)
+	fmt.Fprintf(os.Stderr, "   Target   : %s (%s:%d) db=%s user=%s\n", target.Instance.ID, target.Host, target.Port, target.DB, target.Creds.Username)
+	mode := "read-write"
+	switch {
+	case safety.ReadOnly:
+		mode = "read-only"
+	case safety.Guard:
+		mode = "read-write (destructive statements need confirmation)"
+	}
+	fmt.Fprintf(os.Stderr, "   Session  : %s\n", mode)
+	fmt.Fprintln(os.Stderr, st.colored(strings.Repeat("━", 2*len(rule)+len(st.env.Label())+6)))
+	fmt.Fprintln(os.Stderr)
+}
+
+// callerIdentity returns the AWS principal ARN, or a placeholder when STS
+// cannot be reached.
+func callerIdentity(ctx context.Context, cfg aws.Config) string {
+	out, err := sts.NewFromConfig(cfg).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "unknown (" + err.Error() + ")"
+	}
+	return aws.ToString(out.Arn)
+}
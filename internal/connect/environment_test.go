@@ -0,0 +1,92 @@
+package connect
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/PraveenPrabhuT/rds/internal/core"
+)
+
+func TestPsqlrcContent(t *testing.T) {
+	style := sessionStyle{env: core.Environment{Name: "prod", Color: "red"}, label: "[PROD ackoprod/it's-100%]"}
+
+	t.Setenv("NO_COLOR", "1")
+	want := `\set PROMPT1 '[PROD ackoprod/it\'s-100%%] ' :PROMPT1
+\set PROMPT2 '[PROD ackoprod/it\'s-100%%] ' :PROMPT2
+`
+	if got := psqlrcContent(style, ""); got != want {
+		t.Errorf("no color:\ngot  %q\nwant %q", got, want)
+	}
+
+	t.Setenv("NO_COLOR", "")
+	got := psqlrcContent(style, "/home/me/.psqlrc")
+	if !strings.HasPrefix(got, "\\i '/home/me/.psqlrc'\n") {
+		t.Errorf("user psqlrc not included first:\n%s", got)
+	}
+	if !strings.Contains(got, `'%[%033[1;31m%][PROD ackoprod/it\'s-100%%]%[%033[0m%] ' :PROMPT1`) {
+		t.Errorf("colored PROMPT1 missing:\n%s", got)
+	}
+}
+
+func TestPromptSetup(t *testing.T) {
+	style := sessionStyle{env: core.Environment{Name: "prod"}, label: "[PROD p/db1]"}
+
+	env, args, cleanup, err := promptSetup("pgcli", style, []string{"A=1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cleanup()
+	if !reflect.DeepEqual(env, []string{"A=1"}) || !reflect.DeepEqual(args, []string{"--prompt", `[PROD p/db1] \u@\h:\d> `}) {
+		t.Errorf("pgcli: env %v args %v", env, args)
+	}
+
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	t.Setenv("PSQLRC", filepath.Join(t.TempDir(), "missing"))
+	env, args, cleanup, err = promptSetup("psql", style, []string{"PSQLRC=/old", "A=1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(args) != 0 || len(env) != 2 || env[0] != "A=1" || !strings.HasPrefix(env[1], "PSQLRC=") {
+		t.Fatalf("psql: env %v args %v", env, args)
+	}
+	path := strings.TrimPrefix(env[1], "PSQLRC=")
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("psqlrc mode %v, want 0600", info.Mode().Perm())
+	}
+	cleanup()
+	if _, err := os.Stat(filepath.Dir(path)); !os.IsNotExist(err) {
+		t.Errorf("psqlrc dir not removed: %v", err)
+	}
+
+	env, args, _, err = promptSetup("psql", sessionStyle{}, []string{"A=1"})
+	if err != nil || len(args) != 0 || !reflect.DeepEqual(env, []string{"A=1"}) {
+		t.Errorf("unclassified: env %v args %v err %v", env, args, err)
+	}
+}
+
+func TestResolveStyle(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("profiles:\n  ackoprod:\n    environment: prod\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("RDS_CONFIG", path)
+
+	st := resolveStyle("ackoprod", core.InstanceInfo{ID: "pricing-db"})
+	if st.label != "[PROD ackoprod/pricing-db]" || !st.env.Warn {
+		t.Errorf("prod profile: %+v", st)
+	}
+	st = resolveStyle("sandbox", core.InstanceInfo{ID: "x", Tags: map[string]string{"env": "dev"}})
+	if st.label != "[DEV sandbox/x]" || st.env.Warn {
+		t.Errorf("dev tag: %+v", st)
+	}
+	if st := resolveStyle("sandbox", core.InstanceInfo{ID: "x"}); st.label != "" {
+		t.Errorf("unclassified: %+v", st)
+	}
+}
@@ -0,0 +1,85 @@
+package connect
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// ExecOptions configures an `rds exec` run.
+type ExecOptions struct {
+	Options
+	Command     []string
+	SSLMode     string
+	DatabaseURL bool
+}
+
+// Exec resolves the target like Run and runs Command with the libpq PG*
+// environment (and optionally DATABASE_URL) pointing at it. The password is
+// passed through a temporary PGPASSFILE unless EnvPassword is set. The child gets
+// the terminal the same way pgcli/psql do; its exit status is returned as an
+// *exec.ExitError so callers can propagate the code. Read-only sessions are
+// requested through PGOPTIONS.
+func Exec(ctx context.Context, opts ExecOptions) error {
+	if len(opts.Command) == 0 {
+		return fmt.Errorf("no command given (usage: rds exec [instance] -- <command...>)")
+	}
+	if err := ValidateSSLMode(opts.SSLMode); err != nil {
+		return err
+	}
+
+	target, err := Resolve(ctx, opts.Options)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "🚀 Target: %s [%s] db=%s user=%s\n", target.Instance.ID, target.Host, target.DB, target.Creds.Username)
+
+	safety := ResolveSafety(opts.ReadOnly, opts.Profile)
+	printSafety(safety)
+	printBanner(ctx, resolveStyle(opts.Profile, target.Instance), opts.Profile, target, safety)
+
+	params := target.ConnParams(opts.SSLMode)
+	base := append(os.Environ(), envAssignments(params, opts.DatabaseURL, opts.EnvPassword)...)
+	if safety.ReadOnly {
+		base = readOnlyEnv(base)
+	}
+	env, cleanup, err := credentialEnv(base, params, opts.EnvPassword)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(opts.Command[0], opts.Command[1:]...)
+	cmd.Env = env
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	return startWithCleanup(cmd, cleanup)
+}
+
+// envAssignments renders the environment for p (plus DATABASE_URL when
+// requested) as NAME=value pairs for exec.Cmd.Env. PGPASSWORD is left to
+// credentialEnv, and DATABASE_URL only carries the password with
+// envPassword; libpq clients otherwise read it from the PGPASSFILE.
+func envAssignments(p ConnParams, databaseURL, envPassword bool) []string {
+	urlParams := p
+	if !envPassword {
+		urlParams.Password = ""
+	}
+	var env []string
+	for _, v := range connEnv(urlParams, databaseURL) {
+		if v.Name == "PGPASSWORD" {
+			continue
+		}
+		env = append(env, v.Name+"="+v.Value)
+	}
+	return env
+}
+
+// connEnv returns PGEnv for p, followed by DATABASE_URL as a libpq URI when
+// databaseURL is set.
+func connEnv(p ConnParams, databaseURL bool) []EnvVar {
+	vars := PGEnv(p)
+	if databaseURL {
+		vars = append(vars, EnvVar{"DATABASE_URL", formatURI("postgresql", p, sslQuery(p.SSLMode))})
+	}
+	return vars
+}
@@ -5,80 +5,61 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
-	"strings"
 
 	"github.com/PraveenPrabhuT/rds/internal/core"
-	"github.com/chzyer/readline"
-	"github.com/jackc/pgx/v5"
+	"github.com/PraveenPrabhuT/rds/internal/repl"
 )
 
 func buildConnectArgs(inst core.InstanceInfo, creds core.RDSCreds, dbname string) []string {
 	return []string{"-h", inst.Host, "-p", fmt.Sprintf("%d", inst.Port), "-U", creds.Username, "-d", dbname}
 }
 
-func executeExternal(bin string, inst core.InstanceInfo, creds core.RDSCreds, dbname string) {
-	args := buildConnectArgs(inst, creds, dbname)
-	cmd := exec.Command(bin, args...)
-	cmd.Env = append(os.Environ(), fmt.Sprintf("PGPASSWORD=%s", creds.Password))
-	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
-	_ = startAndWait(cmd)
-}
-
-func runNativeConnect(host string, port int32, user, password, dbname string) {
-	ctx := context.Background()
-	conn, err := core.NewPgxConn(ctx, host, port, user, password, dbname)
+// executeExternal launches an external client against inst and returns its
+// exit status (an *exec.ExitError on non-zero exit). The password is handed
+// over in a temporary PGPASSFILE unless envPassword opts into PGPASSWORD,
+// which other local users may read from /proc/<pid>/environ. A read-only
+// session is requested through PGOPTIONS, and psql and pgcli prompts are
+// labelled with the target's environment (see promptSetup).
+func executeExternal(client, bin string, args []string, inst core.InstanceInfo, creds core.RDSCreds, dbname string, envPassword bool, safety Safety, style sessionStyle) error {
+	params := ConnParams{Host: inst.Host, Port: inst.Port, User: creds.Username, Password: creds.Password, DB: dbname}
+	base := os.Environ()
+	if safety.ReadOnly {
+		base = readOnlyEnv(base)
+	}
+	base, promptArgs, promptCleanup, err := promptSetup(client, style, base)
 	if err != nil {
-		fmt.Printf("❌ Connection Error: %v\n", err)
-		return
+		return err
 	}
-	defer conn.Close(ctx)
-
-	fmt.Printf("✅ Connected to %s (Native Mode)\n", host)
-	rl, _ := readline.NewEx(&readline.Config{
-		Prompt: dbname + "=> ",
-	})
-	defer rl.Close()
-
-	for {
-		line, err := rl.Readline()
-		if err != nil {
-			break
-		}
-		query := strings.TrimSpace(line)
-		if query == "" {
-			continue
-		}
-		if query == "exit" || query == "quit" {
-			break
-		}
-		executeAndPrint(ctx, conn, query)
+	env, credCleanup, err := credentialEnv(base, params, envPassword)
+	if err != nil {
+		promptCleanup()
+		return err
 	}
+	cmd := exec.Command(bin, append(promptArgs, args...)...)
+	cmd.Env = env
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	return startWithCleanup(cmd, func() {
+		credCleanup()
+		promptCleanup()
+	})
 }
 
-func executeAndPrint(ctx context.Context, conn *pgx.Conn, query string) {
-	rows, err := conn.Query(ctx, query)
+// runNativeConnect runs the built-in REPL; its error (such as a failed
+// connection) is returned so that it becomes the exit status of rds.
+func runNativeConnect(host string, port int32, user, password, dbname string, safety Safety, style sessionStyle) error {
+	err := repl.Run(context.Background(), repl.Options{
+		Host:       host,
+		Port:       port,
+		User:       user,
+		Password:   password,
+		DB:         dbname,
+		ReadOnly:   safety.ReadOnly,
+		Guard:      safety.Guard,
+		Label:      style.label,
+		LabelColor: style.ansi(),
+	})
 	if err != nil {
-		fmt.Printf("ERROR: %v\n", err)
-		return
-	}
-	defer rows.Close()
-
-	cols := rows.FieldDescriptions()
-	colNames := make([]string, len(cols))
-	for i, fd := range cols {
-		colNames[i] = fd.Name
-	}
-	fmt.Println(strings.Join(colNames, " | "))
-
-	for rows.Next() {
-		values, err := rows.Values()
-		if err != nil {
-			fmt.Printf("ERROR scanning row: %v\n", err)
-			continue
-		}
-		for _, val := range values {
-			fmt.Printf("%v | ", val)
-		}
-		fmt.Println()
+		return fmt.Errorf("connection error: %w", err)
 	}
+	return nil
 }
@@ -1,6 +1,7 @@
 package connect
 
 import (
+	"net"
 	"testing"
 
 	"github.com/PraveenPrabhuT/rds/internal/core"
@@ -48,3 +49,18 @@ func TestBuildConnectArgs_MetabasePOC(t *testing.T) {
 		t.Errorf("buildConnectArgs (metabasedev-poc): user/db: %v", args[4:8])
 	}
 }
+
+func TestRunNativeConnect_ReturnsConnectionError(t *testing.T) {
+	// A port nothing listens on fails the connection right away.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := int32(l.Addr().(*net.TCPAddr).Port)
+	l.Close()
+
+	err = runNativeConnect("127.0.0.1", port, "app", "secret", "postgres", Safety{}, sessionStyle{})
+	if err == nil {
+		t.Fatal("runNativeConnect: expected the connection error")
+	}
+}
@@ -4,9 +4,12 @@ import (
 	"fmt"
 	"net"
 	"net/url"
-	"os/exec"
+	"os"
 	"strconv"
 	"strings"
+
+	"github.com/PraveenPrabhuT/rds/internal/clipboard"
+	"github.com/PraveenPrabhuT/rds/internal/core"
 )
 
 const jdbcPrefix = "jdbc:postgresql://"
@@ -68,21 +71,31 @@ func ResolveCNAME(host string) (string, error) {
 
 // BuildJDBCURL constructs a JDBC PostgreSQL URL with URL-encoded credentials.
 func BuildJDBCURL(host string, port int32, user, password, database string) string {
-	return fmt.Sprintf("jdbc:postgresql://%s:%d/%s?user=%s&password=%s&sslmode=require",
-		host, port,
-		url.PathEscape(database),
-		url.QueryEscape(user),
-		url.QueryEscape(password),
-	)
+	return formatJDBC(ConnParams{
+		Host:     host,
+		Port:     port,
+		User:     user,
+		Password: password,
+		DB:       database,
+		SSLMode:  DefaultSSLMode,
+	})
 }
 
-// copyToClipboard copies text to the system clipboard using pbcopy (macOS).
+// copyToClipboard copies text to the system clipboard using the backend and
+// auto-clear settings from the config file (autodetected by default).
 func copyToClipboard(text string) {
-	cmd := exec.Command("pbcopy")
-	cmd.Stdin = strings.NewReader(text)
-	if err := cmd.Run(); err != nil {
-		fmt.Printf("⚠️  Could not copy to clipboard: %v\n", err)
+	cfg, err := core.LoadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  %v (using clipboard defaults)\n", err)
+	}
+	backend, err := clipboard.Copy(text, cfg.Clipboard)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not copy to clipboard: %v\n", err)
+		return
+	}
+	if cfg.Clipboard.ClearAfter > 0 {
+		fmt.Fprintf(os.Stderr, "📋 Copied to clipboard via %s (clears in %ds)!\n", backend, cfg.Clipboard.ClearAfter)
 		return
 	}
-	fmt.Println("📋 Copied to clipboard!")
+	fmt.Fprintf(os.Stderr, "📋 Copied to clipboard via %s!\n", backend)
 }
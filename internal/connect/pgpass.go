@@ -0,0 +1,120 @@
+package connect
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// pgpassFile is a temporary libpq password file that only lives as long as
+// the child process using it.
+type pgpassFile struct {
+	dir  string
+	path string
+	once sync.Once
+}
+
+// writePgpass writes a 0600 password file for p inside a fresh 0700
+// directory, preferring the per-user runtime dir (tmpfs on most Linux
+// systems) over the shared temp dir. The database field is a wildcard so
+// \c to another database on the same server keeps working.
+func writePgpass(p ConnParams) (*pgpassFile, error) {
+	dir, err := os.MkdirTemp(os.Getenv("XDG_RUNTIME_DIR"), "rds-pgpass-")
+	if err != nil {
+		return nil, fmt.Errorf("create pgpass dir: %w", err)
+	}
+	path := filepath.Join(dir, "pgpass")
+	line := pgpassLine(p.Host, fmt.Sprintf("%d", p.Port), "*", p.User, p.Password)
+	if err := os.WriteFile(path, []byte(line), 0600); err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("write pgpass file: %w", err)
+	}
+	return &pgpassFile{dir: dir, path: path}, nil
+}
+
+// Remove overwrites the password file before unlinking it. It is safe to
+// call more than once.
+func (f *pgpassFile) Remove() {
+	f.once.Do(func() {
+		if info, err := os.Stat(f.path); err == nil {
+			_ = os.WriteFile(f.path, make([]byte, info.Size()), 0600)
+		}
+		os.RemoveAll(f.dir)
+	})
+}
+
+// pgpassLine renders one hostname:port:database:username:password entry,
+// escaping ':' and '\' as libpq requires.
+func pgpassLine(fields ...string) string {
+	r := strings.NewReplacer(`\`, `\\`, `:`, `\:`)
+	escaped := make([]string, len(fields))
+	for i, f := range fields {
+		if f == "*" {
+			escaped[i] = f
+			continue
+		}
+		escaped[i] = r.Replace(f)
+	}
+	return strings.Join(escaped, ":") + "\n"
+}
+
+// credentialEnv returns base with the password for p supplied through a
+// temporary PGPASSFILE, or through PGPASSWORD when envPassword is set. Any
+// inherited PGPASSWORD/PGPASSFILE is dropped since PGPASSWORD would take
+// precedence over the file. The returned cleanup removes the file.
+func credentialEnv(base []string, p ConnParams, envPassword bool) ([]string, func(), error) {
+	env := withoutVars(base, "PGPASSWORD", "PGPASSFILE")
+	if envPassword {
+		return append(env, "PGPASSWORD="+p.Password), func() {}, nil
+	}
+	f, err := writePgpass(p)
+	if err != nil {
+		return nil, nil, err
+	}
+	return append(env, "PGPASSFILE="+f.path), f.Remove, nil
+}
+
+func withoutVars(env []string, names ...string) []string {
+	out := make([]string, 0, len(env))
+outer:
+	for _, kv := range env {
+		for _, n := range names {
+			if strings.HasPrefix(kv, n+"=") {
+				continue outer
+			}
+		}
+		out = append(out, kv)
+	}
+	return out
+}
+
+// startWithCleanup runs cmd via startAndWait and calls cleanup once it exits.
+// If rds itself is interrupted or terminated first, cleanup still runs
+// before the signal is re-raised with its default action.
+func startWithCleanup(cmd *exec.Cmd, cleanup func()) error {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case sig := <-sigs:
+			cleanup()
+			signal.Stop(sigs)
+			if self, err := os.FindProcess(os.Getpid()); err == nil {
+				_ = self.Signal(sig)
+			}
+		case <-done:
+		}
+	}()
+
+	err := startAndWait(cmd)
+	close(done)
+	signal.Stop(sigs)
+	cleanup()
+	return err
+}
@@ -0,0 +1,69 @@
+package connect
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestPgpassLine_Escaping(t *testing.T) {
+	got := pgpassLine("db.example.com", "5432", "*", "app", `p:a\ss`)
+	want := "db.example.com:5432:*:app:p\\:a\\\\ss\n"
+	if got != want {
+		t.Errorf("pgpassLine: got %q, want %q", got, want)
+	}
+}
+
+func TestWritePgpass_PermissionsAndRemove(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	p := ConnParams{Host: "db.example.com", Port: 5432, User: "app", Password: "s3cr3t", DB: "pricing"}
+
+	f, err := writePgpass(p)
+	if err != nil {
+		t.Fatalf("writePgpass: %v", err)
+	}
+	info, err := os.Stat(f.path)
+	if err != nil {
+		t.Fatalf("stat pgpass: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("pgpass permissions: got %o, want 600", perm)
+	}
+	data, _ := os.ReadFile(f.path)
+	if string(data) != "db.example.com:5432:*:app:s3cr3t\n" {
+		t.Errorf("pgpass content: got %q", data)
+	}
+
+	f.Remove()
+	f.Remove()
+	if _, err := os.Stat(f.dir); !os.IsNotExist(err) {
+		t.Errorf("pgpass dir should be removed, stat err: %v", err)
+	}
+}
+
+func TestCredentialEnv(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	p := ConnParams{Host: "h", Port: 5432, User: "u", Password: "pw"}
+	base := []string{"HOME=/home/u", "PGPASSWORD=stale", "PGPASSFILE=/old"}
+
+	env, cleanup, err := credentialEnv(base, p, false)
+	if err != nil {
+		t.Fatalf("credentialEnv: %v", err)
+	}
+	defer cleanup()
+	joined := strings.Join(env, "\n")
+	if strings.Contains(joined, "PGPASSWORD") || strings.Contains(joined, "/old") {
+		t.Errorf("credentialEnv: inherited password vars leaked: %v", env)
+	}
+	if !strings.Contains(joined, "PGPASSFILE=") || !strings.Contains(joined, "HOME=/home/u") {
+		t.Errorf("credentialEnv: got %v", env)
+	}
+
+	env, _, err = credentialEnv(base, p, true)
+	if err != nil {
+		t.Fatalf("credentialEnv (env password): %v", err)
+	}
+	if env[len(env)-1] != "PGPASSWORD=pw" || strings.Contains(strings.Join(env, "\n"), "PGPASSFILE") {
+		t.Errorf("credentialEnv (env password): got %v", env)
+	}
+}
@@ -0,0 +1,144 @@
+package connect
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/PraveenPrabhuT/rds/internal/core"
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// Target is a resolved connection target: the selected instance, the
+// endpoint to dial, the database and the credentials to log in with.
+type Target struct {
+	Instance   core.InstanceInfo
+	Host       string
+	Port       int32
+	DB         string
+	Creds      core.RDSCreds
+	Config     aws.Config
+	HomeRegion string
+}
+
+// ConnParams returns the connection parameters for the target.
+func (t *Target) ConnParams(sslMode string) ConnParams {
+	return ConnParams{
+		Host:     t.Host,
+		Port:     t.Port,
+		User:     t.Creds.Username,
+		Password: t.Creds.Password,
+		DB:       t.DB,
+		SSLMode:  sslMode,
+	}
+}
+
+// Resolve performs the optional VPN check, instance selection (JDBC URL,
+// host, name, last used or interactive picker) and credential fetch shared by
+// every command that talks to an instance. Progress messages go to stderr so
+// stdout stays clean for commands whose output is meant to be piped or eval'd.
+func Resolve(ctx context.Context, opts Options) (*Target, error) {
+	if err := core.CheckVPNWithPritunl(opts.Profile); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  VPN check: %v (continuing anyway)\n", err)
+	}
+
+	cfg, homeRegion, err := core.LoadAWSConfig(ctx, opts.Profile, opts.Region)
+	if err != nil {
+		return nil, err
+	}
+
+	instances, err := core.GetInstancesWithCache(ctx, cfg, opts.Profile)
+	if err != nil {
+		return nil, fmt.Errorf("fetch instances: %w", err)
+	}
+
+	var selected core.InstanceInfo
+	var selectErr error
+	var connectHost string
+	var connectPort int32
+	dbname := opts.DB
+	if dbname == "" {
+		dbname = "postgres"
+	}
+
+	if opts.JDBCURL != "" {
+		urlHost, urlPort, urlDB, parseErr := ParseJDBCURL(opts.JDBCURL)
+		if parseErr != nil {
+			return nil, fmt.Errorf("parse JDBC URL: %w", parseErr)
+		}
+
+		connectHost = urlHost
+		connectPort = int32(urlPort)
+		dbname = urlDB
+
+		resolved, err := ResolveCNAME(urlHost)
+		if err != nil {
+			return nil, fmt.Errorf("resolve CNAME for %s: %w", urlHost, err)
+		}
+
+		selected, selectErr = core.FindInstanceByEndpoint(instances, resolved)
+		if selectErr != nil {
+			// Private IPs in JDBC URLs often do not match DNS resolution of RDS
+			// hostnames from a laptop (different network path than VPC).
+			if len(opts.Args) > 0 {
+				selected, selectErr = core.FindByName(instances, opts.Args[0])
+				if selectErr != nil {
+					return nil, fmt.Errorf("could not map JDBC host %q to an RDS endpoint, and instance %q: %w", urlHost, opts.Args[0], selectErr)
+				}
+			} else {
+				fmt.Fprintf(os.Stderr, "⚠️  Could not map JDBC host %q to an RDS endpoint (typical for private IPs). Pick the instance to load Secrets Manager credentials.\n", urlHost)
+				selected, selectErr = core.PickWithFuzzyFinder(instances)
+				if selectErr != nil {
+					return nil, fmt.Errorf("selection: %w", selectErr)
+				}
+			}
+		}
+	} else {
+		switch {
+		case opts.Host != "":
+			selected, selectErr = core.FindInstanceByEndpoint(instances, opts.Host)
+		case len(opts.Args) > 0:
+			selected, selectErr = core.FindByName(instances, opts.Args[0])
+		case opts.LastConnected:
+			selected, selectErr = core.LoadLastConnected(instances, opts.Profile)
+		default:
+			selected, selectErr = core.PickWithFuzzyFinder(instances)
+		}
+
+		if selectErr != nil {
+			return nil, fmt.Errorf("selection: %w", selectErr)
+		}
+
+		connectHost = selected.Host
+		connectPort = selected.Port
+	}
+
+	if opts.Port != 0 {
+		connectPort = int32(opts.Port)
+	}
+
+	var creds core.RDSCreds
+	if opts.As != "" {
+		if dbname == "postgres" {
+			return nil, fmt.Errorf("--as requires --db (role credentials are stored per database)")
+		}
+		creds, err = core.GetDatabaseUserCredentials(ctx, cfg, selected, homeRegion, dbname, opts.As)
+	} else {
+		creds, err = core.GetRDSCredentials(ctx, cfg, selected, homeRegion)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("secrets: %w", err)
+	}
+
+	core.SaveLastID(selected.ID, opts.Profile)
+
+	return &Target{
+		Instance:   selected,
+		Host:       connectHost,
+		Port:       connectPort,
+		DB:         dbname,
+		Creds:      creds,
+		Config:     cfg,
+		HomeRegion: homeRegion,
+	}, nil
+}
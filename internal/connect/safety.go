@@ -0,0 +1,69 @@
+package connect
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/PraveenPrabhuT/rds/internal/core"
+)
+
+// readOnlyOption is the libpq PGOPTIONS switch that starts a read-only
+// session.
+const readOnlyOption = "-c default_transaction_read_only=on"
+
+// Safety is the resolved write protection of a session.
+type Safety struct {
+	// ReadOnly starts the session with default_transaction_read_only=on.
+	ReadOnly bool
+	// Guard asks for typed confirmation before destructive statements.
+	Guard bool
+}
+
+// ResolveSafety combines --read-only (nil when not given) with the
+// profile's read_only and protected settings. Writes on a read-only
+// profile are only possible with an explicit --read-only=false, and are
+// then guarded.
+func ResolveSafety(flag *bool, profile string) Safety {
+	cfg, err := core.LoadConfig()
+	if err != nil {
+		cfg = core.Config{}
+	}
+	p := cfg.Profile(profile)
+
+	readOnly := p.ReadOnly
+	if flag != nil {
+		readOnly = *flag
+	}
+	return Safety{
+		ReadOnly: readOnly,
+		Guard:    !readOnly && (p.Protected || p.ReadOnly),
+	}
+}
+
+// readOnlyEnv adds the read-only switch to PGOPTIONS in env, keeping any
+// options the user already set, so libpq clients (psql, pgcli) start
+// read-only sessions too.
+func readOnlyEnv(env []string) []string {
+	existing := ""
+	for _, kv := range env {
+		if v, ok := strings.CutPrefix(kv, "PGOPTIONS="); ok {
+			existing = v
+		}
+	}
+	value := readOnlyOption
+	if existing != "" {
+		value = existing + " " + readOnlyOption
+	}
+	return append(withoutVars(env, "PGOPTIONS"), "PGOPTIONS="+value)
+}
+
+// printSafety tells the user on stderr when a session is write-protected.
+func printSafety(s Safety) {
+	switch {
+	case s.ReadOnly:
+		fmt.Fprintln(os.Stderr, "🔒 Read-only session (default_transaction_read_only=on)")
+	case s.Guard:
+		fmt.Fprintln(os.Stderr, "⚠️  Writes allowed on a protected profile: destructive statements need confirmation")
+	}
+}
@@ -0,0 +1,56 @@
+package connect
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestResolveSafety(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	config := `profiles:
+  prod:
+    read_only: true
+  staging:
+    protected: true
+`
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("RDS_CONFIG", path)
+
+	on, off := true, false
+	tests := []struct {
+		name    string
+		flag    *bool
+		profile string
+		want    Safety
+	}{
+		{"default profile", nil, "dev", Safety{}},
+		{"flag on", &on, "dev", Safety{ReadOnly: true}},
+		{"read-only profile", nil, "prod", Safety{ReadOnly: true}},
+		{"read-only profile overridden", &off, "prod", Safety{Guard: true}},
+		{"protected profile", nil, "staging", Safety{Guard: true}},
+		{"protected profile read-only", &on, "staging", Safety{ReadOnly: true}},
+	}
+	for _, tt := range tests {
+		if got := ResolveSafety(tt.flag, tt.profile); got != tt.want {
+			t.Errorf("%s: ResolveSafety = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestReadOnlyEnv(t *testing.T) {
+	got := readOnlyEnv([]string{"HOME=/root"})
+	want := []string{"HOME=/root", "PGOPTIONS=-c default_transaction_read_only=on"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("readOnlyEnv: got %q, want %q", got, want)
+	}
+
+	got = readOnlyEnv([]string{"PGOPTIONS=-c statement_timeout=5s", "HOME=/root"})
+	want = []string{"HOME=/root", "PGOPTIONS=-c statement_timeout=5s -c default_transaction_read_only=on"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("readOnlyEnv (existing): got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,46 @@
+package connect
+
+import (
+	"context"
+	"fmt"
+)
+
+// URLOptions configures an `rds url` run.
+type URLOptions struct {
+	Options
+	Format     string
+	SSLMode    string
+	NoPassword bool
+	Copy       bool
+}
+
+// PrintURL resolves the target like Run and prints its connection string in
+// the requested format to stdout, without launching a client.
+func PrintURL(ctx context.Context, opts URLOptions) error {
+	if err := ValidateSSLMode(opts.SSLMode); err != nil {
+		return err
+	}
+	if _, err := FormatConnString(opts.Format, ConnParams{}); err != nil {
+		return err
+	}
+
+	target, err := Resolve(ctx, opts.Options)
+	if err != nil {
+		return err
+	}
+
+	params := target.ConnParams(opts.SSLMode)
+	if opts.NoPassword {
+		params.Password = ""
+	}
+	out, err := FormatConnString(opts.Format, params)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(out)
+	if opts.Copy {
+		copyToClipboard(out)
+	}
+	return nil
+}
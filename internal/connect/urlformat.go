@@ -0,0 +1,235 @@
+package connect
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// DefaultSSLMode is the sslmode used for every generated connection string
+// unless overridden; RDS accepts TLS on every instance.
+const DefaultSSLMode = "require"
+
+// URLFormats lists the connection string formats supported by FormatConnString.
+var URLFormats = []string{"jdbc", "libpq-uri", "dsn", "sqlalchemy", "go-pgx", "dotnet", "env", "prisma"}
+
+var sslModes = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+
+// ConnParams holds everything needed to render a connection string.
+// An empty Password is omitted from the output.
+type ConnParams struct {
+	Host     string
+	Port     int32
+	User     string
+	Password string
+	DB       string
+	SSLMode  string
+}
+
+// EnvVar is a single environment variable assignment.
+type EnvVar struct {
+	Name  string
+	Value string
+}
+
+// ValidateSSLMode returns an error unless mode is one of the libpq sslmode values.
+func ValidateSSLMode(mode string) error {
+	for _, m := range sslModes {
+		if m == mode {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid sslmode %q (want one of %s)", mode, strings.Join(sslModes, ", "))
+}
+
+// FormatConnString renders p in the given format (see URLFormats).
+func FormatConnString(format string, p ConnParams) (string, error) {
+	switch format {
+	case "jdbc":
+		return formatJDBC(p), nil
+	case "libpq-uri":
+		return formatURI("postgresql", p, sslQuery(p.SSLMode)), nil
+	case "go-pgx":
+		return formatURI("postgres", p, sslQuery(p.SSLMode)), nil
+	case "sqlalchemy":
+		return formatURI("postgresql+psycopg2", p, sslQuery(p.SSLMode)), nil
+	case "prisma":
+		return formatURI("postgresql", p, prismaSSLQuery(p.SSLMode)), nil
+	case "dsn":
+		return formatDSN(p), nil
+	case "dotnet":
+		return formatDotNet(p), nil
+	case "env":
+		return formatEnv(p), nil
+	}
+	return "", fmt.Errorf("unknown format %q (want one of %s)", format, strings.Join(URLFormats, ", "))
+}
+
+// PGEnv returns the libpq environment variables (PGHOST, PGPORT, ...) for p.
+// PGPASSWORD is left out when p has no password and PGSSLMODE when no sslmode is set.
+func PGEnv(p ConnParams) []EnvVar {
+	vars := []EnvVar{
+		{"PGHOST", p.Host},
+		{"PGPORT", strconv.Itoa(int(p.Port))},
+		{"PGUSER", p.User},
+	}
+	if p.Password != "" {
+		vars = append(vars, EnvVar{"PGPASSWORD", p.Password})
+	}
+	vars = append(vars, EnvVar{"PGDATABASE", p.DB})
+	if p.SSLMode != "" {
+		vars = append(vars, EnvVar{"PGSSLMODE", p.SSLMode})
+	}
+	return vars
+}
+
+func formatJDBC(p ConnParams) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "jdbc:postgresql://%s:%d/%s?user=%s", p.Host, p.Port, url.PathEscape(p.DB), url.QueryEscape(p.User))
+	if p.Password != "" {
+		b.WriteString("&password=" + url.QueryEscape(p.Password))
+	}
+	if p.SSLMode != "" {
+		b.WriteString("&sslmode=" + url.QueryEscape(p.SSLMode))
+	}
+	return b.String()
+}
+
+// formatURI renders a postgresql:// style URI. User, password and database
+// are percent-encoded so any character survives every URI parser.
+func formatURI(scheme string, p ConnParams, query string) string {
+	var b strings.Builder
+	b.WriteString(scheme + "://" + uriEscape(p.User))
+	if p.Password != "" {
+		b.WriteString(":" + uriEscape(p.Password))
+	}
+	b.WriteString("@" + net.JoinHostPort(p.Host, strconv.Itoa(int(p.Port))))
+	b.WriteString("/" + uriEscape(p.DB))
+	if query != "" {
+		b.WriteString("?" + query)
+	}
+	return b.String()
+}
+
+func sslQuery(mode string) string {
+	if mode == "" {
+		return ""
+	}
+	return "sslmode=" + mode
+}
+
+// prismaSSLQuery maps libpq sslmodes onto Prisma's narrower set
+// (disable, prefer, require plus sslaccept for certificate verification).
+func prismaSSLQuery(mode string) string {
+	switch mode {
+	case "":
+		return ""
+	case "allow":
+		return "sslmode=prefer"
+	case "verify-ca", "verify-full":
+		return "sslmode=require&sslaccept=strict"
+	}
+	return "sslmode=" + mode
+}
+
+// uriEscape percent-encodes everything outside the RFC 3986 unreserved set.
+func uriEscape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if isUnreserved(c) {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
+
+func isUnreserved(c byte) bool {
+	return (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+// formatDSN renders a libpq keyword/value connection string.
+func formatDSN(p ConnParams) string {
+	parts := []string{
+		"host=" + dsnQuote(p.Host),
+		fmt.Sprintf("port=%d", p.Port),
+		"user=" + dsnQuote(p.User),
+	}
+	if p.Password != "" {
+		parts = append(parts, "password="+dsnQuote(p.Password))
+	}
+	parts = append(parts, "dbname="+dsnQuote(p.DB))
+	if p.SSLMode != "" {
+		parts = append(parts, "sslmode="+p.SSLMode)
+	}
+	return strings.Join(parts, " ")
+}
+
+// dsnQuote single-quotes a libpq keyword value when it is empty or contains
+// whitespace, quotes or backslashes.
+func dsnQuote(v string) string {
+	if v != "" && !strings.ContainsAny(v, " \t\n'\\") {
+		return v
+	}
+	r := strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+	return "'" + r.Replace(v) + "'"
+}
+
+var dotnetSSLModes = map[string]string{
+	"disable":     "Disable",
+	"allow":       "Allow",
+	"prefer":      "Prefer",
+	"require":     "Require",
+	"verify-ca":   "VerifyCA",
+	"verify-full": "VerifyFull",
+}
+
+// formatDotNet renders an Npgsql connection string.
+func formatDotNet(p ConnParams) string {
+	parts := []string{
+		"Host=" + dotnetQuote(p.Host),
+		fmt.Sprintf("Port=%d", p.Port),
+		"Database=" + dotnetQuote(p.DB),
+		"Username=" + dotnetQuote(p.User),
+	}
+	if p.Password != "" {
+		parts = append(parts, "Password="+dotnetQuote(p.Password))
+	}
+	if mode, ok := dotnetSSLModes[p.SSLMode]; ok {
+		parts = append(parts, "SSL Mode="+mode)
+	}
+	return strings.Join(parts, ";")
+}
+
+// dotnetQuote double-quotes a connection string value (doubling embedded
+// quotes) when it would otherwise be split or trimmed by the parser.
+func dotnetQuote(v string) string {
+	if v != "" && !strings.ContainsAny(v, `;"'=`) && strings.TrimSpace(v) == v {
+		return v
+	}
+	return `"` + strings.ReplaceAll(v, `"`, `""`) + `"`
+}
+
+// formatEnv renders PGEnv as NAME=value lines that both dotenv loaders and
+// POSIX shells accept.
+func formatEnv(p ConnParams) string {
+	var lines []string
+	for _, v := range PGEnv(p) {
+		lines = append(lines, v.Name+"="+shellQuote(v.Value))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// shellQuote single-quotes v for POSIX shells unless it consists only of
+// characters that need no quoting.
+func shellQuote(v string) string {
+	if v != "" && strings.Trim(v, "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-._/:@%+,") == "" {
+		return v
+	}
+	return "'" + strings.ReplaceAll(v, "'", `'\''`) + "'"
+}
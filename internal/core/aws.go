@@ -5,8 +5,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
@@ -58,24 +60,32 @@ func GetInstancesWithCache(ctx context.Context, cfg aws.Config, profile string)
 		}
 	}
 
-	fmt.Printf("🔍 Fetching RDS instances [%s:%s]...\n", profile, cfg.Region)
+	fmt.Fprintf(os.Stderr, "🔍 Fetching RDS instances [%s:%s]...\n", profile, cfg.Region)
 
 	rdsClient := rds.NewFromConfig(cfg)
-	out, err := rdsClient.DescribeDBInstances(ctx, &rds.DescribeDBInstancesInput{})
-	if err != nil {
-		return nil, err
-	}
-
 	var instances []InstanceInfo
-	for _, db := range out.DBInstances {
-		if aws.ToString(db.Engine) == "postgres" {
+	pages := rds.NewDescribeDBInstancesPaginator(rdsClient, &rds.DescribeDBInstancesInput{})
+	for pages.HasMorePages() {
+		out, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, db := range out.DBInstances {
+			if aws.ToString(db.Engine) != "postgres" || db.Endpoint == nil {
+				continue
+			}
+			tags := make(map[string]string, len(db.TagList))
+			for _, t := range db.TagList {
+				tags[aws.ToString(t.Key)] = aws.ToString(t.Value)
+			}
 			instances = append(instances, InstanceInfo{
 				ID:       aws.ToString(db.DBInstanceIdentifier),
 				Host:     aws.ToString(db.Endpoint.Address),
 				Size:     aws.ToString(db.DBInstanceClass),
-				Port:     *db.Endpoint.Port,
+				Port:     aws.ToInt32(db.Endpoint.Port),
 				Version:  aws.ToString(db.EngineVersion),
 				SourceID: aws.ToString(db.ReadReplicaSourceDBInstanceIdentifier),
+				Tags:     tags,
 			})
 		}
 	}
@@ -139,7 +149,7 @@ func regionFromSecretARN(arn string) string {
 func GetRDSCredentials(ctx context.Context, cfg aws.Config, selected InstanceInfo, homeRegion string) (RDSCreds, error) {
 	secretTargetID := InstanceSecretTargetID(selected)
 	if selected.SourceID != "" && strings.HasPrefix(selected.SourceID, "arn:aws:rds:") && secretTargetID != selected.ID {
-		fmt.Printf("🌐 DR Replica detected. Fetching master secret '%s' from primary region: %s\n",
+		fmt.Fprintf(os.Stderr, "🌐 DR Replica detected. Fetching master secret '%s' from primary region: %s\n",
 			secretTargetID, homeRegion)
 	}
 
@@ -217,3 +227,96 @@ func getRDSCredentialsFromManagedSecret(ctx context.Context, cfg aws.Config, sel
 	}
 	return creds, nil
 }
+
+// DatabaseRoles are the roles of the built-in `rds db create` template that
+// DatabaseUsername resolves.
+var DatabaseRoles = []string{"migration", "owner", "ro_v1", "ro_v2", "rw_v1", "rw_v2", "iam"}
+
+// DatabaseUsername maps a role to the username provisioned by `rds db create`
+// for dbName. "migration" and "owner" select the migration user (the bare
+// database name) and the other built-in roles append their name to the
+// database name. Roles from custom templates are not known here and are
+// rejected; GetDatabaseUserCredentials finds them in the stored secret.
+func DatabaseUsername(dbName, role string) (string, error) {
+	switch role {
+	case "migration", "owner":
+		return dbName, nil
+	case "ro_v1", "ro_v2", "rw_v1", "rw_v2", "iam":
+		return dbName + "_" + role, nil
+	}
+	return "", fmt.Errorf("unknown role %q (want one of %s)", role, strings.Join(DatabaseRoles, ", "))
+}
+
+// GetDatabaseUserCredentials fetches the credentials of a user provisioned by
+// `rds db create` from the <dbName>/<instanceID>/psql secret in the home region.
+// The secret holds a {"username": "password"} map; role is resolved with
+// resolveDatabaseRole.
+func GetDatabaseUserCredentials(ctx context.Context, cfg aws.Config, selected InstanceInfo, homeRegion, dbName, role string) (RDSCreds, error) {
+	payload, err := fetchStoredCredentials(ctx, cfg, selected, homeRegion, dbName)
+	if err != nil {
+		return RDSCreds{}, err
+	}
+	username, err := resolveDatabaseRole(payload, dbName, role)
+	if err != nil {
+		return RDSCreds{}, err
+	}
+	return lookupDatabaseUser(payload, dbName, username)
+}
+
+// GetStoredUserCredentials fetches the credentials of username from the
+// <dbName>/<instanceID>/psql secret in the home region.
+func GetStoredUserCredentials(ctx context.Context, cfg aws.Config, selected InstanceInfo, homeRegion, dbName, username string) (RDSCreds, error) {
+	payload, err := fetchStoredCredentials(ctx, cfg, selected, homeRegion, dbName)
+	if err != nil {
+		return RDSCreds{}, err
+	}
+	return lookupDatabaseUser(payload, dbName, username)
+}
+
+// fetchStoredCredentials reads the {"username": "password"} map of the
+// <dbName>/<instanceID>/psql secret in the home region.
+func fetchStoredCredentials(ctx context.Context, cfg aws.Config, selected InstanceInfo, homeRegion, dbName string) (map[string]string, error) {
+	sm := secretsmanager.NewFromConfig(cfg, func(o *secretsmanager.Options) {
+		o.Region = homeRegion
+	})
+
+	secretID := fmt.Sprintf("%s/%s/psql", dbName, InstanceSecretTargetID(selected))
+	out, err := sm.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{SecretId: &secretID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch secret '%s' in %s: %w", secretID, homeRegion, err)
+	}
+
+	var payload map[string]string
+	if err := json.Unmarshal([]byte(aws.ToString(out.SecretString)), &payload); err != nil {
+		return nil, fmt.Errorf("parse secret '%s': %w", secretID, err)
+	}
+	return payload, nil
+}
+
+// resolveDatabaseRole maps role to a username of the stored payload. The
+// built-in roles resolve with DatabaseUsername; a role of a custom template
+// (such as analytics with suffix _analytics) resolves to the stored user
+// <dbName>_<role>, and a stored username of the database is taken as is.
+func resolveDatabaseRole(payload map[string]string, dbName, role string) (string, error) {
+	if username, err := DatabaseUsername(dbName, role); err == nil {
+		return username, nil
+	}
+	for _, candidate := range []string{dbName + "_" + role, role} {
+		if _, ok := payload[candidate]; ok && strings.HasPrefix(candidate, dbName) {
+			return candidate, nil
+		}
+	}
+	stored := slices.Sorted(maps.Keys(payload))
+	return "", fmt.Errorf("no user for role %q in the stored credentials for database '%s' (stored users: %s)",
+		role, dbName, strings.Join(stored, ", "))
+}
+
+// lookupDatabaseUser picks the credentials of username out of a stored
+// {"username": "password"} map.
+func lookupDatabaseUser(payload map[string]string, dbName, username string) (RDSCreds, error) {
+	password, ok := payload[username]
+	if !ok {
+		return RDSCreds{}, fmt.Errorf("user '%s' not found in stored credentials for database '%s'", username, dbName)
+	}
+	return RDSCreds{Username: username, Password: password}, nil
+}
@@ -151,3 +151,73 @@ func TestGetInstancesWithCache_CacheHit(t *testing.T) {
 		t.Errorf("GetInstancesWithCache: got %+v", got)
 	}
 }
+
+func TestDatabaseUsername(t *testing.T) {
+	tests := []struct {
+		role    string
+		want    string
+		wantErr bool
+	}{
+		{"migration", "pricing", false},
+		{"owner", "pricing", false},
+		{"ro_v1", "pricing_ro_v1", false},
+		{"rw_v2", "pricing_rw_v2", false},
+		{"iam", "pricing_iam", false},
+		{"analytics", "", true},
+		{"pricing_ro_v2", "", true},
+	}
+	for _, tt := range tests {
+		got, err := DatabaseUsername("pricing", tt.role)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("DatabaseUsername(pricing, %q) = %q, %v; want %q, error %v", tt.role, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestLookupDatabaseUser(t *testing.T) {
+	payload := map[string]string{
+		"pricing":       "owner-pw",
+		"pricing_ro_v1": "ro-pw",
+	}
+
+	creds, err := lookupDatabaseUser(payload, "pricing", "pricing_ro_v1")
+	if err != nil {
+		t.Fatalf("lookupDatabaseUser: %v", err)
+	}
+	if creds.Username != "pricing_ro_v1" || creds.Password != "ro-pw" {
+		t.Errorf("lookupDatabaseUser: got %+v", creds)
+	}
+
+	if _, err := lookupDatabaseUser(payload, "pricing", "pricing_rw_v1"); err == nil {
+		t.Error("lookupDatabaseUser: expected error for missing user")
+	}
+}
+
+func TestResolveDatabaseRole(t *testing.T) {
+	payload := map[string]string{
+		"pricing":           "owner-pw",
+		"pricing_ro_v1":     "ro-pw",
+		"pricing_analytics": "analytics-pw",
+		"someone_else":      "other-pw",
+	}
+	tests := []struct {
+		role    string
+		want    string
+		wantErr bool
+	}{
+		{"migration", "pricing", false},
+		{"ro_v1", "pricing_ro_v1", false},
+		// Built-in roles resolve even when missing; the lookup reports it.
+		{"rw_v1", "pricing_rw_v1", false},
+		{"analytics", "pricing_analytics", false},
+		{"pricing_analytics", "pricing_analytics", false},
+		{"someone_else", "", true},
+		{"reporting", "", true},
+	}
+	for _, tt := range tests {
+		got, err := resolveDatabaseRole(payload, "pricing", tt.role)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("resolveDatabaseRole(%q) = %q, %v; want %q, error %v", tt.role, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
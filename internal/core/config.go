@@ -0,0 +1,79 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// Config is the optional user configuration file. A missing file yields the
+// zero Config, which keeps every built-in default.
+type Config struct {
+	Clipboard    ClipboardConfig              `yaml:"clipboard"`
+	Profiles     map[string]ProfileConfig     `yaml:"profiles"`
+	Environments map[string]EnvironmentConfig `yaml:"environments"`
+	// EnvironmentTag is the RDS instance tag naming its environment. Empty
+	// checks "environment" and then "env".
+	EnvironmentTag string `yaml:"environment_tag"`
+}
+
+// ProfileConfig holds per-AWS-profile defaults.
+type ProfileConfig struct {
+	// Client is the default interactive client: pgcli, psql, usql or native.
+	// Empty keeps the pgcli > psql > native preference order.
+	Client string `yaml:"client"`
+	// ReadOnly starts sessions with default_transaction_read_only=on unless
+	// --read-only=false is given.
+	ReadOnly bool `yaml:"read_only"`
+	// Protected asks for typed confirmation before destructive statements
+	// when writes are allowed. Read-only profiles are always protected.
+	Protected bool `yaml:"protected"`
+	// Environment classifies every instance of the profile (prod, staging,
+	// dev, ...) unless the instance carries an environment tag.
+	Environment string `yaml:"environment"`
+}
+
+// Profile returns the settings for an AWS profile (zero value if absent).
+func (c Config) Profile(name string) ProfileConfig {
+	return c.Profiles[name]
+}
+
+// ClipboardConfig controls how --copy places text on the clipboard.
+type ClipboardConfig struct {
+	// Backend is one of auto, pbcopy, wl-copy, xclip, xsel or osc52.
+	// Empty means auto.
+	Backend string `yaml:"backend"`
+	// ClearAfter clears the clipboard this many seconds after copying,
+	// unless it has changed in the meantime. 0 disables clearing.
+	ClearAfter int `yaml:"clear_after"`
+}
+
+// GetConfigPath returns the path of the config file.
+// Respects the RDS_CONFIG environment variable.
+func GetConfigPath() string {
+	if p := os.Getenv("RDS_CONFIG"); p != "" {
+		return p
+	}
+	return filepath.Join(os.Getenv("HOME"), ".config", "rds", "config.yaml")
+}
+
+// LoadConfig reads and parses the config file.
+func LoadConfig() (Config, error) {
+	var cfg Config
+	path := GetConfigPath()
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, fmt.Errorf("read config %s: %w", path, err)
+	}
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("parse config %s: %w", path, err)
+	}
+	return cfg, nil
+}
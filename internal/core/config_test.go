@@ -0,0 +1,81 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadConfig_Missing(t *testing.T) {
+	os.Setenv("RDS_CONFIG", filepath.Join(t.TempDir(), "nope.yaml"))
+	defer os.Unsetenv("RDS_CONFIG")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig: unexpected error for missing file: %v", err)
+	}
+	if cfg.Clipboard.Backend != "" || cfg.Clipboard.ClearAfter != 0 {
+		t.Errorf("LoadConfig: expected zero config, got %+v", cfg)
+	}
+}
+
+func TestLoadConfig_Clipboard(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	data := "clipboard:\n  backend: osc52\n  clear_after: 30\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("RDS_CONFIG", path)
+	defer os.Unsetenv("RDS_CONFIG")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.Clipboard.Backend != "osc52" || cfg.Clipboard.ClearAfter != 30 {
+		t.Errorf("LoadConfig: got %+v", cfg.Clipboard)
+	}
+}
+
+func TestLoadConfig_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("clipboard: [unclosed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("RDS_CONFIG", path)
+	defer os.Unsetenv("RDS_CONFIG")
+
+	if _, err := LoadConfig(); err == nil {
+		t.Fatal("LoadConfig: expected parse error")
+	}
+}
+
+func TestGetConfigPath_Override(t *testing.T) {
+	os.Setenv("RDS_CONFIG", "/custom/rds.yaml")
+	defer os.Unsetenv("RDS_CONFIG")
+
+	if got := GetConfigPath(); got != "/custom/rds.yaml" {
+		t.Errorf("GetConfigPath(): got %q, want /custom/rds.yaml", got)
+	}
+}
+
+func TestLoadConfig_ProfileClient(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	data := "profiles:\n  ackoprod:\n    client: psql\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("RDS_CONFIG", path)
+	defer os.Unsetenv("RDS_CONFIG")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if got := cfg.Profile("ackoprod").Client; got != "psql" {
+		t.Errorf("Profile(ackoprod).Client: got %q, want psql", got)
+	}
+	if got := cfg.Profile("ackodev").Client; got != "" {
+		t.Errorf("Profile(ackodev).Client: got %q, want empty", got)
+	}
+}
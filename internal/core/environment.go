@@ -0,0 +1,84 @@
+package core
+
+import "strings"
+
+// EnvironmentConfig styles the sessions of one environment.
+type EnvironmentConfig struct {
+	// Color of the prompt label: red, yellow, green, blue, magenta or cyan.
+	Color string `yaml:"color"`
+	// Warn prints a banner with identity and target before a session
+	// starts.
+	Warn bool `yaml:"warn"`
+}
+
+// defaultEnvironments apply to common environment names that are not
+// configured explicitly.
+var defaultEnvironments = map[string]EnvironmentConfig{
+	"prod":        {Color: "red", Warn: true},
+	"production":  {Color: "red", Warn: true},
+	"staging":     {Color: "yellow"},
+	"stage":       {Color: "yellow"},
+	"uat":         {Color: "yellow"},
+	"dev":         {Color: "green"},
+	"development": {Color: "green"},
+	"test":        {Color: "green"},
+}
+
+var ansiColors = map[string]string{
+	"red":     "1;31",
+	"green":   "1;32",
+	"yellow":  "1;33",
+	"blue":    "1;34",
+	"magenta": "1;35",
+	"cyan":    "1;36",
+}
+
+// Environment is the classification of a session target.
+type Environment struct {
+	// Name is empty when the target is not classified.
+	Name  string
+	Color string
+	Warn  bool
+}
+
+// Label is the upper-case environment name shown in prompts and banners.
+func (e Environment) Label() string {
+	return strings.ToUpper(e.Name)
+}
+
+// ANSI returns the SGR parameters for the environment's color, or "" when
+// it has none.
+func (e Environment) ANSI() string {
+	return ansiColors[strings.ToLower(e.Color)]
+}
+
+// Environment classifies an instance of profile: an environment tag on the
+// instance wins over the profile's environment setting. Colors and warnings
+// come from the environments section, falling back to built-in defaults
+// for common names (prod is red and warns).
+func (c Config) Environment(profile string, tags map[string]string) Environment {
+	name := ""
+	keys := []string{"environment", "env"}
+	if c.EnvironmentTag != "" {
+		keys = []string{c.EnvironmentTag}
+	}
+	for _, k := range keys {
+		if v := tags[k]; v != "" {
+			name = v
+			break
+		}
+	}
+	if name == "" {
+		name = c.Profile(profile).Environment
+	}
+	if name == "" {
+		return Environment{}
+	}
+
+	key := strings.ToLower(name)
+	style, ok := c.Environments[key]
+	if !ok {
+		style = defaultEnvironments[key]
+	}
+	return Environment{Name: key, Color: style.Color, Warn: style.Warn}
+}
@@ -0,0 +1,45 @@
+package core
+
+import "testing"
+
+func TestConfigEnvironment(t *testing.T) {
+	cfg := Config{
+		Profiles: map[string]ProfileConfig{
+			"ackoprod": {Environment: "prod"},
+			"sandbox":  {Environment: "sandbox"},
+		},
+		Environments: map[string]EnvironmentConfig{
+			"sandbox": {Color: "cyan"},
+		},
+	}
+	tests := []struct {
+		name    string
+		cfg     Config
+		profile string
+		tags    map[string]string
+		want    Environment
+	}{
+		{"unclassified", cfg, "dev-account", nil, Environment{}},
+		{"profile default", cfg, "ackoprod", nil, Environment{Name: "prod", Color: "red", Warn: true}},
+		{"configured style", cfg, "sandbox", nil, Environment{Name: "sandbox", Color: "cyan"}},
+		{"tag wins", cfg, "ackoprod", map[string]string{"environment": "Staging"}, Environment{Name: "staging", Color: "yellow"}},
+		{"env tag", cfg, "other", map[string]string{"env": "production"}, Environment{Name: "production", Color: "red", Warn: true}},
+		{"custom tag key", Config{EnvironmentTag: "stage"}, "x", map[string]string{"stage": "dev", "env": "prod"}, Environment{Name: "dev", Color: "green"}},
+		{"unknown name", Config{}, "x", map[string]string{"env": "qa"}, Environment{Name: "qa"}},
+	}
+	for _, tt := range tests {
+		if got := tt.cfg.Environment(tt.profile, tt.tags); got != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEnvironmentLabelAndANSI(t *testing.T) {
+	e := Environment{Name: "prod", Color: "Red"}
+	if e.Label() != "PROD" || e.ANSI() != "1;31" {
+		t.Errorf("got label %q ansi %q", e.Label(), e.ANSI())
+	}
+	if (Environment{Name: "qa", Color: "chartreuse"}).ANSI() != "" {
+		t.Error("unknown colors should have no ANSI code")
+	}
+}
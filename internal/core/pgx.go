@@ -3,19 +3,45 @@ package core
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/jackc/pgx/v5"
 )
 
+// SessionOptions are settings applied to a pgx session when it starts.
+type SessionOptions struct {
+	// ReadOnly sets default_transaction_read_only=on, so the server
+	// rejects writes unless the session explicitly turns it off.
+	ReadOnly bool
+}
+
 // NewPgxConn creates a new pgx connection to a PostgreSQL database with sslmode=require.
 func NewPgxConn(ctx context.Context, host string, port int32, user, password, dbname string) (*pgx.Conn, error) {
+	return NewPgxSession(ctx, host, port, user, password, dbname, SessionOptions{})
+}
+
+// NewPgxSession is NewPgxConn with session options.
+func NewPgxSession(ctx context.Context, host string, port int32, user, password, dbname string, opts SessionOptions) (*pgx.Conn, error) {
 	connStr := fmt.Sprintf(
 		"host=%s port=%d user=%s password=%s dbname=%s sslmode=require",
-		host, port, user, password, dbname,
+		dsnValue(host), port, dsnValue(user), dsnValue(password), dsnValue(dbname),
 	)
-	conn, err := pgx.Connect(ctx, connStr)
+	cfg, err := pgx.ParseConfig(connStr)
+	if err != nil {
+		return nil, fmt.Errorf("connect to %s:%d/%s: %w", host, port, dbname, err)
+	}
+	if opts.ReadOnly {
+		cfg.RuntimeParams["default_transaction_read_only"] = "on"
+	}
+	conn, err := pgx.ConnectConfig(ctx, cfg)
 	if err != nil {
 		return nil, fmt.Errorf("connect to %s:%d/%s: %w", host, port, dbname, err)
 	}
 	return conn, nil
 }
+
+// dsnValue quotes a keyword/value connection string value so passwords with
+// spaces, quotes or backslashes survive parsing.
+func dsnValue(v string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(v) + "'"
+}
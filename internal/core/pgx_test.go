@@ -0,0 +1,19 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func TestDSNValue_RoundTrip(t *testing.T) {
+	for _, pw := range []string{"simple", "with space", `it's`, `back\slash`, "x=y"} {
+		cfg, err := pgx.ParseConfig("host=localhost user=u password=" + dsnValue(pw))
+		if err != nil {
+			t.Fatalf("ParseConfig(%q): %v", pw, err)
+		}
+		if cfg.Password != pw {
+			t.Errorf("password %q parsed as %q", pw, cfg.Password)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package core
 import (
 	"fmt"
 	"net"
+	"regexp"
 	"strings"
 
 	"github.com/ktr0731/go-fuzzyfinder"
@@ -74,3 +75,43 @@ func FindByName(instances []InstanceInfo, name string) (InstanceInfo, error) {
 
 	return InstanceInfo{}, fmt.Errorf("no instance matching '%s'", name)
 }
+
+// FilterInstances returns the instances matching every filter. A filter is
+// either "tag:KEY=VALUE" (or "tag:KEY" for any value) or a regular
+// expression matched against the instance ID.
+func FilterInstances(instances []InstanceInfo, filters []string) ([]InstanceInfo, error) {
+	matchers := make([]func(InstanceInfo) bool, 0, len(filters))
+	for _, f := range filters {
+		if tag, ok := strings.CutPrefix(f, "tag:"); ok {
+			key, value, hasValue := strings.Cut(tag, "=")
+			if key == "" {
+				return nil, fmt.Errorf("invalid filter %q (want tag:KEY or tag:KEY=VALUE)", f)
+			}
+			matchers = append(matchers, func(inst InstanceInfo) bool {
+				v, ok := inst.Tags[key]
+				return ok && (!hasValue || v == value)
+			})
+			continue
+		}
+		re, err := regexp.Compile(f)
+		if err != nil {
+			return nil, fmt.Errorf("invalid filter %q: %w", f, err)
+		}
+		matchers = append(matchers, func(inst InstanceInfo) bool { return re.MatchString(inst.ID) })
+	}
+
+	var out []InstanceInfo
+	for _, inst := range instances {
+		matched := true
+		for _, m := range matchers {
+			if !m(inst) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			out = append(out, inst)
+		}
+	}
+	return out, nil
+}
@@ -1,6 +1,7 @@
 package core
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -86,3 +87,41 @@ func TestFindInstanceByEndpoint_NoMatch(t *testing.T) {
 		t.Fatal("FindInstanceByEndpoint: expected error for no match")
 	}
 }
+
+func TestFilterInstances(t *testing.T) {
+	instances := []InstanceInfo{
+		{ID: "orders-prod", Tags: map[string]string{"env": "prod", "team": "checkout"}},
+		{ID: "orders-staging", Tags: map[string]string{"env": "staging"}},
+		{ID: "billing-prod", Tags: map[string]string{"env": "prod"}},
+	}
+	tests := []struct {
+		name    string
+		filters []string
+		want    []string
+		wantErr bool
+	}{
+		{"no filters", nil, []string{"orders-prod", "orders-staging", "billing-prod"}, false},
+		{"regex", []string{"^orders-"}, []string{"orders-prod", "orders-staging"}, false},
+		{"tag value", []string{"tag:env=prod"}, []string{"orders-prod", "billing-prod"}, false},
+		{"tag present", []string{"tag:team"}, []string{"orders-prod"}, false},
+		{"combined", []string{"tag:env=prod", "billing"}, []string{"billing-prod"}, false},
+		{"no match", []string{"tag:env=dev"}, nil, false},
+		{"bad regex", []string{"("}, nil, true},
+		{"empty tag key", []string{"tag:=x"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FilterInstances(instances, tt.filters)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FilterInstances: err %v, wantErr %v", err, tt.wantErr)
+			}
+			var ids []string
+			for _, inst := range got {
+				ids = append(ids, inst.ID)
+			}
+			if strings.Join(ids, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("FilterInstances: got %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
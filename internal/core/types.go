@@ -1,7 +1,7 @@
 package core
 
 // CacheVersion is incremented when InstanceInfo (or cache format) changes.
-const CacheVersion = "v2"
+const CacheVersion = "v3"
 
 // CacheEnvelope is the on-disk cache format for RDS instance list.
 type CacheEnvelope struct {
@@ -11,12 +11,13 @@ type CacheEnvelope struct {
 
 // InstanceInfo describes one RDS PostgreSQL instance.
 type InstanceInfo struct {
-	ID       string `json:"id"`
-	Host     string `json:"host"`
-	Size     string `json:"size"`
-	Port     int32  `json:"port"`
-	Version  string `json:"version"`
-	SourceID string `json:"source_id"`
+	ID       string            `json:"id"`
+	Host     string            `json:"host"`
+	Size     string            `json:"size"`
+	Port     int32             `json:"port"`
+	Version  string            `json:"version"`
+	SourceID string            `json:"source_id"`
+	Tags     map[string]string `json:"tags,omitempty"`
 }
 
 // RDSCreds holds DB username/password from Secrets Manager.
@@ -15,6 +15,13 @@ var vpnProfileMapping = map[string]string{
 	"ackodrive": "sso_ackodrive_prod",
 }
 
+// RequiredVPN returns the Pritunl VPN profile mapped to an AWS profile.
+// The second result is false when the profile has no mapping.
+func RequiredVPN(profile string) (string, bool) {
+	vpn, ok := vpnProfileMapping[profile]
+	return vpn, ok
+}
+
 // ValidatePritunlConnections checks if connections satisfy the required VPN for profile.
 func ValidatePritunlConnections(conns []PritunlConnection, profile string) error {
 	requiredVPN, exists := vpnProfileMapping[profile]
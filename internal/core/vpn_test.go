@@ -60,3 +60,13 @@ func TestCheckVPNWithPritunl_Integration(t *testing.T) {
 		t.Errorf("VPN check failed (is Pritunl running and required VPN connected?): %v", err)
 	}
 }
+
+func TestRequiredVPN(t *testing.T) {
+	got, ok := RequiredVPN("ackoprod")
+	if !ok || got != "sso_ackoprodvpnusers" {
+		t.Errorf("RequiredVPN(ackoprod): got %q, %v", got, ok)
+	}
+	if _, ok := RequiredVPN("unknown-profile"); ok {
+		t.Error("RequiredVPN(unknown-profile): expected no mapping")
+	}
+}
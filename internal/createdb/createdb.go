@@ -1,33 +1,103 @@
 package createdb
 
 import (
-	"bufio"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"slices"
 	"strings"
 	"text/tabwriter"
 
 	"github.com/PraveenPrabhuT/rds/internal/core"
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/jackc/pgx/v5"
 )
 
 const passwordLength = 20
 
-// Run orchestrates the full database creation flow matching the Ansible playbook.
+// Run orchestrates the full database creation flow: the built-in role
+// template matches the Ansible playbook, and opts.Template replaces it.
+// The instance is inspected first and only the missing or different
+// statements run, so a rerun completes a partial database; opts.Plan stops
+// after showing them. Every applied run is journaled; opts.Resume picks up
+// a failed run with its recorded instance, database and template. Without
+// a terminal on stdin, every question must be answered by a flag.
 func Run(ctx context.Context, opts Options) error {
+	if opts.Output != "" && !slices.Contains(OutputFormats, opts.Output) {
+		return fmt.Errorf("unknown output format %q (want %s)", opts.Output, strings.Join(OutputFormats, ", "))
+	}
+	con := newConsole(opts)
+	report := newReport()
+	// done writes the JSON report, if requested, and passes err through.
+	// Every return from here on goes through it, so a pipeline always gets
+	// a report, with as much of the target as is known.
+	done := func(status string, err error) error {
+		if opts.Output != OutputJSON {
+			return err
+		}
+		report.Status = status
+		if err != nil {
+			report.Error = err.Error()
+		}
+		if werr := report.write(os.Stdout); werr != nil && err == nil {
+			return werr
+		}
+		return err
+	}
+	var journal *Journal
+	var tmpl *Template
+	var err error
+	if opts.Resume != "" {
+		if journal, err = LoadJournal(opts.Resume); err != nil {
+			return done(RunFailed, err)
+		}
+		if journal.Status == RunDone || journal.Status == RunRolledBack {
+			return done(RunFailed, fmt.Errorf("run %s is %s and cannot be resumed", journal.ID, journal.Status))
+		}
+		if tmpl, err = journal.template(); err != nil {
+			return done(RunFailed, err)
+		}
+		if opts.DBName != "" && opts.DBName != journal.Database {
+			return done(RunFailed, fmt.Errorf("run %s provisions %q, not %q", journal.ID, journal.Database, opts.DBName))
+		}
+		opts.Profile, opts.DBName, opts.DefaultDB = journal.Profile, journal.Database, journal.DefaultDB
+		opts.Host, opts.Instance, opts.Port = "", journal.Instance, int(journal.Port)
+		report.RunID = journal.ID
+		fmt.Fprintf(con.out, "🔁 Resuming run %s (%s on %s)\n", journal.ID, journal.Database, journal.Instance)
+	} else if tmpl, err = resolveTemplate(opts); err != nil {
+		return done(RunFailed, err)
+	}
+	if opts.DBName != "" {
+		report.setDatabase(opts.DBName, tmpl)
+	}
+	if err := checkNonInteractive(opts, con); err != nil {
+		return done(RunFailed, err)
+	}
+	sinks, prefixes, err := parseSinks(opts.Emit, opts.EmitKeys, tmpl)
+	if err != nil {
+		return done(RunFailed, err)
+	}
+	if opts.StoreSecrets != nil && !*opts.StoreSecrets && slices.ContainsFunc(sinks, func(s sink) bool { return s.kind == EmitExternalSecret }) {
+		return done(RunFailed, fmt.Errorf("--emit %s reads the stored credentials: drop --no-store-secrets", EmitExternalSecret))
+	}
+	if opts.SecretStore == "" {
+		opts.SecretStore = DefaultSecretStore
+	}
+
 	if err := core.CheckVPNWithPritunl(opts.Profile); err != nil {
-		fmt.Printf("⚠️  VPN check: %v (continuing anyway)\n", err)
+		fmt.Fprintf(con.out, "⚠️  VPN check: %v (continuing anyway)\n", err)
 	}
 
 	cfg, homeRegion, err := core.LoadAWSConfig(ctx, opts.Profile, opts.Region)
 	if err != nil {
-		return err
+		return done(RunFailed, err)
 	}
 
 	instances, err := core.GetInstancesWithCache(ctx, cfg, opts.Profile)
 	if err != nil {
-		return fmt.Errorf("fetch instances: %w", err)
+		return done(RunFailed, fmt.Errorf("fetch instances: %w", err))
 	}
 
 	// Filter out read replicas
@@ -44,164 +114,398 @@ func Run(ctx context.Context, opts Options) error {
 	switch {
 	case opts.Host != "":
 		selected, selectErr = core.FindInstanceByEndpoint(primary, opts.Host)
-	case len(opts.Args) > 0:
-		selected, selectErr = core.FindByName(primary, opts.Args[0])
+	case opts.Instance != "":
+		selected, selectErr = instanceByID(primary, opts.Instance)
 	default:
 		selected, selectErr = core.PickWithFuzzyFinder(primary)
 	}
 	if selectErr != nil {
-		return fmt.Errorf("instance selection: %w", selectErr)
+		return done(RunFailed, fmt.Errorf("instance selection: %w", selectErr))
 	}
 
 	if opts.Port != 0 {
 		selected.Port = int32(opts.Port)
 	}
+	report.setInstance(selected)
 
 	creds, err := core.GetRDSCredentials(ctx, cfg, selected, homeRegion)
 	if err != nil {
-		return fmt.Errorf("secrets: %w", err)
+		return done(RunFailed, fmt.Errorf("secrets: %w", err))
 	}
 
 	dbName := opts.DBName
 	if dbName == "" {
-		fmt.Print("Enter database name: ")
-		scanner := bufio.NewScanner(os.Stdin)
-		if scanner.Scan() {
-			dbName = strings.TrimSpace(scanner.Text())
+		if dbName, err = con.prompt("Enter database name", "the database name as an argument"); err != nil {
+			return done(RunFailed, err)
 		}
 		if dbName == "" {
-			return fmt.Errorf("database name is required")
+			return done(RunFailed, fmt.Errorf("database name is required"))
 		}
 	}
 
-	users, err := generateCredentials(dbName, opts)
+	report.setDatabase(dbName, tmpl)
+
+	if err := ValidateNames(dbName, tmpl); err != nil {
+		return done(RunFailed, err)
+	}
+
+	users, err := generateCredentials(dbName, tmpl)
 	if err != nil {
-		return fmt.Errorf("generate passwords: %w", err)
+		return done(RunFailed, fmt.Errorf("generate passwords: %w", err))
+	}
+
+	printSummary(con.out, selected, dbName, opts, tmpl, users)
+
+	if opts.DryRun {
+		ok, err := con.confirm("Proceed?", "--yes")
+		if err != nil {
+			return done(RunFailed, err)
+		}
+		if !ok {
+			fmt.Fprintln(con.out, "Aborted.")
+			return done("aborted", nil)
+		}
+		printDryRun(con.out, BuildSteps(tmpl, dbName, users))
+		return done("planned", nil)
 	}
 
-	steps := BuildSteps(dbName, opts.Schema, users)
+	plan, err := BuildPlan(ctx, selected, creds, opts.DefaultDB, tmpl, dbName, users)
+	if err != nil {
+		return done(RunFailed, err)
+	}
+	// Users an earlier attempt created exist, but their passwords were never
+	// shown or stored; give them the ones generated now.
+	var resets []UserCredentials
+	if journal != nil {
+		for _, u := range users {
+			if journal.created(u.Username) && !plan.Creates(u.Username) {
+				resets = append(resets, u)
+			}
+		}
+	}
+	plan = plan.withPasswordResets(resets)
+	report.setPlan(plan)
+	printPlan(con.out, plan)
+	switch {
+	case opts.Plan:
+		return done("planned", nil)
+	case plan.Empty():
+		return done("unchanged", nil)
+	}
 
-	printSummary(selected, dbName, opts, users)
+	ok, err := con.confirm("Proceed?", "--yes")
+	if err != nil {
+		return done(RunFailed, err)
+	}
+	if !ok {
+		fmt.Fprintln(con.out, "Aborted.")
+		return done("aborted", nil)
+	}
 
-	if !confirm() {
-		fmt.Println("Aborted.")
-		return nil
+	var created []UserCredentials
+	for _, u := range users {
+		if plan.Creates(u.Username) || slices.Contains(resets, u) {
+			created = append(created, u)
+		}
+	}
+	migrationUser, err := ownerCredentials(ctx, cfg, homeRegion, selected, dbName, tmpl.Owner().Username(dbName), created, plan.Steps)
+	if err != nil {
+		return done(RunFailed, err)
 	}
 
-	if opts.DryRun {
-		printDryRun(steps)
-		return nil
+	if journal == nil {
+		if journal, err = newJournal(opts, selected, dbName, tmpl); err != nil {
+			return done(RunFailed, fmt.Errorf("start run journal: %w", err))
+		}
+	}
+	report.RunID = journal.ID
+	journal.Status = RunRunning
+	journal.recordCreated(plan)
+	if err := journal.save(); err != nil {
+		return done(RunFailed, fmt.Errorf("save run journal: %w", err))
 	}
+	fmt.Fprintf(con.out, "📒 Run %s\n", journal.ID)
 
-	results := executeSteps(ctx, steps, selected, creds, users[0], dbName, opts)
+	results := executeSteps(ctx, con.out, plan.Steps, selected, creds, migrationUser, dbName, opts, journal.record)
+	report.Steps = append(report.Steps, results...)
 
-	printResults(results)
+	printResults(con.out, results)
 	for _, r := range results {
 		if r.Status == "FAILED" {
-			return fmt.Errorf("step %q failed: %w", r.Name, r.Error)
+			stepErr := fmt.Errorf("step %q failed: %w", r.Name, r.Error)
+			journal.finish(RunFailed, stepErr)
+			status := RunFailed
+			if opts.RollbackOnFailure {
+				if err := rollback(ctx, con, selected, creds, opts.DefaultDB, journal); err != nil {
+					fmt.Fprintf(con.out, "❌ Rollback: %v\n", err)
+				}
+				status = journal.Status
+			} else {
+				fmt.Fprintf(con.out, "Fix the cause and resume with: rds db create --resume %s\n", journal.ID)
+			}
+			return done(status, stepErr)
 		}
 	}
+	journal.finish(RunDone, nil)
 
-	printCredentialsTable(selected, dbName, users)
+	if len(created) == 0 {
+		if len(sinks) > 0 {
+			fmt.Fprintln(con.out, "No new credentials to emit; existing users keep their passwords.")
+		}
+		return done(RunDone, nil)
+	}
+	report.setUsers(created, opts.ShowPasswords)
+	if opts.Output != OutputJSON {
+		printCredentialsTable(con.out, selected, dbName, created)
+	}
 
-	if promptStoreSecrets() {
-		if err := StoreCredentials(ctx, cfg, homeRegion, dbName, selected.ID, users); err != nil {
-			return fmt.Errorf("store secrets: %w", err)
+	store := opts.StoreSecrets != nil && *opts.StoreSecrets
+	if opts.StoreSecrets == nil {
+		if store, err = con.confirm("Store credentials in AWS Secrets Manager?", "--store-secrets or --no-store-secrets"); err != nil {
+			return done(RunDone, err)
+		}
+	}
+	var secretID string
+	if store {
+		stored, err := StoreCredentials(ctx, cfg, homeRegion, dbName, selected, created, opts.Secrets)
+		if err != nil {
+			return done(RunDone, fmt.Errorf("store secrets: %w", err))
 		}
-		fmt.Printf("✅ Credentials stored at %s/%s/psql\n", dbName, selected.ID)
+		secretID = CredentialsSecretID(dbName, selected.ID)
+		report.SecretARN, report.UserSecretARNs = stored.ARN, stored.UserARNs
+		fmt.Fprintf(con.out, "✅ Credentials stored at %s\n", secretID)
+		if opts.Secrets.PerUser {
+			fmt.Fprintf(con.out, "✅ Per-user secrets stored at %s\n", UserSecretID(dbName, selected.ID, "<username>"))
+		}
+	}
+
+	report.Emitted, err = emitCredentials(con.out, sinks, selected, dbName, secretID, opts.SecretStore, created, prefixes)
+	if err != nil {
+		return done(RunDone, fmt.Errorf("emit credentials: %w", err))
+	}
+	if !store && len(report.Emitted) == 0 && opts.Output == OutputJSON && !opts.ShowPasswords {
+		fmt.Fprintln(con.out, "⚠️  The new passwords were neither stored nor shown; reset them to use these users.")
+	}
+
+	return done(RunDone, nil)
+}
+
+// instanceByID finds the instance with exactly this ID, so that a resumed
+// run never lands on another instance.
+func instanceByID(instances []core.InstanceInfo, id string) (core.InstanceInfo, error) {
+	i := slices.IndexFunc(instances, func(inst core.InstanceInfo) bool { return inst.ID == id })
+	if i < 0 {
+		return core.InstanceInfo{}, fmt.Errorf("no primary instance %q", id)
 	}
+	return instances[i], nil
+}
 
+// checkNonInteractive fails fast when stdin is not a terminal and a
+// question the run would ask is not answered by a flag. Questions asked
+// only after changes are applied are checked here so that a pipeline never
+// stops halfway.
+func checkNonInteractive(opts Options, con *console) error {
+	// Every sink but an ExternalSecret writes the passwords to a file.
+	emitsPasswords := slices.ContainsFunc(opts.Emit, func(spec string) bool {
+		kind, _, _ := strings.Cut(spec, "=")
+		return kind != EmitExternalSecret
+	})
+	if opts.Output == OutputJSON && !opts.ShowPasswords && !emitsPasswords && opts.StoreSecrets != nil && !*opts.StoreSecrets && !opts.Plan && !opts.DryRun {
+		return errors.New("the new passwords would be discarded: pass --store-secrets, --show-passwords or --emit")
+	}
+	if con.interactive {
+		return nil
+	}
+	switch {
+	case opts.Host == "" && opts.Instance == "":
+		return fmt.Errorf("%w: pass --host instead of picking an instance", errNotInteractive)
+	case opts.Plan:
+		return nil
+	case !opts.Yes:
+		return fmt.Errorf("%w: pass --yes to apply the changes without confirmation", errNotInteractive)
+	}
 	return nil
 }
 
-func generateCredentials(dbName string, opts Options) ([]UserCredentials, error) {
-	type userDef struct {
-		suffix    string
-		role      string
-		connLimit int
+// ownerCredentials returns the owner's credentials for the steps that run
+// as the owner: freshly generated when the plan creates it, otherwise read
+// from the stored <dbName>/<instance>/psql secret.
+func ownerCredentials(ctx context.Context, cfg aws.Config, homeRegion string, inst core.InstanceInfo, dbName, owner string,
+	created []UserCredentials, steps []Step) (UserCredentials, error) {
+	for _, u := range created {
+		if u.Username == owner {
+			return u, nil
+		}
+	}
+	needed := false
+	for _, s := range steps {
+		needed = needed || s.ConnectAs == "migration"
+	}
+	if !needed {
+		return UserCredentials{}, nil
 	}
+	creds, err := core.GetStoredUserCredentials(ctx, cfg, inst, homeRegion, dbName, owner)
+	if err != nil {
+		return UserCredentials{}, fmt.Errorf("owner %q already exists and its password is needed for the remaining grants: %w", owner, err)
+	}
+	return UserCredentials{Username: creds.Username, Password: creds.Password}, nil
+}
 
-	defs := []userDef{
-		{"", "migration", opts.MigrationConnLimit},
-		{"_ro_v1", "read-only", opts.ROConnLimit},
-		{"_ro_v2", "read-only", opts.ROConnLimit},
-		{"_rw_v1", "read-write", opts.RWConnLimit},
-		{"_rw_v2", "read-write", opts.RWConnLimit},
+// resolveTemplate loads opts.Template, or the built-in template with the
+// conn limit flags applied, and adds opts.Extensions.
+func resolveTemplate(opts Options) (*Template, error) {
+	var tmpl *Template
+	if opts.Template != "" {
+		var err error
+		if tmpl, err = LoadTemplate(opts.Template); err != nil {
+			return nil, err
+		}
+	} else {
+		tmpl = DefaultTemplate()
+		tmpl.SetConnLimits(opts.MigrationConnLimit, opts.ROConnLimit, opts.RWConnLimit)
+	}
+	if err := tmpl.AddSchemas(opts.Schemas); err != nil {
+		return nil, err
+	}
+	if err := tmpl.AddExtensions(opts.Extensions); err != nil {
+		return nil, err
 	}
+	return tmpl, nil
+}
 
-	users := make([]UserCredentials, 0, len(defs))
-	for _, d := range defs {
+// generateCredentials creates a password for every password-login role of
+// tmpl, in template order.
+func generateCredentials(dbName string, tmpl *Template) ([]UserCredentials, error) {
+	var users []UserCredentials
+	for _, r := range tmpl.Roles {
+		if r.Login != LoginPassword {
+			continue
+		}
 		pw, err := GeneratePassword(passwordLength)
 		if err != nil {
 			return nil, err
 		}
 		users = append(users, UserCredentials{
-			Username:  dbName + d.suffix,
+			Username:  r.Username(dbName),
 			Password:  pw,
-			Role:      d.role,
-			ConnLimit: d.connLimit,
+			RoleName:  r.Name,
+			Verifier:  ScramVerifier(pw),
+			Role:      r.DisplayLabel(),
+			ConnLimit: r.Limit(),
 		})
 	}
 	return users, nil
 }
 
-func printSummary(inst core.InstanceInfo, dbName string, opts Options, users []UserCredentials) {
-	fmt.Println()
-	fmt.Println("=== Database Creation Summary ===")
-	fmt.Printf("  Instance:  %s [%s]\n", inst.ID, inst.Host)
-	fmt.Printf("  Database:  %s\n", dbName)
-	fmt.Printf("  Schema:    %s\n", opts.Schema)
-	fmt.Println("  Users:")
+func printSummary(w io.Writer, inst core.InstanceInfo, dbName string, opts Options, tmpl *Template, users []UserCredentials) {
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "=== Database Creation Summary ===")
+	fmt.Fprintf(w, "  Instance:  %s [%s]\n", inst.ID, inst.Host)
+	fmt.Fprintf(w, "  Database:  %s\n", dbName)
+	fmt.Fprintf(w, "  Schemas:   %s\n", strings.Join(tmpl.SchemaNames(), ", "))
+	fmt.Fprintln(w, "  Users:")
 	for _, u := range users {
-		fmt.Printf("    - %-20s (%s, conn_limit=%d)\n", u.Username, u.Role, u.ConnLimit)
+		fmt.Fprintf(w, "    - %-20s (%s, conn_limit=%d)\n", u.Username, u.Role, u.ConnLimit)
+	}
+	for _, r := range tmpl.Roles {
+		if r.Login == LoginIAM {
+			fmt.Fprintf(w, "  IAM user:  %s\n", r.Username(dbName))
+		}
+	}
+	if len(tmpl.Extensions) > 0 {
+		fmt.Fprintf(w, "  Extensions: %s\n", strings.Join(tmpl.Extensions, ", "))
+	}
+	for _, o := range settingOps(tmpl.Settings, "", dbName) {
+		fmt.Fprintf(w, "  Parameter: %s = %s\n", o.object, o.value)
+	}
+	for _, r := range tmpl.Roles {
+		for _, o := range settingOps(r.Settings, r.Username(dbName), dbName) {
+			fmt.Fprintf(w, "  Parameter: %s = %s for %s\n", o.object, o.value, o.role)
+		}
 	}
-	fmt.Printf("  IAM user:  %s_iam\n", dbName)
 	if opts.DryRun {
-		fmt.Println("  Mode:      DRY RUN (no changes will be made)")
+		fmt.Fprintln(w, "  Mode:      DRY RUN (no changes will be made)")
 	}
-	fmt.Println()
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "=== Access Matrix ===")
+	printAccessMatrix(w, tmpl, dbName)
+	fmt.Fprintln(w)
 }
 
-func confirm() bool {
-	fmt.Print("Proceed? [y/N] ")
-	scanner := bufio.NewScanner(os.Stdin)
-	if scanner.Scan() {
-		answer := strings.TrimSpace(strings.ToLower(scanner.Text()))
-		return answer == "y" || answer == "yes"
+// printAccessMatrix prints what every role gets in every schema: owner,
+// the privilege set it is granted, the roles it inherits privileges from,
+// or - for nothing.
+func printAccessMatrix(w io.Writer, tmpl *Template, dbName string) {
+	schemas := tmpl.SchemaNames()
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "  USER\t%s\n", strings.Join(schemas, "\t"))
+	for _, r := range tmpl.Roles {
+		cells := []string{r.Username(dbName)}
+		for _, schema := range schemas {
+			cells = append(cells, accessCell(tmpl, r, schema))
+		}
+		fmt.Fprintf(tw, "  %s\n", strings.Join(cells, "\t"))
 	}
-	return false
+	tw.Flush()
 }
 
-func printDryRun(steps []Step) {
-	fmt.Println("\n=== DRY RUN: SQL Statements ===")
+func accessCell(tmpl *Template, r RoleTemplate, schema string) string {
+	if r.Owner {
+		return "owner"
+	}
+	var parts []string
+	if set := tmpl.PrivilegesIn(r, schema); set != "" {
+		parts = append(parts, set)
+	}
+	for _, parent := range r.MemberOf {
+		if p, _ := tmpl.Role(parent); accessCell(tmpl, p, schema) != "-" {
+			parts = append(parts, "via "+parent)
+		}
+	}
+	if len(parts) == 0 {
+		return "-"
+	}
+	return strings.Join(parts, ", ")
+}
+
+func printDryRun(w io.Writer, steps []Step) {
+	fmt.Fprintln(w, "\n=== DRY RUN: SQL Statements ===")
 	for i, step := range steps {
-		fmt.Printf("\n-- Step %d: %s (as %s -> %s)\n", i+1, step.Name, step.ConnectAs, step.ConnectDB)
+		fmt.Fprintf(w, "\n-- Step %d: %s (as %s -> %s)\n", i+1, step.Name, step.ConnectAs, step.ConnectDB)
 		for _, stmt := range step.Statements {
-			fmt.Printf("%s;\n", stmt)
+			fmt.Fprintf(w, "%s;\n", Redact(stmt))
 		}
 	}
-	fmt.Println()
+	fmt.Fprintln(w)
 }
 
 func executeSteps(
 	ctx context.Context,
+	w io.Writer,
 	steps []Step,
 	inst core.InstanceInfo,
 	superCreds core.RDSCreds,
 	migrationUser UserCredentials,
 	dbName string,
 	opts Options,
+	record func(StepResult),
 ) []StepResult {
 	var results []StepResult
+	add := func(r StepResult) {
+		results = append(results, r)
+		if record != nil {
+			record(r)
+		}
+	}
 	failed := false
 
 	for i, step := range steps {
 		if failed {
-			results = append(results, StepResult{Name: step.Name, Status: "SKIPPED"})
+			add(StepResult{Name: step.Name, Status: "SKIPPED"})
 			continue
 		}
 
-		fmt.Printf("Step %d/%d: %s... ", i+1, len(steps), step.Name)
+		fmt.Fprintf(w, "Step %d/%d: %s... ", i+1, len(steps), step.Name)
 
 		var user, password, targetDB string
 
@@ -223,8 +527,8 @@ func executeSteps(
 
 		conn, err := core.NewPgxConn(ctx, inst.Host, inst.Port, user, password, targetDB)
 		if err != nil {
-			fmt.Printf("FAILED (connect: %v)\n", err)
-			results = append(results, StepResult{Name: step.Name, Status: "FAILED", Error: err})
+			fmt.Fprintf(w, "FAILED (connect: %v)\n", err)
+			add(StepResult{Name: step.Name, Status: "FAILED", Error: err})
 			failed = true
 			continue
 		}
@@ -233,12 +537,12 @@ func executeSteps(
 		conn.Close(ctx)
 
 		if stepErr != nil {
-			fmt.Printf("FAILED (%v)\n", stepErr)
-			results = append(results, StepResult{Name: step.Name, Status: "FAILED", Error: stepErr})
+			fmt.Fprintf(w, "FAILED (%v)\n", stepErr)
+			add(StepResult{Name: step.Name, Status: "FAILED", Error: stepErr})
 			failed = true
 		} else {
-			fmt.Println("done")
-			results = append(results, StepResult{Name: step.Name, Status: "done"})
+			fmt.Fprintln(w, "done")
+			add(StepResult{Name: step.Name, Status: "done"})
 		}
 	}
 	return results
@@ -250,7 +554,7 @@ func executeStatements(ctx context.Context, conn *pgx.Conn, stmts []string, forc
 			if force && isAlreadyExistsError(err) {
 				continue
 			}
-			return fmt.Errorf("exec %q: %w", truncate(stmt, 80), err)
+			return fmt.Errorf("exec %q: %w", truncate(Redact(stmt), 80), err)
 		}
 	}
 	return nil
@@ -269,41 +573,31 @@ func truncate(s string, max int) string {
 	return s[:max-3] + "..."
 }
 
-func printResults(results []StepResult) {
-	fmt.Println()
+func printResults(w io.Writer, results []StepResult) {
+	fmt.Fprintln(w)
 	for _, r := range results {
 		switch r.Status {
 		case "done":
-			fmt.Printf("  ✅ %s\n", r.Name)
+			fmt.Fprintf(w, "  ✅ %s\n", r.Name)
 		case "FAILED":
-			fmt.Printf("  ❌ %s: %v\n", r.Name, r.Error)
+			fmt.Fprintf(w, "  ❌ %s: %v\n", r.Name, r.Error)
 		case "SKIPPED":
-			fmt.Printf("  ⏭️  %s (skipped)\n", r.Name)
+			fmt.Fprintf(w, "  ⏭️  %s (skipped)\n", r.Name)
 		}
 	}
-	fmt.Println()
+	fmt.Fprintln(w)
 }
 
-func printCredentialsTable(inst core.InstanceInfo, dbName string, users []UserCredentials) {
-	fmt.Printf("\nDatabase %q created on %s\n\n", dbName, inst.Host)
-	fmt.Println("Credentials:")
+func printCredentialsTable(out io.Writer, inst core.InstanceInfo, dbName string, users []UserCredentials) {
+	fmt.Fprintf(out, "\nDatabase %q created on %s\n\n", dbName, inst.Host)
+	fmt.Fprintln(out, "Credentials:")
 
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
 	fmt.Fprintln(w, "USER\tPASSWORD\tROLE\tCONN LIMIT")
 	fmt.Fprintln(w, "----\t--------\t----\t----------")
 	for _, u := range users {
 		fmt.Fprintf(w, "%s\t%s\t%s\t%d\n", u.Username, u.Password, u.Role, u.ConnLimit)
 	}
 	w.Flush()
-	fmt.Println()
-}
-
-func promptStoreSecrets() bool {
-	fmt.Print("Store credentials in AWS Secrets Manager? [y/N] ")
-	scanner := bufio.NewScanner(os.Stdin)
-	if scanner.Scan() {
-		answer := strings.TrimSpace(strings.ToLower(scanner.Text()))
-		return answer == "y" || answer == "yes"
-	}
-	return false
+	fmt.Fprintln(out)
 }
@@ -0,0 +1,272 @@
+package createdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/PraveenPrabhuT/rds/internal/core"
+	"go.yaml.in/yaml/v3"
+)
+
+// Credential sinks selectable with --emit.
+const (
+	EmitK8sSecret      = "k8s-secret"
+	EmitExternalSecret = "external-secret"
+	EmitDotenv         = "dotenv"
+	EmitJSON           = "json"
+)
+
+// EmitKinds lists the accepted --emit kinds.
+var EmitKinds = []string{EmitK8sSecret, EmitExternalSecret, EmitDotenv, EmitJSON}
+
+// DefaultSecretStore is the ClusterSecretStore an ExternalSecret reads
+// from unless --secret-store names another.
+const DefaultSecretStore = "aws-secrets-manager"
+
+var (
+	keyPattern    = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	keyUnsafe     = regexp.MustCompile(`[^A-Z0-9_]`)
+	dotenvSafe    = regexp.MustCompile(`^[A-Za-z0-9_./:@-]*$`)
+	k8sNameUnsafe = regexp.MustCompile(`[^a-z0-9-]+`)
+)
+
+// sink is one --emit KIND=PATH.
+type sink struct {
+	kind string
+	path string
+}
+
+// parseSinks parses the --emit and --emit-key values. keys maps a template
+// role name to the prefix of its keys.
+func parseSinks(emit, emitKeys []string, tmpl *Template) ([]sink, map[string]string, error) {
+	var sinks []sink
+	for _, spec := range emit {
+		kind, path, ok := strings.Cut(spec, "=")
+		if !ok || path == "" {
+			return nil, nil, fmt.Errorf("--emit %q: want KIND=PATH", spec)
+		}
+		if !slices.Contains(EmitKinds, kind) {
+			return nil, nil, fmt.Errorf("--emit %q: unknown kind %q (want %s)", spec, kind, strings.Join(EmitKinds, ", "))
+		}
+		sinks = append(sinks, sink{kind: kind, path: path})
+	}
+	keys := map[string]string{}
+	for _, spec := range emitKeys {
+		role, prefix, ok := strings.Cut(spec, "=")
+		if !ok {
+			return nil, nil, fmt.Errorf("--emit-key %q: want ROLE=PREFIX", spec)
+		}
+		r, found := tmpl.Role(role)
+		if !found {
+			return nil, nil, fmt.Errorf("--emit-key %q: no role %q in the template", spec, role)
+		}
+		if r.Login != LoginPassword {
+			return nil, nil, fmt.Errorf("--emit-key %q: role %q has no password", spec, role)
+		}
+		if !keyPattern.MatchString(prefix) {
+			return nil, nil, fmt.Errorf("--emit-key %q: prefix must be letters, digits and _", spec)
+		}
+		keys[role] = prefix
+	}
+	return sinks, keys, nil
+}
+
+// credentialKey is one key of the emitted credentials.
+type credentialKey struct {
+	name  string
+	value string
+	// user is set on password keys: the username whose password it is.
+	user string
+}
+
+// credentialKeys lists the keys every sink writes: the connection details,
+// then <PREFIX>_USERNAME and <PREFIX>_PASSWORD per user, where PREFIX is
+// the --emit-key prefix or the upper-cased template role name.
+func credentialKeys(inst core.InstanceInfo, dbName string, users []UserCredentials, prefixes map[string]string) []credentialKey {
+	keys := []credentialKey{
+		{name: "DB_HOST", value: inst.Host},
+		{name: "DB_PORT", value: strconv.Itoa(int(inst.Port))},
+		{name: "DB_NAME", value: dbName},
+	}
+	for _, u := range users {
+		prefix, ok := prefixes[u.RoleName]
+		if !ok {
+			prefix = keyUnsafe.ReplaceAllString(strings.ToUpper(u.RoleName), "_")
+		}
+		keys = append(keys,
+			credentialKey{name: prefix + "_USERNAME", value: u.Username},
+			credentialKey{name: prefix + "_PASSWORD", value: u.Password, user: u.Username})
+	}
+	return keys
+}
+
+// emitCredentials writes the credentials to every sink. secretID is the
+// Secrets Manager secret holding the passwords, which ExternalSecret
+// manifests read through store; it is empty when the credentials were not
+// stored.
+func emitCredentials(w io.Writer, sinks []sink, inst core.InstanceInfo, dbName, secretID, store string,
+	users []UserCredentials, prefixes map[string]string) ([]string, error) {
+	keys := credentialKeys(inst, dbName, users, prefixes)
+	var written []string
+	for _, s := range sinks {
+		var data []byte
+		var err error
+		switch s.kind {
+		case EmitK8sSecret:
+			data, err = k8sSecret(dbName, keys)
+		case EmitExternalSecret:
+			if secretID == "" {
+				return written, fmt.Errorf("%s %s: the credentials were not stored in Secrets Manager", s.kind, s.path)
+			}
+			data, err = externalSecret(dbName, secretID, store, keys)
+		case EmitDotenv:
+			data = dotenv(keys)
+		case EmitJSON:
+			data, err = jsonCredentials(keys)
+		}
+		if err == nil {
+			err = writePrivate(s.path, data)
+		}
+		if err != nil {
+			return written, fmt.Errorf("%s %s: %w", s.kind, s.path, err)
+		}
+		written = append(written, s.path)
+		fmt.Fprintf(w, "✅ Wrote %s %s\n", s.kind, s.path)
+	}
+	return written, nil
+}
+
+// k8sName turns dbName into a Kubernetes object name.
+func k8sName(dbName string) string {
+	return strings.Trim(k8sNameUnsafe.ReplaceAllString(strings.ToLower(dbName), "-"), "-") + "-db-credentials"
+}
+
+type k8sMetadata struct {
+	Name string `yaml:"name"`
+}
+
+func k8sSecret(dbName string, keys []credentialKey) ([]byte, error) {
+	data := map[string]string{}
+	for _, k := range keys {
+		data[k.name] = k.value
+	}
+	return yaml.Marshal(struct {
+		APIVersion string            `yaml:"apiVersion"`
+		Kind       string            `yaml:"kind"`
+		Metadata   k8sMetadata       `yaml:"metadata"`
+		Type       string            `yaml:"type"`
+		StringData map[string]string `yaml:"stringData"`
+	}{"v1", "Secret", k8sMetadata{k8sName(dbName)}, "Opaque", data})
+}
+
+// externalSecret references the passwords in secretID, whose properties
+// are the usernames, and templates the other keys in as literals, so the
+// manifest holds no password.
+func externalSecret(dbName, secretID, store string, keys []credentialKey) ([]byte, error) {
+	type remoteRef struct {
+		Key      string `yaml:"key"`
+		Property string `yaml:"property"`
+	}
+	type dataRef struct {
+		SecretKey string    `yaml:"secretKey"`
+		RemoteRef remoteRef `yaml:"remoteRef"`
+	}
+	var refs []dataRef
+	tmpl := map[string]string{}
+	for _, k := range keys {
+		if k.user == "" {
+			tmpl[k.name] = k.value
+			continue
+		}
+		refs = append(refs, dataRef{SecretKey: k.name, RemoteRef: remoteRef{Key: secretID, Property: k.user}})
+		tmpl[k.name] = "{{ ." + k.name + " }}"
+	}
+	name := k8sName(dbName)
+	type storeRef struct {
+		Name string `yaml:"name"`
+		Kind string `yaml:"kind"`
+	}
+	type template struct {
+		EngineVersion string            `yaml:"engineVersion"`
+		Data          map[string]string `yaml:"data"`
+	}
+	type target struct {
+		Name     string   `yaml:"name"`
+		Template template `yaml:"template"`
+	}
+	type spec struct {
+		RefreshInterval string    `yaml:"refreshInterval"`
+		SecretStoreRef  storeRef  `yaml:"secretStoreRef"`
+		Target          target    `yaml:"target"`
+		Data            []dataRef `yaml:"data"`
+	}
+	return yaml.Marshal(struct {
+		APIVersion string      `yaml:"apiVersion"`
+		Kind       string      `yaml:"kind"`
+		Metadata   k8sMetadata `yaml:"metadata"`
+		Spec       spec        `yaml:"spec"`
+	}{
+		APIVersion: "external-secrets.io/v1beta1",
+		Kind:       "ExternalSecret",
+		Metadata:   k8sMetadata{name},
+		Spec: spec{
+			RefreshInterval: "1h",
+			SecretStoreRef:  storeRef{Name: store, Kind: "ClusterSecretStore"},
+			Target:          target{Name: name, Template: template{EngineVersion: "v2", Data: tmpl}},
+			Data:            refs,
+		},
+	})
+}
+
+func dotenv(keys []credentialKey) []byte {
+	var b strings.Builder
+	for _, k := range keys {
+		value := k.value
+		if !dotenvSafe.MatchString(value) {
+			value = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`, "\n", `\n`).Replace(value) + `"`
+		}
+		fmt.Fprintf(&b, "%s=%s\n", k.name, value)
+	}
+	return []byte(b.String())
+}
+
+func jsonCredentials(keys []credentialKey) ([]byte, error) {
+	data := map[string]string{}
+	for _, k := range keys {
+		data[k.name] = k.value
+	}
+	out, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+// writePrivate replaces path with data, readable only by the owner, even
+// when path already exists with wider permissions.
+func writePrivate(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if err := f.Chmod(0600); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
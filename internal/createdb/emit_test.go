@@ -0,0 +1,164 @@
+package createdb
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/PraveenPrabhuT/rds/internal/core"
+	"go.yaml.in/yaml/v3"
+)
+
+var emitInstance = core.InstanceInfo{ID: "db-1", Host: "db-1.example.com", Port: 5432}
+
+func emitUsers() []UserCredentials {
+	return []UserCredentials{
+		{Username: "app", Password: "ownerpw", RoleName: "migration"},
+		{Username: "app_ro_v1", Password: "ropw", RoleName: "ro_v1"},
+	}
+}
+
+func TestParseSinks(t *testing.T) {
+	tests := []struct {
+		name     string
+		emit     []string
+		keys     []string
+		wantErr  string
+		wantKeys map[string]string
+	}{
+		{"valid", []string{"dotenv=.env", "k8s-secret=s.yaml"}, []string{"ro_v1=READONLY"}, "", map[string]string{"ro_v1": "READONLY"}},
+		{"missing path", []string{"dotenv"}, nil, "KIND=PATH", nil},
+		{"unknown kind", []string{"vault=x"}, nil, "unknown kind", nil},
+		{"unknown role", nil, []string{"admin=ADMIN"}, "no role", nil},
+		{"iam role", nil, []string{"iam=IAM"}, "no password", nil},
+		{"bad prefix", nil, []string{"ro_v1=READ-ONLY"}, "prefix", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sinks, keys, err := parseSinks(tt.emit, tt.keys, DefaultTemplate())
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseSinks error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(sinks) != len(tt.emit) {
+				t.Errorf("sinks = %v", sinks)
+			}
+			if len(keys) != len(tt.wantKeys) || keys["ro_v1"] != tt.wantKeys["ro_v1"] {
+				t.Errorf("keys = %v, want %v", keys, tt.wantKeys)
+			}
+		})
+	}
+}
+
+func TestCredentialKeys(t *testing.T) {
+	keys := credentialKeys(emitInstance, "app", emitUsers(), map[string]string{"ro_v1": "READONLY"})
+	var names []string
+	for _, k := range keys {
+		names = append(names, k.name+"="+k.value)
+	}
+	want := "DB_HOST=db-1.example.com DB_PORT=5432 DB_NAME=app MIGRATION_USERNAME=app MIGRATION_PASSWORD=ownerpw READONLY_USERNAME=app_ro_v1 READONLY_PASSWORD=ropw"
+	if got := strings.Join(names, " "); got != want {
+		t.Errorf("keys =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestDotenv_Quoting(t *testing.T) {
+	got := string(dotenv([]credentialKey{{name: "A", value: "plain-1.2"}, {name: "B", value: `a b"$c`}}))
+	want := "A=plain-1.2\nB=\"a b\\\"\\$c\"\n"
+	if got != want {
+		t.Errorf("dotenv = %q, want %q", got, want)
+	}
+}
+
+func TestK8sSecret(t *testing.T) {
+	data, err := k8sSecret("My_App", credentialKeys(emitInstance, "My_App", emitUsers(), nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var secret struct {
+		Kind       string            `yaml:"kind"`
+		Metadata   map[string]string `yaml:"metadata"`
+		StringData map[string]string `yaml:"stringData"`
+	}
+	if err := yaml.Unmarshal(data, &secret); err != nil {
+		t.Fatal(err)
+	}
+	if secret.Kind != "Secret" || secret.Metadata["name"] != "my-app-db-credentials" {
+		t.Errorf("secret = %+v", secret)
+	}
+	if secret.StringData["RO_V1_PASSWORD"] != "ropw" {
+		t.Errorf("stringData = %v", secret.StringData)
+	}
+}
+
+func TestExternalSecret_NoPasswords(t *testing.T) {
+	data, err := externalSecret("app", "app/db-1/psql", "store", credentialKeys(emitInstance, "app", emitUsers(), nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(data)
+	for _, pw := range []string{"ownerpw", "ropw"} {
+		if strings.Contains(out, pw) {
+			t.Errorf("manifest contains password %q:\n%s", pw, out)
+		}
+	}
+	for _, want := range []string{"key: app/db-1/psql", "property: app_ro_v1", "secretKey: RO_V1_PASSWORD", "name: store", "RO_V1_USERNAME: app_ro_v1"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("manifest missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestEmitCredentials(t *testing.T) {
+	dir := t.TempDir()
+	envPath := filepath.Join(dir, ".env")
+	// An existing file keeps no wider permissions than 0600.
+	if err := os.WriteFile(envPath, []byte("OLD=1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	jsonPath := filepath.Join(dir, "creds.json")
+	sinks := []sink{{EmitDotenv, envPath}, {EmitJSON, jsonPath}}
+	written, err := emitCredentials(&strings.Builder{}, sinks, emitInstance, "app", "", DefaultSecretStore, emitUsers(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(written) != 2 {
+		t.Errorf("written = %v", written)
+	}
+	for _, path := range written {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0600 {
+			t.Errorf("%s mode = %v, want 0600", path, info.Mode().Perm())
+		}
+	}
+	env, _ := os.ReadFile(envPath)
+	if strings.Contains(string(env), "OLD=1") || !strings.Contains(string(env), "MIGRATION_PASSWORD=ownerpw") {
+		t.Errorf(".env = %s", env)
+	}
+	var creds map[string]string
+	data, _ := os.ReadFile(jsonPath)
+	if err := json.Unmarshal(data, &creds); err != nil || creds["DB_PORT"] != "5432" {
+		t.Errorf("creds.json = %s (%v)", data, err)
+	}
+}
+
+func TestEmitCredentials_ExternalSecretNeedsStoredSecret(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "es.yaml")
+	_, err := emitCredentials(&strings.Builder{}, []sink{{EmitExternalSecret, path}}, emitInstance, "app", "", DefaultSecretStore, emitUsers(), nil)
+	if err == nil || !strings.Contains(err.Error(), "not stored") {
+		t.Fatalf("emitCredentials error = %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("%s written without a stored secret", path)
+	}
+}
@@ -0,0 +1,71 @@
+package createdb
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// maxIdentifierLength is PostgreSQL's NAMEDATALEN-1; longer names are
+// silently truncated by the server, which could make two roles collide.
+const maxIdentifierLength = 63
+
+// identifierPattern is the syntax of an unquoted identifier, allowing upper
+// case since every identifier is quoted in the generated SQL.
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_$]*$`)
+
+// lowerIdentifier is an identifier PostgreSQL does not need to quote.
+var lowerIdentifier = regexp.MustCompile(`^[a-z_][a-z0-9_$]*$`)
+
+// suffixPattern is what a template suffix may add to the database name.
+var suffixPattern = regexp.MustCompile(`^[A-Za-z0-9_$]*$`)
+
+// extensionPattern is the syntax of extension names (uuid-ossp has a
+// hyphen).
+var extensionPattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_-]*$`)
+
+// settingPattern is the syntax of a lower-cased parameter name, including
+// custom parameters such as auto_explain.log_min_duration.
+var settingPattern = regexp.MustCompile(`^[a-z_][a-z0-9_]*(\.[a-z_][a-z0-9_]*)?$`)
+
+// ValidateNames checks dbName against PostgreSQL identifier rules and makes
+// sure every username tmpl derives from dbName fits in 63 bytes. Schema names
+// are checked when they are added to the template.
+func ValidateNames(dbName string, tmpl *Template) error {
+	if err := validateIdentifier("database name", dbName); err != nil {
+		return err
+	}
+	for _, r := range tmpl.Roles {
+		if user := r.Username(dbName); len(user) > maxIdentifierLength {
+			return fmt.Errorf("database name %q is too long: username %q for role %s exceeds %d bytes",
+				dbName, user, r.Name, maxIdentifierLength)
+		}
+	}
+	return nil
+}
+
+func validateIdentifier(what, name string) error {
+	switch {
+	case name == "":
+		return fmt.Errorf("%s is empty", what)
+	case len(name) > maxIdentifierLength:
+		return fmt.Errorf("%s %q exceeds %d bytes", what, name, maxIdentifierLength)
+	case !identifierPattern.MatchString(name):
+		return fmt.Errorf("%s %q is not a valid identifier (use letters, digits, _ and $, starting with a letter or _)", what, name)
+	case strings.HasPrefix(strings.ToLower(name), "pg_"):
+		return fmt.Errorf("%s %q uses the reserved pg_ prefix", what, name)
+	}
+	return nil
+}
+
+// ident quotes name as an SQL identifier.
+func ident(name string) string {
+	return pgx.Identifier{name}.Sanitize()
+}
+
+// literal quotes s as an SQL string literal.
+func literal(s string) string {
+	return "'" + strings.ReplaceAll(strings.ReplaceAll(s, "\x00", ""), "'", "''") + "'"
+}
@@ -0,0 +1,104 @@
+package createdb
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateNames(t *testing.T) {
+	tests := []struct {
+		name    string
+		dbName  string
+		schema  string
+		wantErr string
+	}{
+		{"valid", "pricing", "public", ""},
+		{"mixed case", "Pricing", "app_v2", ""},
+		{"dollar", "app$1", "public", ""},
+		{"empty database", "", "public", "database name is empty"},
+		{"empty schema", "app", "", "schema is empty"},
+		{"injection", `a"; DROP DATABASE x; --`, "public", "not a valid identifier"},
+		{"hyphen", "my-app", "public", "not a valid identifier"},
+		{"leading digit", "1app", "public", "not a valid identifier"},
+		{"space in schema", "app", "my schema", "not a valid identifier"},
+		{"reserved database", "pg_app", "public", "reserved pg_ prefix"},
+		{"reserved schema", "app", "PG_catalog", "reserved pg_ prefix"},
+		{"database too long", strings.Repeat("a", 64), "public", "exceeds 63 bytes"},
+		{"username too long", strings.Repeat("a", 60), "public", "_ro_v1"},
+		{"username fits", strings.Repeat("a", 57), "public", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl := DefaultTemplate()
+			err := tmpl.AddSchemas([]string{tt.schema})
+			if err == nil {
+				err = ValidateNames(tt.dbName, tmpl)
+			}
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("ValidateNames: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("ValidateNames error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseTemplate_InvalidSuffix(t *testing.T) {
+	_, err := ParseTemplate([]byte(`
+roles:
+  - name: owner
+    owner: true
+  - name: ro
+    suffix: "-ro"
+`))
+	if err == nil || !strings.Contains(err.Error(), "suffix") {
+		t.Fatalf("ParseTemplate error = %v, want suffix error", err)
+	}
+}
+
+func TestIdent(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"app", `"app"`},
+		{`a"b`, `"a""b"`},
+		{`x"; DROP DATABASE y; --`, `"x""; DROP DATABASE y; --"`},
+	}
+	for _, tt := range tests {
+		if got := ident(tt.in); got != tt.want {
+			t.Errorf("ident(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestLiteral(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"abc", `'abc'`},
+		{"it's", `'it''s'`},
+		{"a\x00b", `'ab'`},
+	}
+	for _, tt := range tests {
+		if got := literal(tt.in); got != tt.want {
+			t.Errorf("literal(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestBuildSteps_QuotesSchema(t *testing.T) {
+	users, err := generateCredentials("app", DefaultTemplate())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Templates validate schema names; quote them all the same.
+	tmpl := DefaultTemplate()
+	tmpl.Schemas = []SchemaTemplate{{Name: `s"x`}}
+	for _, step := range BuildSteps(tmpl, "app", users) {
+		for _, stmt := range step.Statements {
+			if strings.Contains(stmt, "SCHEMA s") || (strings.Contains(stmt, `s"x`) && !strings.Contains(stmt, `"s""x"`)) {
+				t.Errorf("schema not quoted: %s", stmt)
+			}
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package createdb
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/PraveenPrabhuT/rds/internal/core"
+	"go.yaml.in/yaml/v3"
+)
+
+// Journal statuses.
+const (
+	RunRunning    = "running"
+	RunFailed     = "failed"
+	RunDone       = "done"
+	RunRolledBack = "rolled back"
+)
+
+// Journal records a db create run so that a failed run can be resumed or
+// rolled back. Passwords are never written: users created by a failed run
+// have never been handed out, so a resume gives them new passwords.
+type Journal struct {
+	ID        string    `json:"id"`
+	Status    string    `json:"status"`
+	Started   time.Time `json:"started"`
+	Updated   time.Time `json:"updated"`
+	Profile   string    `json:"profile"`
+	Instance  string    `json:"instance"`
+	Port      int32     `json:"port"`
+	Database  string    `json:"database"`
+	DefaultDB string    `json:"default_db"`
+	// Template is the YAML of the template the run provisions.
+	Template string `json:"template"`
+	// CreatedDatabase and CreatedUsers are what the run created (or set out
+	// to create), across resumes; rollback drops them.
+	CreatedDatabase bool         `json:"created_database"`
+	CreatedUsers    []string     `json:"created_users"`
+	Steps           []StepResult `json:"steps"`
+	Error           string       `json:"error,omitempty"`
+}
+
+// JournalDir is where run journals are kept.
+func JournalDir() string {
+	return filepath.Join(core.GetCacheDir(), "db-runs")
+}
+
+// newJournal starts a journal with a fresh run ID.
+func newJournal(opts Options, inst core.InstanceInfo, dbName string, tmpl *Template) (*Journal, error) {
+	suffix := make([]byte, 3)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, err
+	}
+	data, err := yaml.Marshal(tmpl)
+	if err != nil {
+		return nil, fmt.Errorf("encode template: %w", err)
+	}
+	now := time.Now()
+	return &Journal{
+		ID:        now.Format("20060102-150405") + "-" + hex.EncodeToString(suffix),
+		Status:    RunRunning,
+		Started:   now,
+		Profile:   opts.Profile,
+		Instance:  inst.ID,
+		Port:      inst.Port,
+		Database:  dbName,
+		DefaultDB: opts.DefaultDB,
+		Template:  string(data),
+	}, nil
+}
+
+// LoadJournal reads the journal of run id.
+func LoadJournal(id string) (*Journal, error) {
+	if id == "" || strings.ContainsAny(id, `/\`) || strings.HasPrefix(id, ".") {
+		return nil, fmt.Errorf("invalid run ID %q", id)
+	}
+	data, err := os.ReadFile(filepath.Join(JournalDir(), id+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("no run %q in %s", id, JournalDir())
+	}
+	if err != nil {
+		return nil, err
+	}
+	var j Journal
+	if err := json.Unmarshal(data, &j); err != nil {
+		return nil, fmt.Errorf("parse run %s: %w", id, err)
+	}
+	return &j, nil
+}
+
+// ListJournals returns the recorded runs, newest first.
+func ListJournals() ([]*Journal, error) {
+	entries, err := os.ReadDir(JournalDir())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var runs []*Journal
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), ".json")
+		if !ok {
+			continue
+		}
+		if j, err := LoadJournal(id); err == nil {
+			runs = append(runs, j)
+		}
+	}
+	sort.Slice(runs, func(a, b int) bool { return runs[a].Started.After(runs[b].Started) })
+	return runs, nil
+}
+
+// save writes the journal (0600, as it names users and hosts).
+func (j *Journal) save() error {
+	if err := os.MkdirAll(JournalDir(), 0700); err != nil {
+		return err
+	}
+	j.Updated = time.Now()
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(JournalDir(), "."+j.ID+".tmp")
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(JournalDir(), j.ID+".json"))
+}
+
+// template decodes the recorded template.
+func (j *Journal) template() (*Template, error) {
+	t, err := ParseTemplate([]byte(j.Template))
+	if err != nil {
+		return nil, fmt.Errorf("run %s template: %w", j.ID, err)
+	}
+	return t, nil
+}
+
+// recordCreated adds what plan creates to the journal.
+func (j *Journal) recordCreated(plan Plan) {
+	j.CreatedDatabase = j.CreatedDatabase || plan.CreatesDatabase
+	for _, user := range plan.Created {
+		if !j.created(user) {
+			j.CreatedUsers = append(j.CreatedUsers, user)
+		}
+	}
+}
+
+func (j *Journal) created(user string) bool {
+	return slices.Contains(j.CreatedUsers, user)
+}
+
+// record stores the outcome of a step.
+func (j *Journal) record(r StepResult) {
+	if r.Error != nil {
+		r.Message = r.Error.Error()
+	}
+	j.Steps = append(j.Steps, r)
+	if err := j.save(); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not update run journal: %v\n", err)
+	}
+}
+
+// finish sets the final status and saves the journal.
+func (j *Journal) finish(status string, err error) {
+	j.Status = status
+	j.Error = ""
+	if err != nil {
+		j.Error = err.Error()
+	}
+	if err := j.save(); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not update run journal: %v\n", err)
+	}
+}
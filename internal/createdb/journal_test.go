@@ -0,0 +1,78 @@
+package createdb
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/PraveenPrabhuT/rds/internal/core"
+)
+
+func TestJournal_SaveLoad(t *testing.T) {
+	t.Setenv("RDS_CACHE_DIR", t.TempDir())
+
+	tmpl := DefaultTemplate()
+	tmpl.SetConnLimits(20, 5, 8)
+	j, err := newJournal(Options{Profile: "prod", DefaultDB: "postgres"},
+		core.InstanceInfo{ID: "pricing-db", Port: 5432}, "pricing", tmpl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.recordCreated(Plan{CreatesDatabase: true, Created: []string{"pricing", "pricing_ro_v1"}})
+	j.recordCreated(Plan{Created: []string{"pricing_ro_v1", "pricing_iam"}})
+	j.record(StepResult{Name: "Create users", Status: "FAILED", Error: os.ErrPermission})
+	j.finish(RunFailed, os.ErrPermission)
+
+	info, err := os.Stat(filepath.Join(JournalDir(), j.ID+".json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("journal mode %v, want 0600", info.Mode().Perm())
+	}
+
+	loaded, err := LoadJournal(j.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Status != RunFailed || loaded.Database != "pricing" || loaded.Instance != "pricing-db" || !loaded.CreatedDatabase {
+		t.Errorf("loaded = %+v", loaded)
+	}
+	if want := []string{"pricing", "pricing_ro_v1", "pricing_iam"}; !reflect.DeepEqual(loaded.CreatedUsers, want) {
+		t.Errorf("created users = %v, want %v", loaded.CreatedUsers, want)
+	}
+	if len(loaded.Steps) != 1 || loaded.Steps[0].Message != os.ErrPermission.Error() {
+		t.Errorf("steps = %+v", loaded.Steps)
+	}
+
+	// The recorded template reproduces the run, conn limits included.
+	recorded, err := loaded.template()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(BuildSteps(recorded, "pricing", nil), BuildSteps(tmpl, "pricing", nil)) {
+		t.Error("recorded template differs")
+	}
+	users, _ := generateCredentials("pricing", recorded)
+	if users[0].ConnLimit != 20 || users[1].ConnLimit != 5 {
+		t.Errorf("recorded conn limits: %+v", users)
+	}
+
+	runs, err := ListJournals()
+	if err != nil || len(runs) != 1 || runs[0].ID != j.ID {
+		t.Errorf("ListJournals = %v, %v", runs, err)
+	}
+}
+
+func TestLoadJournal_Invalid(t *testing.T) {
+	t.Setenv("RDS_CACHE_DIR", t.TempDir())
+	for _, id := range []string{"", "../config", ".hidden", "missing"} {
+		if _, err := LoadJournal(id); err == nil {
+			t.Errorf("LoadJournal(%q): expected error", id)
+		}
+	}
+	if runs, err := ListJournals(); err != nil || len(runs) != 0 {
+		t.Errorf("ListJournals without runs = %v, %v", runs, err)
+	}
+}
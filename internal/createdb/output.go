@@ -0,0 +1,161 @@
+package createdb
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/PraveenPrabhuT/rds/internal/core"
+	"golang.org/x/term"
+)
+
+// Output formats of a run.
+const (
+	OutputText = "text"
+	OutputJSON = "json"
+)
+
+// OutputFormats lists the accepted --output values.
+var OutputFormats = []string{OutputText, OutputJSON}
+
+// errNotInteractive is returned instead of prompting when stdin is not a
+// terminal, so pipelines fail fast rather than hang.
+var errNotInteractive = errors.New("stdin is not a terminal")
+
+// console is how a run talks to the user. Progress goes to out, which is
+// stderr when stdout carries the JSON report, and questions are read from
+// in unless yes answers them.
+type console struct {
+	out         io.Writer
+	in          io.Reader
+	interactive bool
+	yes         bool
+}
+
+// newConsole returns the console for opts.
+func newConsole(opts Options) *console {
+	c := &console{out: os.Stdout, in: os.Stdin, interactive: term.IsTerminal(int(os.Stdin.Fd())), yes: opts.Yes}
+	if opts.Output == OutputJSON {
+		c.out = os.Stderr
+	}
+	return c
+}
+
+// confirm asks a yes/no question; --yes answers it. Without a terminal it
+// fails, naming the flag that answers the question.
+func (c *console) confirm(question, flag string) (bool, error) {
+	if c.yes {
+		return true, nil
+	}
+	if !c.interactive {
+		return false, fmt.Errorf("%w: pass %s to answer %q", errNotInteractive, flag, question)
+	}
+	fmt.Fprintf(c.out, "%s [y/N] ", question)
+	answer := strings.ToLower(c.readLine())
+	return answer == "y" || answer == "yes", nil
+}
+
+// prompt reads a line of text; flag names the non-interactive alternative.
+func (c *console) prompt(question, flag string) (string, error) {
+	if !c.interactive {
+		return "", fmt.Errorf("%w: pass %s", errNotInteractive, flag)
+	}
+	fmt.Fprintf(c.out, "%s: ", question)
+	return c.readLine(), nil
+}
+
+func (c *console) readLine() string {
+	scanner := bufio.NewScanner(c.in)
+	if scanner.Scan() {
+		return strings.TrimSpace(scanner.Text())
+	}
+	return ""
+}
+
+// Report is the --output json document of a run.
+type Report struct {
+	RunID    string   `json:"run_id,omitempty"`
+	Instance string   `json:"instance"`
+	Host     string   `json:"host"`
+	Port     int32    `json:"port"`
+	Database string   `json:"database"`
+	Schemas  []string `json:"schemas"`
+	// Status is "planned" (--plan, --dry-run), "unchanged", "done", "failed",
+	// "rolled back" or "aborted".
+	Status  string       `json:"status"`
+	Changes []Change     `json:"changes"`
+	Steps   []StepResult `json:"steps"`
+	// Users are the users the run created or gave new passwords.
+	Users     []ReportUser `json:"users"`
+	IAMUsers  []string     `json:"iam_users,omitempty"`
+	SecretARN string       `json:"secret_arn,omitempty"`
+	// UserSecretARNs maps usernames to their per-user secret.
+	UserSecretARNs map[string]string `json:"user_secret_arns,omitempty"`
+	// Emitted lists the files written by --emit.
+	Emitted []string `json:"emitted,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// ReportUser describes a created user. Password is only set when
+// passwords were requested.
+type ReportUser struct {
+	Username  string `json:"username"`
+	Role      string `json:"role"`
+	ConnLimit int    `json:"conn_limit"`
+	Password  string `json:"password,omitempty"`
+}
+
+// newReport starts the report of a run; the target is filled in as it is
+// resolved.
+func newReport() *Report {
+	return &Report{Changes: []Change{}, Steps: []StepResult{}, Users: []ReportUser{}}
+}
+
+// setInstance records the instance the run provisions on.
+func (r *Report) setInstance(inst core.InstanceInfo) {
+	r.Instance, r.Host, r.Port = inst.ID, inst.Host, inst.Port
+}
+
+// setDatabase records the database the run provisions and its schemas and
+// IAM users.
+func (r *Report) setDatabase(dbName string, tmpl *Template) {
+	r.Database = dbName
+	r.Schemas = tmpl.SchemaNames()
+	r.IAMUsers = nil
+	for _, role := range tmpl.Roles {
+		if role.Login == LoginIAM {
+			r.IAMUsers = append(r.IAMUsers, role.Username(dbName))
+		}
+	}
+}
+
+// setPlan records the plan's changes.
+func (r *Report) setPlan(plan Plan) {
+	r.Changes = append(r.Changes, plan.Changes...)
+}
+
+// setUsers records the created users, with their passwords if requested.
+func (r *Report) setUsers(users []UserCredentials, passwords bool) {
+	for _, u := range users {
+		ru := ReportUser{Username: u.Username, Role: u.Role, ConnLimit: u.ConnLimit}
+		if passwords {
+			ru.Password = u.Password
+		}
+		r.Users = append(r.Users, ru)
+	}
+}
+
+func (r *Report) write(w io.Writer) error {
+	for i := range r.Steps {
+		if r.Steps[i].Error != nil {
+			r.Steps[i].Message = r.Steps[i].Error.Error()
+		}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
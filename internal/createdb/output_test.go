@@ -0,0 +1,148 @@
+package createdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/PraveenPrabhuT/rds/internal/core"
+)
+
+func TestConsoleConfirm(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		interactive bool
+		yes         bool
+		want        bool
+		wantErr     bool
+	}{
+		{"yes flag", "", false, true, true, false},
+		{"not a terminal", "y\n", false, false, false, true},
+		{"answer yes", "yes\n", true, false, true, false},
+		{"answer y", " Y \n", true, false, true, false},
+		{"answer no", "n\n", true, false, false, false},
+		{"no answer", "", true, false, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			c := &console{out: &out, in: strings.NewReader(tt.input), interactive: tt.interactive, yes: tt.yes}
+			got, err := c.confirm("Proceed?", "--yes")
+			if tt.wantErr {
+				if !errors.Is(err, errNotInteractive) || !strings.Contains(err.Error(), "--yes") {
+					t.Fatalf("confirm error = %v, want errNotInteractive naming --yes", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("confirm: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("confirm = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConsolePrompt_NotInteractive(t *testing.T) {
+	c := &console{out: &bytes.Buffer{}, in: strings.NewReader("app\n")}
+	if _, err := c.prompt("Enter database name", "the database name"); !errors.Is(err, errNotInteractive) {
+		t.Fatalf("prompt error = %v, want errNotInteractive", err)
+	}
+}
+
+func TestCheckNonInteractive(t *testing.T) {
+	no := false
+	tests := []struct {
+		name        string
+		opts        Options
+		interactive bool
+		wantErr     string
+	}{
+		{"terminal", Options{}, true, ""},
+		{"no instance", Options{Yes: true}, false, "--host"},
+		{"database name is no instance", Options{DBName: "app", Yes: true}, false, "--host"},
+		{"no confirmation", Options{Host: "db.example"}, false, "--yes"},
+		{"plan needs no confirmation", Options{Host: "db.example", Plan: true}, false, ""},
+		{"automated", Options{Host: "db.example", Yes: true}, false, ""},
+		{"resumed", Options{Instance: "db-1", Yes: true}, false, ""},
+		{"passwords discarded", Options{Output: OutputJSON, StoreSecrets: &no}, true, "discarded"},
+		{"passwords shown", Options{Output: OutputJSON, StoreSecrets: &no, ShowPasswords: true}, true, ""},
+		{"passwords emitted", Options{Output: OutputJSON, StoreSecrets: &no, Emit: []string{"dotenv=.env"}}, true, ""},
+		{"passwords only referenced", Options{Output: OutputJSON, StoreSecrets: &no, Emit: []string{"external-secret=es.yaml"}}, true, "discarded"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkNonInteractive(tt.opts, &console{interactive: tt.interactive, yes: tt.opts.Yes})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("checkNonInteractive: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("checkNonInteractive error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReport_Passwords(t *testing.T) {
+	users := []UserCredentials{{Username: "app", Password: "s3cret", Role: "migration", ConnLimit: 10}}
+	for _, show := range []bool{false, true} {
+		r := newReport()
+		r.setInstance(core.InstanceInfo{ID: "db-1", Host: "db-1.example", Port: 5432})
+		r.setDatabase("app", DefaultTemplate())
+		r.setUsers(users, show)
+		r.Steps = []StepResult{{Name: "Create database", Status: "FAILED", Error: errors.New("boom")}}
+		r.Status = RunFailed
+		var buf bytes.Buffer
+		if err := r.write(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Contains(buf.String(), "s3cret"); got != show {
+			t.Errorf("show=%v: password in report = %v", show, got)
+		}
+		var decoded struct {
+			Steps    []map[string]string `json:"steps"`
+			IAMUsers []string            `json:"iam_users"`
+		}
+		if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+			t.Fatal(err)
+		}
+		if len(decoded.Steps) != 1 || decoded.Steps[0]["error"] != "boom" {
+			t.Errorf("steps = %v", decoded.Steps)
+		}
+		if len(decoded.IAMUsers) != 1 || decoded.IAMUsers[0] != "app_iam" {
+			t.Errorf("iam_users = %v", decoded.IAMUsers)
+		}
+	}
+}
+
+func TestRun_ReportsEarlyFailures(t *testing.T) {
+	stdout := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout = w
+	runErr := Run(t.Context(), Options{Output: OutputJSON, DBName: "app", Template: filepath.Join(t.TempDir(), "missing.yaml")})
+	os.Stdout = stdout
+	w.Close()
+	if runErr == nil {
+		t.Fatal("Run: expected an error for a missing template")
+	}
+
+	var report Report
+	if err := json.NewDecoder(r).Decode(&report); err != nil {
+		t.Fatalf("no JSON report on stdout: %v", err)
+	}
+	if report.Status != RunFailed || report.Error != runErr.Error() {
+		t.Errorf("report status = %q, error = %q; want failed, %q", report.Status, report.Error, runErr)
+	}
+}
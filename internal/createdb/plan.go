@@ -0,0 +1,566 @@
+package createdb
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/PraveenPrabhuT/rds/internal/core"
+	"github.com/jackc/pgx/v5"
+)
+
+// privilegeSet is a set of privilege names as reported by aclexplode.
+type privilegeSet map[string]bool
+
+// grants maps grantee (PUBLIC for everyone) to its privileges.
+type grants map[string]privilegeSet
+
+func (g grants) add(grantee, privilege string) {
+	if g[grantee] == nil {
+		g[grantee] = privilegeSet{}
+	}
+	g[grantee][privilege] = true
+}
+
+// liveRole is an existing role.
+type liveRole struct {
+	login     bool
+	connLimit int
+}
+
+// defaultACLKey identifies default privileges on one object type in a
+// schema.
+type defaultACLKey struct {
+	grantor, schema, object string
+}
+
+// objectsKey identifies the objects of one type in a schema owned by one
+// role.
+type objectsKey struct {
+	schema, object, owner string
+}
+
+// liveState is what already exists on the instance for a template.
+type liveState struct {
+	superuser      string
+	databaseExists bool
+	roles          map[string]liveRole
+	// members holds "member\x00parent" for each membership.
+	members     map[string]bool
+	databaseACL grants
+	// schemaOwners and schemaACL are keyed by schema.
+	schemaOwners map[string]string
+	schemaACL    map[string]grants
+	// objects maps TABLES, SEQUENCES and FUNCTIONS in a schema, by owner,
+	// to each object's grants. A grant ON ALL ... IN SCHEMA only reaches
+	// the objects of the granting role, so only those are compared.
+	objects  map[objectsKey]map[string]grants
+	defaults map[defaultACLKey]grants
+	// available and extensions are the extensions the instance offers and
+	// those installed in the database.
+	available  map[string]bool
+	extensions map[string]bool
+	// settings maps a role ("" for the database itself) to its parameters
+	// in the database.
+	settings map[string]map[string]string
+}
+
+func newLiveState(superuser string) *liveState {
+	return &liveState{
+		superuser:    superuser,
+		roles:        map[string]liveRole{},
+		members:      map[string]bool{},
+		databaseACL:  grants{},
+		schemaOwners: map[string]string{},
+		schemaACL:    map[string]grants{},
+		objects:      map[objectsKey]map[string]grants{},
+		defaults:     map[defaultACLKey]grants{},
+		available:    map[string]bool{},
+		extensions:   map[string]bool{},
+		settings:     map[string]map[string]string{},
+	}
+}
+
+// Change is one difference between the template and the instance.
+type Change struct {
+	// Action is "+" to add, "~" to change or "-" to remove.
+	Action      string `json:"action"`
+	Description string `json:"description"`
+}
+
+// Plan is the delta between a template and the live instance.
+type Plan struct {
+	// Steps hold only the statements that are needed.
+	Steps   []Step
+	Changes []Change
+	// Created lists the usernames the plan creates, in creation order.
+	Created []string
+	// CreatesDatabase is set when the database does not exist yet.
+	CreatesDatabase bool
+}
+
+// Creates reports whether the plan creates user.
+func (p Plan) Creates(user string) bool {
+	return slices.Contains(p.Created, user)
+}
+
+// Empty reports whether the instance already matches the template.
+func (p Plan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+// BuildPlan inspects the instance as the superuser and returns the steps
+// needed to bring it in line with tmpl. It fails before any change when
+// the instance does not offer an extension tmpl declares or one of its
+// schemas belongs to another role than the owner.
+func BuildPlan(ctx context.Context, inst core.InstanceInfo, superCreds core.RDSCreds, defaultDB string,
+	tmpl *Template, dbName string, users []UserCredentials) (Plan, error) {
+	steps := buildOps(tmpl, dbName, users)
+
+	var roles []string
+	for _, s := range steps {
+		for _, o := range s.ops {
+			if o.kind == opCreateRole {
+				roles = append(roles, o.role)
+			}
+		}
+	}
+	state, err := inspect(ctx, inst, superCreds, defaultDB, dbName, tmpl.SchemaNames(), roles, tmpl.Extensions)
+	if err != nil {
+		return Plan{}, fmt.Errorf("inspect %s: %w", inst.ID, err)
+	}
+	if missing := state.unavailable(tmpl.Extensions); len(missing) > 0 {
+		return Plan{}, fmt.Errorf("extensions not available on %s: %s (see pg_available_extensions)", inst.ID, strings.Join(missing, ", "))
+	}
+	owner := tmpl.Owner().Username(dbName)
+	if foreign := state.foreignSchemas(tmpl.SchemaNames(), owner); len(foreign) > 0 {
+		return Plan{}, fmt.Errorf("schemas owned by another role than %q: %s (the owner grants on them; change their owner first)",
+			owner, strings.Join(foreign, ", "))
+	}
+	return diff(steps, state), nil
+}
+
+// addSetting records a name=value entry of pg_db_role_setting. PostgreSQL
+// stores names in their canonical spelling (TimeZone, DateStyle); they are
+// lower-cased like the template's.
+func (s *liveState) addSetting(role, setting string) {
+	name, value, _ := strings.Cut(setting, "=")
+	if s.settings[role] == nil {
+		s.settings[role] = map[string]string{}
+	}
+	s.settings[role][strings.ToLower(name)] = value
+}
+
+// foreignSchemas returns the schemas other than public that exist with
+// another owner than owner.
+func (s *liveState) foreignSchemas(schemas []string, owner string) []string {
+	var foreign []string
+	for _, schema := range schemas {
+		if existing, ok := s.schemaOwners[schema]; ok && schema != DefaultSchema && existing != owner {
+			foreign = append(foreign, fmt.Sprintf("%s (%s)", schema, existing))
+		}
+	}
+	return foreign
+}
+
+// unavailable returns the extensions that are neither installed nor
+// offered by the instance.
+func (s *liveState) unavailable(extensions []string) []string {
+	var missing []string
+	for _, ext := range extensions {
+		if !s.available[ext] && !s.extensions[ext] {
+			missing = append(missing, ext)
+		}
+	}
+	return missing
+}
+
+// diff keeps the ops of steps that state does not satisfy yet, turning
+// role creation into ALTER ROLE for roles that exist with other settings.
+func diff(steps []stepOps, state *liveState) Plan {
+	var plan Plan
+	for _, s := range steps {
+		var needed []op
+		for _, o := range s.ops {
+			if o.kind == opCreateRole {
+				if existing, ok := state.roles[o.role]; ok {
+					if alter, change, differs := alterRole(o, existing); differs {
+						needed = append(needed, alter)
+						plan.Changes = append(plan.Changes, change)
+					}
+					continue
+				}
+				plan.Created = append(plan.Created, o.role)
+			}
+			if state.satisfies(o) {
+				continue
+			}
+			if o.kind == opCreateDatabase {
+				plan.CreatesDatabase = true
+			}
+			needed = append(needed, o)
+			action := "+"
+			switch o.kind {
+			case opRevokePublicCreate:
+				action = "-"
+			case opDatabaseSetting, opRoleSetting:
+				if _, set := state.settings[o.role][o.object]; set {
+					action = "~"
+				}
+			}
+			plan.Changes = append(plan.Changes, Change{Action: action, Description: o.describe()})
+		}
+		if len(needed) > 0 {
+			s.ops = needed
+			plan.Steps = append(plan.Steps, s.step())
+		}
+	}
+	return plan
+}
+
+// withPasswordResets puts new passwords for users first. A resumed run
+// uses it for the users an earlier attempt created, whose passwords were
+// never shown or stored.
+func (p Plan) withPasswordResets(users []UserCredentials) Plan {
+	if len(users) == 0 {
+		return p
+	}
+	step := Step{Name: "Reset passwords of users from the failed run", ConnectAs: "superuser", ConnectDB: "default"}
+	var changes []Change
+	for _, u := range users {
+		step.Statements = append(step.Statements, fmt.Sprintf(`ALTER ROLE %s WITH ENCRYPTED PASSWORD %s`, ident(u.Username), literal(u.verifier())))
+		changes = append(changes, Change{Action: "~", Description: fmt.Sprintf("role %q: new password", u.Username)})
+	}
+	p.Steps = append([]Step{step}, p.Steps...)
+	p.Changes = append(changes, p.Changes...)
+	return p
+}
+
+// alterRole returns the ALTER ROLE that gives an existing role the login
+// and connection limit of o, if they differ. Passwords are left alone.
+func alterRole(o op, existing liveRole) (op, Change, bool) {
+	var clauses, diffs []string
+	if !existing.login {
+		clauses = append(clauses, "LOGIN")
+		diffs = append(diffs, "nologin -> login")
+	}
+	if o.connLimit != nil && *o.connLimit != existing.connLimit {
+		clauses = append(clauses, fmt.Sprintf("CONNECTION LIMIT %d", *o.connLimit))
+		diffs = append(diffs, fmt.Sprintf("conn_limit %d -> %d", existing.connLimit, *o.connLimit))
+	}
+	if len(clauses) == 0 {
+		return op{}, Change{}, false
+	}
+	alter := o
+	alter.sql = fmt.Sprintf(`ALTER ROLE %s WITH %s`, ident(o.role), strings.Join(clauses, " "))
+	return alter, Change{Action: "~", Description: fmt.Sprintf("role %q: %s", o.role, strings.Join(diffs, ", "))}, true
+}
+
+// satisfies reports whether the live state already has what o establishes.
+func (s *liveState) satisfies(o op) bool {
+	switch o.kind {
+	case opCreateDatabase:
+		return s.databaseExists
+	case opMembership:
+		return s.members[o.role+"\x00"+o.parent]
+	case opDatabaseGrant:
+		return hasAll(s.databaseACL[o.role], expandPrivileges("database", o.privs))
+	case opCreateSchema:
+		_, exists := s.schemaOwners[o.object]
+		return exists
+	case opRevokePublicCreate:
+		// A new database's public schema lets everyone create objects
+		// before PostgreSQL 15.
+		return s.databaseExists && !s.schemaACL[o.object]["PUBLIC"]["CREATE"]
+	case opSchemaGrant:
+		return hasAll(s.schemaACL[o.object][o.role], expandPrivileges("schema", o.privs))
+	case opObjectGrant:
+		want := expandPrivileges(strings.ToLower(o.object), o.privs)
+		grantor := o.grantor
+		if grantor == "" {
+			grantor = s.superuser
+		}
+		for _, acl := range s.objects[objectsKey{schema: o.schema, object: o.object, owner: grantor}] {
+			if !hasAll(acl[o.role], want) {
+				return false
+			}
+		}
+		return s.databaseExists
+	case opDefaultPrivileges:
+		grantor := o.grantor
+		if grantor == "" {
+			grantor = s.superuser
+		}
+		acl := s.defaults[defaultACLKey{grantor: grantor, schema: o.schema, object: o.object}]
+		return hasAll(acl[o.role], expandPrivileges(strings.ToLower(o.object), o.privs))
+	case opCreateExtension:
+		return s.extensions[o.object]
+	case opDatabaseSetting, opRoleSetting:
+		value, set := s.settings[o.role][o.object]
+		return set && value == o.value
+	}
+	return false
+}
+
+func hasAll(have privilegeSet, want []string) bool {
+	for _, p := range want {
+		if !have[p] {
+			return false
+		}
+	}
+	return true
+}
+
+// expandPrivileges resolves ALL and TEMP to the privilege names reported by
+// aclexplode for kind (database, schema, tables, sequences or functions).
+func expandPrivileges(kind string, privs []string) []string {
+	var out []string
+	for _, p := range privs {
+		switch p = strings.ToUpper(p); p {
+		case "ALL":
+			for _, q := range allowedPrivileges[kind] {
+				if q != "ALL" && q != "TEMP" {
+					out = append(out, q)
+				}
+			}
+		case "TEMP":
+			out = append(out, "TEMPORARY")
+		default:
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// inspect reads roles, memberships, the available extensions, the database
+// ACL and parameters from defaultDB and, when the database exists, the
+// schema owners, schema, object and default ACLs and the installed
+// extensions from it.
+func inspect(ctx context.Context, inst core.InstanceInfo, creds core.RDSCreds, defaultDB, dbName string, schemas, roles, extensions []string) (*liveState, error) {
+	state := newLiveState(creds.Username)
+
+	conn, err := core.NewPgxConn(ctx, inst.Host, inst.Port, creds.Username, creds.Password, defaultDB)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close(context.Background())
+
+	if err := conn.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM pg_catalog.pg_database WHERE datname = $1)`, dbName).Scan(&state.databaseExists); err != nil {
+		return nil, err
+	}
+	err = scanRows(ctx, conn, `SELECT rolname, rolcanlogin, rolconnlimit FROM pg_catalog.pg_roles WHERE rolname::text = ANY($1)`,
+		[]any{roles}, func(rows pgx.Rows) error {
+			var name string
+			var r liveRole
+			if err := rows.Scan(&name, &r.login, &r.connLimit); err != nil {
+				return err
+			}
+			state.roles[name] = r
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	err = scanRows(ctx, conn, `SELECT m.rolname, g.rolname
+FROM pg_catalog.pg_auth_members am
+JOIN pg_catalog.pg_roles g ON g.oid = am.roleid
+JOIN pg_catalog.pg_roles m ON m.oid = am.member
+WHERE m.rolname::text = ANY($1)`, []any{roles}, func(rows pgx.Rows) error {
+		var member, parent string
+		if err := rows.Scan(&member, &parent); err != nil {
+			return err
+		}
+		state.members[member+"\x00"+parent] = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = scanRows(ctx, conn, `SELECT name FROM pg_catalog.pg_available_extensions WHERE name = ANY($1)`,
+		[]any{extensions}, func(rows pgx.Rows) error {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				return err
+			}
+			state.available[name] = true
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	if !state.databaseExists {
+		return state, nil
+	}
+	err = scanRows(ctx, conn, `SELECT coalesce(r.rolname, ''), unnest(s.setconfig)
+FROM pg_catalog.pg_db_role_setting s
+JOIN pg_catalog.pg_database d ON d.oid = s.setdatabase
+LEFT JOIN pg_catalog.pg_roles r ON r.oid = s.setrole
+WHERE d.datname = $1`, []any{dbName}, func(rows pgx.Rows) error {
+		var role, setting string
+		if err := rows.Scan(&role, &setting); err != nil {
+			return err
+		}
+		state.addSetting(role, setting)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = scanGrants(ctx, conn, `SELECT coalesce(r.rolname, 'PUBLIC'), a.privilege_type
+FROM pg_catalog.pg_database d
+CROSS JOIN LATERAL aclexplode(coalesce(d.datacl, acldefault('d', d.datdba))) a
+LEFT JOIN pg_catalog.pg_roles r ON r.oid = a.grantee
+WHERE d.datname = $1`, []any{dbName}, state.databaseACL)
+	if err != nil {
+		return nil, err
+	}
+
+	dbConn, err := core.NewPgxConn(ctx, inst.Host, inst.Port, creds.Username, creds.Password, dbName)
+	if err != nil {
+		return nil, err
+	}
+	defer dbConn.Close(context.Background())
+
+	err = scanRows(ctx, dbConn, `SELECT extname FROM pg_catalog.pg_extension`, nil, func(rows pgx.Rows) error {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		state.extensions[name] = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = scanRows(ctx, dbConn, `SELECT n.nspname, o.rolname
+FROM pg_catalog.pg_namespace n
+JOIN pg_catalog.pg_roles o ON o.oid = n.nspowner
+WHERE n.nspname = ANY($1)`, []any{schemas}, func(rows pgx.Rows) error {
+		var schema, owner string
+		if err := rows.Scan(&schema, &owner); err != nil {
+			return err
+		}
+		state.schemaOwners[schema] = owner
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = scanRows(ctx, dbConn, `SELECT n.nspname, coalesce(r.rolname, 'PUBLIC'), a.privilege_type
+FROM pg_catalog.pg_namespace n
+CROSS JOIN LATERAL aclexplode(coalesce(n.nspacl, acldefault('n', n.nspowner))) a
+LEFT JOIN pg_catalog.pg_roles r ON r.oid = a.grantee
+WHERE n.nspname = ANY($1)`, []any{schemas}, func(rows pgx.Rows) error {
+		var schema, grantee, privilege string
+		if err := rows.Scan(&schema, &grantee, &privilege); err != nil {
+			return err
+		}
+		if state.schemaACL[schema] == nil {
+			state.schemaACL[schema] = grants{}
+		}
+		state.schemaACL[schema].add(grantee, privilege)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = scanRows(ctx, dbConn, `SELECT n.nspname, CASE WHEN c.relkind = 'S' THEN 'SEQUENCES' ELSE 'TABLES' END, o.rolname, c.relname,
+       coalesce(r.rolname, 'PUBLIC'), a.privilege_type
+FROM pg_catalog.pg_class c
+JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+JOIN pg_catalog.pg_roles o ON o.oid = c.relowner
+CROSS JOIN LATERAL aclexplode(coalesce(c.relacl, acldefault(CASE WHEN c.relkind = 'S' THEN 's' ELSE 'r' END::"char", c.relowner))) a
+LEFT JOIN pg_catalog.pg_roles r ON r.oid = a.grantee
+WHERE n.nspname = ANY($1) AND c.relkind IN ('r', 'p', 'v', 'm', 'f', 'S')
+UNION ALL
+SELECT n.nspname, 'FUNCTIONS', o.rolname, p.oid::regprocedure::text, coalesce(r.rolname, 'PUBLIC'), a.privilege_type
+FROM pg_catalog.pg_proc p
+JOIN pg_catalog.pg_namespace n ON n.oid = p.pronamespace
+JOIN pg_catalog.pg_roles o ON o.oid = p.proowner
+CROSS JOIN LATERAL aclexplode(coalesce(p.proacl, acldefault('f', p.proowner))) a
+LEFT JOIN pg_catalog.pg_roles r ON r.oid = a.grantee
+WHERE n.nspname = ANY($1) AND p.prokind IN ('f', 'a', 'w')`, []any{schemas}, func(rows pgx.Rows) error {
+		var key objectsKey
+		var name, grantee, privilege string
+		if err := rows.Scan(&key.schema, &key.object, &key.owner, &name, &grantee, &privilege); err != nil {
+			return err
+		}
+		if state.objects[key] == nil {
+			state.objects[key] = map[string]grants{}
+		}
+		if state.objects[key][name] == nil {
+			state.objects[key][name] = grants{}
+		}
+		state.objects[key][name].add(grantee, privilege)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = scanRows(ctx, dbConn, `SELECT g.rolname, n.nspname,
+       CASE d.defaclobjtype WHEN 'r' THEN 'TABLES' WHEN 'S' THEN 'SEQUENCES' WHEN 'f' THEN 'FUNCTIONS' ELSE d.defaclobjtype::text END,
+       coalesce(r.rolname, 'PUBLIC'), a.privilege_type
+FROM pg_catalog.pg_default_acl d
+JOIN pg_catalog.pg_roles g ON g.oid = d.defaclrole
+JOIN pg_catalog.pg_namespace n ON n.oid = d.defaclnamespace
+CROSS JOIN LATERAL aclexplode(d.defaclacl) a
+LEFT JOIN pg_catalog.pg_roles r ON r.oid = a.grantee
+WHERE n.nspname = ANY($1)`, []any{schemas}, func(rows pgx.Rows) error {
+		var key defaultACLKey
+		var grantee, privilege string
+		if err := rows.Scan(&key.grantor, &key.schema, &key.object, &grantee, &privilege); err != nil {
+			return err
+		}
+		if state.defaults[key] == nil {
+			state.defaults[key] = grants{}
+		}
+		state.defaults[key].add(grantee, privilege)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+func scanRows(ctx context.Context, conn *pgx.Conn, sql string, args []any, fn func(pgx.Rows) error) error {
+	rows, err := conn.Query(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err := fn(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func scanGrants(ctx context.Context, conn *pgx.Conn, sql string, args []any, into grants) error {
+	return scanRows(ctx, conn, sql, args, func(rows pgx.Rows) error {
+		var grantee, privilege string
+		if err := rows.Scan(&grantee, &privilege); err != nil {
+			return err
+		}
+		into.add(grantee, privilege)
+		return nil
+	})
+}
+
+// printPlan writes the changes Terraform-style, followed by a summary line.
+func printPlan(w io.Writer, plan Plan) {
+	if plan.Empty() {
+		fmt.Fprintln(w, "No changes. The instance matches the template.")
+		return
+	}
+	fmt.Fprintln(w, "=== Plan ===")
+	counts := map[string]int{}
+	for _, c := range plan.Changes {
+		fmt.Fprintf(w, "  %s %s\n", c.Action, c.Description)
+		counts[c.Action]++
+	}
+	fmt.Fprintf(w, "\nPlan: %d to add, %d to change, %d to remove.\n\n", counts["+"], counts["~"], counts["-"])
+}
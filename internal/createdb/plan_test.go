@@ -0,0 +1,249 @@
+package createdb
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func defaultOps(t *testing.T) ([]stepOps, []UserCredentials) {
+	t.Helper()
+	tmpl := DefaultTemplate()
+	users, err := generateCredentials("app", tmpl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buildOps(tmpl, "app", users), users
+}
+
+func TestDiff_NothingExists(t *testing.T) {
+	steps, users := defaultOps(t)
+	plan := diff(steps, newLiveState("postgres"))
+
+	if !reflect.DeepEqual(plan.Steps, BuildSteps(DefaultTemplate(), "app", users)) {
+		t.Error("plan on an empty instance should contain every step")
+	}
+	if len(plan.Created) != 6 || !plan.CreatesDatabase {
+		t.Errorf("created = %v, database %v; want 6 users and the database", plan.Created, plan.CreatesDatabase)
+	}
+	if plan.Changes[0] != (Change{"+", `database "app"`}) {
+		t.Errorf("first change = %+v", plan.Changes[0])
+	}
+}
+
+func TestDiff_PartiallyProvisioned(t *testing.T) {
+	steps, _ := defaultOps(t)
+	state := newLiveState("postgres")
+	state.databaseExists = true
+	for _, u := range []string{"app", "app_ro_v1", "app_ro_v2", "app_rw_v1", "app_rw_v2"} {
+		state.roles[u] = liveRole{login: true, connLimit: 10}
+	}
+	state.roles["app_rw_v2"] = liveRole{login: true, connLimit: 5}
+	state.members["app_ro_v2\x00app_ro_v1"] = true
+	state.members["app_rw_v2\x00app_rw_v1"] = true
+	state.databaseACL.add("app", "CREATE")
+	state.databaseACL.add("app", "CONNECT")
+	state.databaseACL.add("app", "TEMPORARY")
+	state.schemaACL["public"] = grants{}
+	state.schemaACL["public"].add("app", "CREATE")
+	// A table the read-only user can already read, the read-write user not;
+	// there are no sequences, so sequence grants have nothing to do.
+	state.objects[objectsKey{"public", "TABLES", "app"}] = map[string]grants{"orders": {"app_ro_v1": {"SELECT": true}}}
+	// A view an extension created as the superuser, which the owner's
+	// grants cannot reach.
+	state.objects[objectsKey{"public", "TABLES", "postgres"}] = map[string]grants{"pg_stat_statements": {"postgres": {"SELECT": true}}}
+	state.defaults[defaultACLKey{"app", "public", "TABLES"}] = grants{"app_ro_v1": {"SELECT": true}}
+	state.defaults[defaultACLKey{"app", "public", "SEQUENCES"}] = grants{"app_ro_v1": {"USAGE": true, "SELECT": true}}
+
+	plan := diff(steps, state)
+
+	var got []string
+	for _, c := range plan.Changes {
+		got = append(got, c.Action+" "+c.Description)
+	}
+	want := []string{
+		`~ role "app_rw_v2": conn_limit 5 -> 10`,
+		`+ grant SELECT, INSERT, UPDATE, DELETE on all tables in "public" to "app_rw_v1"`,
+		`+ default privileges SELECT, INSERT, UPDATE, DELETE on tables in "public" created by "app" to "app_rw_v1"`,
+		`+ default privileges USAGE, SELECT, UPDATE on sequences in "public" created by "app" to "app_rw_v1"`,
+		`+ role "app_iam" (login, conn_limit=unlimited)`,
+		`+ membership "app_iam" in "rds_iam"`,
+		`+ grant CONNECT on database "app" to "app_iam"`,
+		`+ grant USAGE, CREATE on schema "public" to "app_iam"`,
+		`+ grant SELECT, INSERT, UPDATE, DELETE on all tables in "public" to "app_iam"`,
+		`+ default privileges SELECT, INSERT, UPDATE, DELETE on tables in "public" created by the superuser to "app_iam"`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("changes:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	var names []string
+	for _, s := range plan.Steps {
+		names = append(names, s.Name)
+	}
+	wantNames := []string{"Create users", `Grant read-write privileges in "public" to "app_rw_v1"`, `Create IAM user "app_iam"`}
+	if !reflect.DeepEqual(names, wantNames) {
+		t.Errorf("steps = %q, want %q", names, wantNames)
+	}
+	if got := plan.Steps[0].Statements; !reflect.DeepEqual(got, []string{`ALTER ROLE "app_rw_v2" WITH CONNECTION LIMIT 10`}) {
+		t.Errorf("alter = %q", got)
+	}
+	if !reflect.DeepEqual(plan.Created, []string{"app_iam"}) || plan.CreatesDatabase {
+		t.Errorf("created = %v", plan.Created)
+	}
+}
+
+func TestDiff_PublicCreate(t *testing.T) {
+	steps, _ := defaultOps(t)
+	state := newLiveState("postgres")
+	state.databaseExists = true
+	state.schemaACL["public"] = grants{"PUBLIC": {"CREATE": true}}
+
+	plan := diff(steps, state)
+	found := false
+	for _, c := range plan.Changes {
+		if c.Action == "-" && c.Description == `grant CREATE on schema "public" to PUBLIC` {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected removal of PUBLIC CREATE, got %+v", plan.Changes)
+	}
+}
+
+func TestAlterRole(t *testing.T) {
+	ten := 10
+	o := op{kind: opCreateRole, role: "r", login: true, connLimit: &ten}
+	if _, _, differs := alterRole(o, liveRole{login: true, connLimit: 10}); differs {
+		t.Error("same settings should not differ")
+	}
+	alter, change, differs := alterRole(o, liveRole{connLimit: -1})
+	if !differs || alter.sql != `ALTER ROLE "r" WITH LOGIN CONNECTION LIMIT 10` || change.Description != `role "r": nologin -> login, conn_limit -1 -> 10` {
+		t.Errorf("alterRole = %q, %+v", alter.sql, change)
+	}
+	if _, _, differs := alterRole(op{kind: opCreateRole, role: "iam"}, liveRole{login: true, connLimit: 3}); differs {
+		t.Error("unset conn limit should not be compared")
+	}
+}
+
+func TestExpandPrivileges(t *testing.T) {
+	tests := []struct {
+		kind  string
+		privs []string
+		want  []string
+	}{
+		{"database", []string{"ALL"}, []string{"CONNECT", "CREATE", "TEMPORARY"}},
+		{"database", []string{"temp"}, []string{"TEMPORARY"}},
+		{"schema", []string{"all"}, []string{"USAGE", "CREATE"}},
+		{"sequences", []string{"USAGE", "select"}, []string{"USAGE", "SELECT"}},
+	}
+	for _, tt := range tests {
+		if got := expandPrivileges(tt.kind, tt.privs); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("expandPrivileges(%s, %v) = %v, want %v", tt.kind, tt.privs, got, tt.want)
+		}
+	}
+}
+
+func TestPrintPlan(t *testing.T) {
+	var buf bytes.Buffer
+	printPlan(&buf, Plan{})
+	if !strings.Contains(buf.String(), "No changes") {
+		t.Errorf("empty plan: %q", buf.String())
+	}
+
+	buf.Reset()
+	printPlan(&buf, Plan{Changes: []Change{{"+", "a"}, {"+", "b"}, {"~", "c"}, {"-", "d"}}})
+	if !strings.Contains(buf.String(), "  + a\n") || !strings.Contains(buf.String(), "Plan: 2 to add, 1 to change, 1 to remove.") {
+		t.Errorf("plan output:\n%s", buf.String())
+	}
+}
+
+func TestPlan_WithPasswordResets(t *testing.T) {
+	plan := Plan{Steps: []Step{{Name: "Grant"}}, Changes: []Change{{"+", "grant"}}}
+	if got := plan.withPasswordResets(nil); !reflect.DeepEqual(got, plan) {
+		t.Errorf("no resets changed the plan: %+v", got)
+	}
+	got := plan.withPasswordResets([]UserCredentials{{Username: "app", Password: "pw", Verifier: "SCRAM-SHA-256$v"}})
+	if len(got.Steps) != 2 || got.Steps[0].Statements[0] != `ALTER ROLE "app" WITH ENCRYPTED PASSWORD 'SCRAM-SHA-256$v'` {
+		t.Errorf("steps = %+v", got.Steps)
+	}
+	if got.Changes[0] != (Change{"~", `role "app": new password`}) || got.Empty() {
+		t.Errorf("changes = %+v", got.Changes)
+	}
+}
+
+func TestDiff_ExtensionsAndSettings(t *testing.T) {
+	tmpl, err := ParseTemplate([]byte(settingsTemplate))
+	if err != nil {
+		t.Fatal(err)
+	}
+	users, err := generateCredentials("app", tmpl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := newLiveState("postgres")
+	state.databaseExists = true
+	state.extensions["pg_stat_statements"] = true
+	state.addSetting("", "TimeZone=UTC")
+	state.addSetting("app_ro", "statement_timeout=10s")
+	state.addSetting("app_ro", `search_path=app, "$user", public`)
+
+	plan := diff(buildOps(tmpl, "app", users), state)
+
+	var got []string
+	for _, c := range plan.Changes {
+		if strings.Contains(c.Description, "extension") || strings.Contains(c.Description, "parameter") {
+			got = append(got, c.Action+" "+c.Description)
+		}
+	}
+	want := []string{
+		`+ extension "uuid-ossp"`,
+		`~ parameter statement_timeout = 30s for "app_ro"`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("changes =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestUnavailableExtensions(t *testing.T) {
+	state := newLiveState("postgres")
+	state.available["pgcrypto"] = true
+	state.extensions["legacy"] = true
+	if got := state.unavailable([]string{"pgcrypto", "legacy", "timescaledb"}); !reflect.DeepEqual(got, []string{"timescaledb"}) {
+		t.Errorf("unavailable = %v, want [timescaledb]", got)
+	}
+}
+
+func TestDiff_ExistingSchemas(t *testing.T) {
+	tmpl := DefaultTemplate()
+	if err := tmpl.AddSchemas([]string{"app", "audit"}); err != nil {
+		t.Fatal(err)
+	}
+	users, err := generateCredentials("app", tmpl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := newLiveState("postgres")
+	state.databaseExists = true
+	state.schemaOwners["app"] = "app"
+
+	plan := diff(buildOps(tmpl, "app", users), state)
+	var created []string
+	for _, s := range plan.Steps {
+		for _, stmt := range s.Statements {
+			if strings.HasPrefix(stmt, "CREATE SCHEMA") {
+				created = append(created, stmt)
+			}
+		}
+	}
+	if want := []string{`CREATE SCHEMA IF NOT EXISTS "audit"`}; !reflect.DeepEqual(created, want) {
+		t.Errorf("created schemas = %q, want %q", created, want)
+	}
+
+	state.schemaOwners["audit"] = "someone_else"
+	state.schemaOwners["public"] = "pg_database_owner"
+	if got := state.foreignSchemas(tmpl.SchemaNames(), "app"); !reflect.DeepEqual(got, []string{"audit (someone_else)"}) {
+		t.Errorf("foreignSchemas = %q", got)
+	}
+}
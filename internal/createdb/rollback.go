@@ -0,0 +1,121 @@
+package createdb
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/PraveenPrabhuT/rds/internal/core"
+	"github.com/jackc/pgx/v5"
+)
+
+// rollback drops what run j created, after confirmation.
+func rollback(ctx context.Context, con *console, inst core.InstanceInfo, superCreds core.RDSCreds, defaultDB string, j *Journal) error {
+	if !j.CreatedDatabase && len(j.CreatedUsers) == 0 {
+		fmt.Fprintln(con.out, "Nothing to roll back.")
+		return nil
+	}
+
+	conn, err := core.NewPgxConn(ctx, inst.Host, inst.Port, superCreds.Username, superCreds.Password, defaultDB)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+	var exists bool
+	err = conn.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM pg_catalog.pg_database WHERE datname = $1)`, j.Database).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	// A run that failed while creating users recorded users it never got
+	// to create; DROP OWNED BY fails on those.
+	var existing []string
+	err = scanRows(ctx, conn, `SELECT rolname FROM pg_catalog.pg_roles WHERE rolname = ANY($1)`, []any{j.CreatedUsers}, func(rows pgx.Rows) error {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		existing = append(existing, name)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	steps := rollbackSteps(j, exists, existing)
+	ok, err := confirmRollback(con, j)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		fmt.Fprintf(con.out, "Left as is. Resume with: rds db create --resume %s\n", j.ID)
+		return nil
+	}
+	results := executeSteps(ctx, con.out, steps, inst, superCreds, UserCredentials{}, j.Database,
+		Options{DefaultDB: defaultDB}, nil)
+	printResults(con.out, results)
+	for _, r := range results {
+		if r.Status == "FAILED" {
+			return fmt.Errorf("step %q failed: %w", r.Name, r.Error)
+		}
+	}
+	j.CreatedDatabase, j.CreatedUsers = false, nil
+	j.finish(RunRolledBack, nil)
+	fmt.Fprintf(con.out, "✅ Run %s rolled back\n", j.ID)
+	return nil
+}
+
+// rollbackSteps undoes a run in reverse: the privileges and objects in the
+// database (when it exists) of the created users that exist, the users in
+// reverse order of creation, then the database.
+func rollbackSteps(j *Journal, databaseExists bool, existingUsers []string) []Step {
+	var steps []Step
+	if len(j.CreatedUsers) > 0 {
+		quoted := make([]string, len(j.CreatedUsers))
+		var owners []string
+		for i, u := range j.CreatedUsers {
+			quoted[i] = ident(u)
+			if slices.Contains(existingUsers, u) {
+				owners = append(owners, quoted[i])
+			}
+		}
+		if databaseExists && len(owners) > 0 {
+			steps = append(steps, Step{
+				Name:       "Drop privileges and objects of created users",
+				ConnectAs:  "superuser",
+				ConnectDB:  "newdb",
+				Statements: []string{"DROP OWNED BY " + strings.Join(owners, ", ")},
+			})
+		}
+		drops := make([]string, 0, len(quoted))
+		for i := len(quoted) - 1; i >= 0; i-- {
+			drops = append(drops, "DROP ROLE IF EXISTS "+quoted[i])
+		}
+		steps = append(steps, Step{
+			Name:       "Drop created users",
+			ConnectAs:  "superuser",
+			ConnectDB:  "default",
+			Statements: drops,
+		})
+	}
+	if j.CreatedDatabase && databaseExists {
+		steps = append(steps, Step{
+			Name:       fmt.Sprintf("Drop database %q", j.Database),
+			ConnectAs:  "superuser",
+			ConnectDB:  "default",
+			Statements: []string{"DROP DATABASE IF EXISTS " + ident(j.Database)},
+		})
+	}
+	return steps
+}
+
+func confirmRollback(con *console, j *Journal) (bool, error) {
+	fmt.Fprintf(con.out, "\nRoll back run %s? This drops:\n", j.ID)
+	for i := len(j.CreatedUsers) - 1; i >= 0; i-- {
+		fmt.Fprintf(con.out, "  - user %s\n", j.CreatedUsers[i])
+	}
+	if j.CreatedDatabase {
+		fmt.Fprintf(con.out, "  - database %s\n", j.Database)
+	}
+	return con.confirm("Proceed?", "--yes")
+}
@@ -0,0 +1,49 @@
+package createdb
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRollbackSteps(t *testing.T) {
+	j := &Journal{Database: "app", CreatedDatabase: true, CreatedUsers: []string{"app", "app_ro_v1", "app_iam"}}
+
+	steps := rollbackSteps(j, true, j.CreatedUsers)
+	want := []Step{
+		{Name: "Drop privileges and objects of created users", ConnectAs: "superuser", ConnectDB: "newdb",
+			Statements: []string{`DROP OWNED BY "app", "app_ro_v1", "app_iam"`}},
+		{Name: "Drop created users", ConnectAs: "superuser", ConnectDB: "default",
+			Statements: []string{`DROP ROLE IF EXISTS "app_iam"`, `DROP ROLE IF EXISTS "app_ro_v1"`, `DROP ROLE IF EXISTS "app"`}},
+		{Name: `Drop database "app"`, ConnectAs: "superuser", ConnectDB: "default",
+			Statements: []string{`DROP DATABASE IF EXISTS "app"`}},
+	}
+	if !reflect.DeepEqual(steps, want) {
+		t.Errorf("rollbackSteps =\n%+v\nwant\n%+v", steps, want)
+	}
+
+	// The database was never created: only the users go.
+	steps = rollbackSteps(j, false, j.CreatedUsers)
+	if len(steps) != 1 || steps[0].Name != "Drop created users" {
+		t.Errorf("without database: %+v", steps)
+	}
+}
+
+func TestRollbackSteps_MissingUsers(t *testing.T) {
+	// Creating the users failed after app: the others were recorded but
+	// never created.
+	j := &Journal{Database: "app", CreatedDatabase: true, CreatedUsers: []string{"app", "app_ro_v1", "app_iam"}}
+
+	steps := rollbackSteps(j, true, []string{"app"})
+	if got := steps[0].Statements; !reflect.DeepEqual(got, []string{`DROP OWNED BY "app"`}) {
+		t.Errorf("drop owned = %q", got)
+	}
+	if got := steps[1].Statements; len(got) != 3 {
+		t.Errorf("drop roles = %q, want all three with IF EXISTS", got)
+	}
+
+	// None of them exist: there is nothing they could own.
+	steps = rollbackSteps(j, true, nil)
+	if len(steps) != 2 || steps[0].Name != "Drop created users" {
+		t.Errorf("without users: %+v", steps)
+	}
+}
@@ -0,0 +1,47 @@
+package createdb
+
+import (
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"regexp"
+)
+
+// scramIterations matches PostgreSQL's default scram_iterations.
+const scramIterations = 4096
+
+// ScramVerifier returns the SCRAM-SHA-256 verifier PostgreSQL stores for
+// password, with a random salt. Sent as the password in CREATE/ALTER ROLE,
+// the server stores it as is, so the plaintext never leaves this process.
+// Generated passwords are ASCII alphanumerics, for which SASLprep is the
+// identity.
+func ScramVerifier(password string) string {
+	salt := make([]byte, 16)
+	rand.Read(salt)
+	return scramVerifier(password, salt, scramIterations)
+}
+
+func scramVerifier(password string, salt []byte, iterations int) string {
+	salted, _ := pbkdf2.Key(sha256.New, password, salt, iterations, sha256.Size)
+	clientKey := hmacSHA256(salted, "Client Key")
+	storedKey := sha256.Sum256(clientKey)
+	serverKey := hmacSHA256(salted, "Server Key")
+	b64 := base64.StdEncoding.EncodeToString
+	return fmt.Sprintf("SCRAM-SHA-256$%d:%s$%s:%s", iterations, b64(salt), b64(storedKey[:]), b64(serverKey))
+}
+
+func hmacSHA256(key []byte, msg string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(msg))
+	return h.Sum(nil)
+}
+
+var passwordLiteral = regexp.MustCompile(`(?i)(PASSWORD\s+)'(?:[^']|'')*'`)
+
+// Redact hides password literals in stmt for display and error messages.
+func Redact(stmt string) string {
+	return passwordLiteral.ReplaceAllString(stmt, "${1}'<redacted>'")
+}
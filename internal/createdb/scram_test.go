@@ -0,0 +1,61 @@
+package createdb
+
+import (
+	"encoding/base64"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestScramVerifier_KnownVector(t *testing.T) {
+	salt, _ := base64.StdEncoding.DecodeString("W22ZaJ0SNY7soEsUEjb6gQ==")
+	want := "SCRAM-SHA-256$4096:W22ZaJ0SNY7soEsUEjb6gQ==$WG5d8oPm3OtcPnkdi4Uo7BkeZkBFzpcXkuLmtbsT4qY=:wfPLwcE6nTWhTAmQ7tl2KeoiWGPlZqQxSrmfPwDl2dU="
+	if got := scramVerifier("pencil", salt, 4096); got != want {
+		t.Errorf("scramVerifier = %s, want %s", got, want)
+	}
+}
+
+func TestScramVerifier_RandomSalt(t *testing.T) {
+	format := regexp.MustCompile(`^SCRAM-SHA-256\$4096:[A-Za-z0-9+/]{22}==\$[A-Za-z0-9+/]{43}=:[A-Za-z0-9+/]{43}=$`)
+	a, b := ScramVerifier("secret"), ScramVerifier("secret")
+	if !format.MatchString(a) {
+		t.Errorf("verifier format: %s", a)
+	}
+	if a == b {
+		t.Error("two verifiers of the same password share a salt")
+	}
+}
+
+func TestBuildSteps_NoPlaintextPasswords(t *testing.T) {
+	tmpl := DefaultTemplate()
+	users, err := generateCredentials("app", tmpl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, step := range BuildSteps(tmpl, "app", users) {
+		for _, stmt := range step.Statements {
+			for _, u := range users {
+				if strings.Contains(stmt, u.Password) {
+					t.Errorf("plaintext password of %s in %q", u.Username, stmt)
+				}
+			}
+			if strings.HasPrefix(stmt, "CREATE USER") && !strings.Contains(stmt, "'SCRAM-SHA-256$4096:") && !strings.HasSuffix(stmt, "WITH LOGIN") {
+				t.Errorf("CREATE USER without a verifier: %q", stmt)
+			}
+		}
+	}
+}
+
+func TestRedact(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{`CREATE USER "a" WITH ENCRYPTED PASSWORD 'SCRAM-SHA-256$4096:x$y:z' CONNECTION LIMIT 10`,
+			`CREATE USER "a" WITH ENCRYPTED PASSWORD '<redacted>' CONNECTION LIMIT 10`},
+		{`alter role a password 'it''s'`, `alter role a password '<redacted>'`},
+		{`GRANT "a" TO "b"`, `GRANT "a" TO "b"`},
+	}
+	for _, tt := range tests {
+		if got := Redact(tt.in); got != tt.want {
+			t.Errorf("Redact(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
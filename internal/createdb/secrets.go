@@ -3,36 +3,188 @@ package createdb
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
 
+	"github.com/PraveenPrabhuT/rds/internal/core"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	smtypes "github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
 )
 
-// StoreCredentials saves all user credentials as a single JSON secret in
-// AWS Secrets Manager at <dbName>/<instanceID>/psql. Structure: {"username": "password"}.
-func StoreCredentials(ctx context.Context, cfg aws.Config, homeRegion, dbName, instanceID string, users []UserCredentials) error {
-	payload := make(map[string]string)
-	for _, u := range users {
-		payload[u.Username] = u.Password
-	}
+// SecretOptions configures the secrets StoreCredentials writes.
+type SecretOptions struct {
+	// Team is set as the team tag, next to the db and instance tags.
+	Team string
+	// KMSKeyID encrypts the secrets with a customer managed key instead of
+	// the account's aws/secretsmanager key.
+	KMSKeyID string
+	// Description replaces the generated secret description.
+	Description string
+	// PerUser also stores each user in its own
+	// <dbName>/<instanceID>/users/<username> secret, in the JSON shape of
+	// RDS-managed secrets.
+	PerUser bool
+}
 
-	data, err := json.Marshal(payload)
-	if err != nil {
-		return fmt.Errorf("marshal credentials: %w", err)
-	}
+// StoredSecrets are the ARNs of the secrets StoreCredentials wrote.
+type StoredSecrets struct {
+	ARN string
+	// UserARNs maps usernames to their per-user secret.
+	UserARNs map[string]string
+}
+
+// rdsSecret is the JSON shape of RDS-managed secrets, which other tooling
+// (such as the RDS proxy and rotation lambdas) reads.
+type rdsSecret struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Host     string `json:"host"`
+	Port     int32  `json:"port"`
+	DBName   string `json:"dbname"`
+	Engine   string `json:"engine"`
+}
+
+// CredentialsSecretID is the secret holding the {"username": "password"}
+// map of a database's users.
+func CredentialsSecretID(dbName, instanceID string) string {
+	return fmt.Sprintf("%s/%s/psql", dbName, instanceID)
+}
 
+// UserSecretID is the per-user secret of username.
+func UserSecretID(dbName, instanceID, username string) string {
+	return fmt.Sprintf("%s/%s/users/%s", dbName, instanceID, username)
+}
+
+// StoreCredentials saves the user credentials in AWS Secrets Manager at
+// <dbName>/<instanceID>/psql. Structure: {"username": "password"}. An
+// existing secret keeps the users it already holds and gets a new version
+// with these users' passwords replaced.
+func StoreCredentials(ctx context.Context, cfg aws.Config, homeRegion, dbName string, inst core.InstanceInfo, users []UserCredentials, opts SecretOptions) (StoredSecrets, error) {
 	sm := secretsmanager.NewFromConfig(cfg, func(o *secretsmanager.Options) {
 		o.Region = homeRegion
 	})
+	tags := secretTags(opts.Team, dbName, inst.ID)
+	description := opts.Description
+	if description == "" {
+		description = fmt.Sprintf("Credentials of the %s database users on %s, created by rds db create", dbName, inst.ID)
+	}
 
-	secretID := fmt.Sprintf("%s/%s/psql", dbName, instanceID)
-	_, err = sm.CreateSecret(ctx, &secretsmanager.CreateSecretInput{
-		Name:         &secretID,
-		SecretString: aws.String(string(data)),
-	})
+	secretID := CredentialsSecretID(dbName, inst.ID)
+	var existing string
+	out, err := sm.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{SecretId: &secretID})
+	var notFound *smtypes.ResourceNotFoundException
+	switch {
+	case err == nil:
+		existing = aws.ToString(out.SecretString)
+	case !errors.As(err, &notFound):
+		return StoredSecrets{}, fmt.Errorf("read secret %q: %w", secretID, err)
+	}
+	data, err := mergeCredentials(existing, users)
 	if err != nil {
-		return fmt.Errorf("create secret %q: %w", secretID, err)
+		return StoredSecrets{}, fmt.Errorf("secret %q: %w", secretID, err)
+	}
+	var stored StoredSecrets
+	if stored.ARN, err = putSecret(ctx, sm, secretID, data, description, opts, tags); err != nil {
+		return StoredSecrets{}, err
+	}
+
+	if !opts.PerUser {
+		return stored, nil
+	}
+	stored.UserARNs = map[string]string{}
+	for _, u := range users {
+		data, err := json.Marshal(rdsSecret{
+			Username: u.Username,
+			Password: u.Password,
+			Host:     inst.Host,
+			Port:     inst.Port,
+			DBName:   dbName,
+			Engine:   "postgres",
+		})
+		if err != nil {
+			return stored, fmt.Errorf("marshal credentials: %w", err)
+		}
+		id := UserSecretID(dbName, inst.ID, u.Username)
+		desc := fmt.Sprintf("Credentials of %s (%s) on the %s database on %s, created by rds db create", u.Username, u.Role, dbName, inst.ID)
+		arn, err := putSecret(ctx, sm, id, string(data), desc, opts, tags)
+		if err != nil {
+			return stored, err
+		}
+		stored.UserARNs[u.Username] = arn
+	}
+	return stored, nil
+}
+
+// putSecret creates secret id, or adds a version to it when it already
+// exists and brings its description, KMS key and tags up to date.
+func putSecret(ctx context.Context, sm *secretsmanager.Client, id, value, description string, opts SecretOptions, tags []smtypes.Tag) (string, error) {
+	create := &secretsmanager.CreateSecretInput{
+		Name:         &id,
+		SecretString: &value,
+		Description:  &description,
+		Tags:         tags,
+	}
+	if opts.KMSKeyID != "" {
+		create.KmsKeyId = &opts.KMSKeyID
+	}
+	out, err := sm.CreateSecret(ctx, create)
+	if err == nil {
+		return aws.ToString(out.ARN), nil
+	}
+	var exists *smtypes.ResourceExistsException
+	if !errors.As(err, &exists) {
+		return "", fmt.Errorf("create secret %q: %w", id, err)
+	}
+
+	update := &secretsmanager.UpdateSecretInput{SecretId: &id, Description: &description}
+	if opts.KMSKeyID != "" {
+		update.KmsKeyId = &opts.KMSKeyID
+	}
+	if _, err := sm.UpdateSecret(ctx, update); err != nil {
+		return "", fmt.Errorf("update secret %q: %w", id, err)
+	}
+	put, err := sm.PutSecretValue(ctx, &secretsmanager.PutSecretValueInput{SecretId: &id, SecretString: &value})
+	if err != nil {
+		return "", fmt.Errorf("put secret %q: %w", id, err)
+	}
+	if _, err := sm.TagResource(ctx, &secretsmanager.TagResourceInput{SecretId: &id, Tags: tags}); err != nil {
+		return "", fmt.Errorf("tag secret %q: %w", id, err)
+	}
+	return aws.ToString(put.ARN), nil
+}
+
+// mergeCredentials adds users to the {"username": "password"} map in
+// existing (empty for a new secret), replacing their old passwords.
+func mergeCredentials(existing string, users []UserCredentials) (string, error) {
+	payload := map[string]string{}
+	if existing != "" {
+		if err := json.Unmarshal([]byte(existing), &payload); err != nil {
+			return "", fmt.Errorf("existing value is not a {\"username\": \"password\"} map: %w", err)
+		}
+	}
+	for _, u := range users {
+		payload[u.Username] = u.Password
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("marshal credentials: %w", err)
+	}
+	return string(data), nil
+}
+
+// secretTags tags a secret with its team (when known), database and
+// instance.
+func secretTags(team, dbName, instanceID string) []smtypes.Tag {
+	values := map[string]string{"db": dbName, "instance": instanceID}
+	if team != "" {
+		values["team"] = team
+	}
+	var tags []smtypes.Tag
+	for _, k := range slices.Sorted(maps.Keys(values)) {
+		tags = append(tags, smtypes.Tag{Key: aws.String(k), Value: aws.String(values[k])})
 	}
-	return nil
+	return tags
 }
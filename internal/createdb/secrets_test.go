@@ -0,0 +1,89 @@
+package createdb
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+func TestMergeCredentials(t *testing.T) {
+	users := []UserCredentials{{Username: "app", Password: "new"}, {Username: "app_ro_v1", Password: "ro"}}
+	tests := []struct {
+		name     string
+		existing string
+		want     map[string]string
+		wantErr  bool
+	}{
+		{"new secret", "", map[string]string{"app": "new", "app_ro_v1": "ro"}, false},
+		{"keeps other users", `{"app": "old", "app_rw_v1": "rw"}`, map[string]string{"app": "new", "app_ro_v1": "ro", "app_rw_v1": "rw"}, false},
+		{"not a map", `"plain"`, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mergeCredentials(tt.existing, users)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("mergeCredentials: want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var payload map[string]string
+			if err := json.Unmarshal([]byte(got), &payload); err != nil {
+				t.Fatal(err)
+			}
+			if len(payload) != len(tt.want) {
+				t.Fatalf("payload = %v, want %v", payload, tt.want)
+			}
+			for k, v := range tt.want {
+				if payload[k] != v {
+					t.Errorf("payload[%s] = %q, want %q", k, payload[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestSecretTags(t *testing.T) {
+	tests := []struct {
+		team string
+		want string
+	}{
+		{"", "db=app instance=db-1"},
+		{"payments", "db=app instance=db-1 team=payments"},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, tag := range secretTags(tt.team, "app", "db-1") {
+			got = append(got, aws.ToString(tag.Key)+"="+aws.ToString(tag.Value))
+		}
+		if strings.Join(got, " ") != tt.want {
+			t.Errorf("secretTags(%q) = %v, want %s", tt.team, got, tt.want)
+		}
+	}
+}
+
+func TestSecretIDs(t *testing.T) {
+	if got := CredentialsSecretID("app", "db-1"); got != "app/db-1/psql" {
+		t.Errorf("CredentialsSecretID = %s", got)
+	}
+	// A database called psql must not collide with the credentials secret.
+	if got := UserSecretID("psql", "db-1", "psql"); got == CredentialsSecretID("psql", "db-1") {
+		t.Errorf("UserSecretID = %s collides with the credentials secret", got)
+	}
+}
+
+func TestRDSSecretShape(t *testing.T) {
+	data, err := json.Marshal(rdsSecret{Username: "app", Password: "pw", Host: "h", Port: 5432, DBName: "app", Engine: "postgres"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"username":"app","password":"pw","host":"h","port":5432,"dbname":"app","engine":"postgres"}`
+	if string(data) != want {
+		t.Errorf("rdsSecret = %s, want %s", data, want)
+	}
+}
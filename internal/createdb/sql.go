@@ -1,6 +1,11 @@
 package createdb
 
-import "fmt"
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
 
 // Step represents a named group of SQL statements and the connection context
 // they require (which user and database to connect as/to).
@@ -11,119 +16,485 @@ type Step struct {
 	Statements []string
 }
 
-// BuildSteps returns the ordered list of SQL steps matching the Ansible playbook.
-func BuildSteps(dbName, schema string, users []UserCredentials) []Step {
-	migrationUser := dbName
-	roV1 := dbName + "_ro_v1"
-	rwV1 := dbName + "_rw_v1"
-	roV2 := dbName + "_ro_v2"
-	rwV2 := dbName + "_rw_v2"
-	iamUser := dbName + "_iam"
+// opKind is what a statement establishes.
+type opKind int
 
+const (
+	opCreateDatabase opKind = iota
+	opCreateRole
+	opMembership
+	opDatabaseGrant
+	opRevokePublicCreate
+	opSchemaGrant
+	opObjectGrant
+	opDefaultPrivileges
+	opCreateExtension
+	opDatabaseSetting
+	opRoleSetting
+	opCreateSchema
+)
+
+// op is one statement together with the state it establishes, so that
+// Plan can check it against the live instance.
+type op struct {
+	kind opKind
+	sql  string
+	// role is the database, created role or grantee.
+	role string
+	// parent is the granted role of a membership.
+	parent string
+	// object is the database or schema of a grant, the created schema,
+	// TABLES, SEQUENCES or FUNCTIONS for object grants and default
+	// privileges, the extension, or the parameter of a setting.
+	object string
+	// schema is the schema of object grants and default privileges.
+	schema string
+	privs  []string
+	// grantor is the role issuing object grants, which only reach the
+	// objects it owns, and the FOR ROLE of default privileges; empty means
+	// the connecting superuser.
+	grantor string
+	// login and connLimit describe a created role; connLimit is nil when
+	// the template leaves it unset.
+	login     bool
+	connLimit *int
+	// value is a setting's value as pg_db_role_setting shows it.
+	value string
+}
+
+// stepOps is a Step before it is rendered to statements.
+type stepOps struct {
+	name      string
+	connectAs string
+	connectDB string
+	ops       []op
+}
+
+func (s stepOps) step() Step {
+	stmts := make([]string, len(s.ops))
+	for i, o := range s.ops {
+		stmts[i] = o.sql
+	}
+	return Step{Name: s.name, ConnectAs: s.connectAs, ConnectDB: s.connectDB, Statements: stmts}
+}
+
+// BuildSteps returns the ordered list of SQL steps for tmpl: create the
+// database and the password users, grant memberships and database
+// privileges, set the database parameters, create the extensions, grant
+// schema privileges, grant each role's privilege set as the owner, set up
+// IAM users as the superuser (granting rds_iam needs it) and finally set
+// the role parameters. users are the password users from
+// generateCredentials.
+func BuildSteps(tmpl *Template, dbName string, users []UserCredentials) []Step {
 	var steps []Step
+	for _, s := range buildOps(tmpl, dbName, users) {
+		steps = append(steps, s.step())
+	}
+	return steps
+}
+
+func buildOps(tmpl *Template, dbName string, users []UserCredentials) []stepOps {
+	owner := tmpl.Owner().Username(dbName)
+	schemas := tmpl.SchemaNames()
+
+	var steps []stepOps
 
 	// Step 1: Create database (superuser -> default DB)
-	steps = append(steps, Step{
-		Name:      fmt.Sprintf("Create database %q", dbName),
-		ConnectAs: "superuser",
-		ConnectDB: "default",
-		Statements: []string{
-			fmt.Sprintf(`CREATE DATABASE "%s"`, dbName),
-		},
+	steps = append(steps, stepOps{
+		name:      fmt.Sprintf("Create database %q", dbName),
+		connectAs: "superuser",
+		connectDB: "default",
+		ops: []op{{
+			kind: opCreateDatabase,
+			sql:  "CREATE DATABASE " + ident(dbName),
+			role: dbName,
+		}},
 	})
 
 	// Step 2: Create users (superuser -> default DB)
-	var createUserStmts []string
+	var createUserOps []op
 	for _, u := range users {
-		createUserStmts = append(createUserStmts,
-			fmt.Sprintf(`CREATE USER "%s" WITH ENCRYPTED PASSWORD '%s' CONNECTION LIMIT %d`,
-				u.Username, u.Password, u.ConnLimit),
-		)
-	}
-	steps = append(steps, Step{
-		Name:       "Create users",
-		ConnectAs:  "superuser",
-		ConnectDB:  "default",
-		Statements: createUserStmts,
+		limit := u.ConnLimit
+		createUserOps = append(createUserOps, op{
+			kind: opCreateRole,
+			sql: fmt.Sprintf(`CREATE USER %s WITH ENCRYPTED PASSWORD %s CONNECTION LIMIT %d`,
+				ident(u.Username), literal(u.verifier()), u.ConnLimit),
+			role:      u.Username,
+			login:     true,
+			connLimit: &limit,
+		})
+	}
+	steps = append(steps, stepOps{
+		name:      "Create users",
+		connectAs: "superuser",
+		connectDB: "default",
+		ops:       createUserOps,
 	})
 
+	var passwordRoles, iamRoles []RoleTemplate
+	for _, r := range tmpl.Roles {
+		if r.Login == LoginIAM {
+			iamRoles = append(iamRoles, r)
+		} else {
+			passwordRoles = append(passwordRoles, r)
+		}
+	}
+
 	// Step 3: Grant role memberships (superuser -> default DB)
-	steps = append(steps, Step{
-		Name:      "Grant role memberships",
-		ConnectAs: "superuser",
-		ConnectDB: "default",
-		Statements: []string{
-			fmt.Sprintf(`GRANT "%s" TO "%s"`, roV1, roV2),
-			fmt.Sprintf(`GRANT "%s" TO "%s"`, rwV1, rwV2),
-		},
-	})
+	var memberOps, databaseOps []op
+	for _, r := range passwordRoles {
+		memberOps = append(memberOps, membershipOps(tmpl, r, dbName)...)
+		databaseOps = append(databaseOps, databaseGrantOps(r, dbName)...)
+	}
+	if len(memberOps) > 0 {
+		steps = append(steps, stepOps{
+			name:      "Grant role memberships",
+			connectAs: "superuser",
+			connectDB: "default",
+			ops:       memberOps,
+		})
+	}
 
-	// Step 4: Grant ALL on database to migration user (superuser -> default DB)
-	steps = append(steps, Step{
-		Name:      fmt.Sprintf("Grant ALL on database to %q", migrationUser),
-		ConnectAs: "superuser",
-		ConnectDB: "default",
-		Statements: []string{
-			fmt.Sprintf(`GRANT ALL ON DATABASE "%s" TO "%s"`, dbName, migrationUser),
-		},
-	})
+	// Step 4: Database privileges (superuser -> default DB)
+	if len(databaseOps) > 0 {
+		steps = append(steps, stepOps{
+			name:      "Grant database privileges",
+			connectAs: "superuser",
+			connectDB: "default",
+			ops:       databaseOps,
+		})
+	}
 
-	// Step 5: Schema permissions (superuser -> new DB)
-	steps = append(steps, Step{
-		Name:      fmt.Sprintf("Configure schema %q permissions", schema),
-		ConnectAs: "superuser",
-		ConnectDB: "newdb",
-		Statements: []string{
-			fmt.Sprintf(`REVOKE CREATE ON SCHEMA %s FROM PUBLIC`, schema),
-			fmt.Sprintf(`GRANT CREATE ON SCHEMA %s TO "%s"`, schema, migrationUser),
-		},
-	})
+	// Database parameters (superuser -> default DB)
+	if ops := settingOps(tmpl.Settings, "", dbName); len(ops) > 0 {
+		steps = append(steps, stepOps{
+			name:      "Set database parameters",
+			connectAs: "superuser",
+			connectDB: "default",
+			ops:       ops,
+		})
+	}
 
-	// Step 6: RO privileges (migration user -> new DB)
-	steps = append(steps, Step{
-		Name:      fmt.Sprintf("Grant read-only privileges to %q", roV1),
-		ConnectAs: "migration",
-		ConnectDB: "newdb",
-		Statements: []string{
-			fmt.Sprintf(`GRANT SELECT ON ALL TABLES IN SCHEMA %s TO "%s"`, schema, roV1),
-			fmt.Sprintf(`GRANT USAGE, SELECT ON ALL SEQUENCES IN SCHEMA %s TO "%s"`, schema, roV1),
-			fmt.Sprintf(`ALTER DEFAULT PRIVILEGES FOR ROLE "%s" IN SCHEMA %s GRANT SELECT ON TABLES TO "%s"`,
-				migrationUser, schema, roV1),
-			fmt.Sprintf(`ALTER DEFAULT PRIVILEGES FOR ROLE "%s" IN SCHEMA %s GRANT USAGE, SELECT ON SEQUENCES TO "%s"`,
-				migrationUser, schema, roV1),
-		},
-	})
+	// Extensions (superuser -> new DB). The objects they create belong to
+	// the superuser, so the privilege sets, granted by the owner, do not
+	// cover them.
+	if len(tmpl.Extensions) > 0 {
+		var ops []op
+		for _, ext := range tmpl.Extensions {
+			ops = append(ops, op{
+				kind:   opCreateExtension,
+				sql:    "CREATE EXTENSION IF NOT EXISTS " + ident(ext),
+				object: ext,
+			})
+		}
+		steps = append(steps, stepOps{
+			name:      "Create extensions",
+			connectAs: "superuser",
+			connectDB: "newdb",
+			ops:       ops,
+		})
+	}
 
-	// Step 7: RW privileges (migration user -> new DB)
-	steps = append(steps, Step{
-		Name:      fmt.Sprintf("Grant read-write privileges to %q", rwV1),
-		ConnectAs: "migration",
-		ConnectDB: "newdb",
-		Statements: []string{
-			fmt.Sprintf(`GRANT SELECT, INSERT, UPDATE, DELETE ON ALL TABLES IN SCHEMA %s TO "%s"`, schema, rwV1),
-			fmt.Sprintf(`GRANT USAGE, SELECT, UPDATE ON ALL SEQUENCES IN SCHEMA %s TO "%s"`, schema, rwV1),
-			fmt.Sprintf(`ALTER DEFAULT PRIVILEGES FOR ROLE "%s" IN SCHEMA %s GRANT SELECT, INSERT, UPDATE, DELETE ON TABLES TO "%s"`,
-				migrationUser, schema, rwV1),
-			fmt.Sprintf(`ALTER DEFAULT PRIVILEGES FOR ROLE "%s" IN SCHEMA %s GRANT USAGE, SELECT, UPDATE ON SEQUENCES TO "%s"`,
-				migrationUser, schema, rwV1),
-		},
-	})
+	// Step 5: Schema permissions (new DB). public belongs to the database
+	// owner, so the superuser moves CREATE from PUBLIC to the owner there.
+	// The owner creates the other schemas itself, which lets it grant on
+	// them: the RDS master user is no real superuser and can neither
+	// create schemas for another role nor grant on schemas it does not own.
+	for _, schema := range schemas {
+		var ops []op
+		connectAs := "superuser"
+		if schema == DefaultSchema {
+			ops = []op{
+				{
+					kind:   opRevokePublicCreate,
+					sql:    fmt.Sprintf(`REVOKE CREATE ON SCHEMA %s FROM PUBLIC`, ident(schema)),
+					role:   "PUBLIC",
+					object: schema,
+					privs:  []string{"CREATE"},
+				},
+				{
+					kind:   opSchemaGrant,
+					sql:    fmt.Sprintf(`GRANT CREATE ON SCHEMA %s TO %s`, ident(schema), ident(owner)),
+					role:   owner,
+					object: schema,
+					privs:  []string{"CREATE"},
+				},
+			}
+		} else {
+			connectAs = "migration"
+			ops = []op{{
+				kind:   opCreateSchema,
+				sql:    fmt.Sprintf(`CREATE SCHEMA IF NOT EXISTS %s`, ident(schema)),
+				role:   owner,
+				object: schema,
+			}}
+		}
+		for _, r := range passwordRoles {
+			ops = append(ops, schemaGrantOps(tmpl, r, dbName, schema)...)
+		}
+		steps = append(steps, stepOps{
+			name:      fmt.Sprintf("Configure schema %q permissions", schema),
+			connectAs: connectAs,
+			connectDB: "newdb",
+			ops:       ops,
+		})
+	}
 
-	// Step 8: IAM user (superuser -> new DB)
-	steps = append(steps, Step{
-		Name:      fmt.Sprintf("Create IAM user %q", iamUser),
-		ConnectAs: "superuser",
-		ConnectDB: "newdb",
-		Statements: []string{
-			fmt.Sprintf(`CREATE USER "%s" WITH LOGIN`, iamUser),
-			fmt.Sprintf(`GRANT rds_iam TO "%s"`, iamUser),
-			fmt.Sprintf(`GRANT CONNECT ON DATABASE "%s" TO "%s"`, dbName, iamUser),
-			fmt.Sprintf(`GRANT USAGE, CREATE ON SCHEMA %s TO "%s"`, schema, iamUser),
-			fmt.Sprintf(`GRANT SELECT, INSERT, UPDATE, DELETE ON ALL TABLES IN SCHEMA %s TO "%s"`, schema, iamUser),
-			fmt.Sprintf(`ALTER DEFAULT PRIVILEGES IN SCHEMA %s GRANT SELECT, INSERT, UPDATE, DELETE ON TABLES TO "%s"`,
-				schema, iamUser),
-		},
-	})
+	// Step 6+: Privilege sets (migration user -> new DB)
+	for _, schema := range schemas {
+		for _, r := range passwordRoles {
+			set := tmpl.PrivilegesIn(r, schema)
+			if set == "" {
+				continue
+			}
+			steps = append(steps, stepOps{
+				name:      fmt.Sprintf("Grant %s privileges in %q to %q", set, schema, r.Username(dbName)),
+				connectAs: "migration",
+				connectDB: "newdb",
+				ops:       privilegeOps(tmpl, r, set, dbName, schema, owner),
+			})
+		}
+	}
+
+	// Last: IAM users (superuser -> new DB). As in the playbook, their
+	// default privileges cover objects created by the superuser.
+	for _, r := range iamRoles {
+		user := r.Username(dbName)
+		create := fmt.Sprintf(`CREATE USER %s WITH LOGIN`, ident(user))
+		if r.ConnLimit != nil {
+			create += fmt.Sprintf(" CONNECTION LIMIT %d", *r.ConnLimit)
+		}
+		ops := []op{
+			{kind: opCreateRole, sql: create, role: user, login: true, connLimit: r.ConnLimit},
+			{kind: opMembership, sql: fmt.Sprintf(`GRANT rds_iam TO %s`, ident(user)), role: user, parent: "rds_iam"},
+		}
+		ops = append(ops, membershipOps(tmpl, r, dbName)...)
+		ops = append(ops, databaseGrantOps(r, dbName)...)
+		var ownerOps []op
+		for _, schema := range schemas {
+			if schema == DefaultSchema {
+				ops = append(ops, schemaGrantOps(tmpl, r, dbName, schema)...)
+			} else {
+				ownerOps = append(ownerOps, schemaGrantOps(tmpl, r, dbName, schema)...)
+			}
+			if set := tmpl.PrivilegesIn(r, schema); set != "" {
+				ops = append(ops, privilegeOps(tmpl, r, set, dbName, schema, "")...)
+			}
+		}
+		steps = append(steps, stepOps{
+			name:      fmt.Sprintf("Create IAM user %q", user),
+			connectAs: "superuser",
+			connectDB: "newdb",
+			ops:       ops,
+		})
+		// Only the owner can grant on the schemas it created.
+		if len(ownerOps) > 0 {
+			steps = append(steps, stepOps{
+				name:      fmt.Sprintf("Grant schema privileges to IAM user %q", user),
+				connectAs: "migration",
+				connectDB: "newdb",
+				ops:       ownerOps,
+			})
+		}
+	}
+
+	// Role parameters (superuser -> default DB), once every role exists.
+	var roleSettingOps []op
+	for _, r := range tmpl.Roles {
+		roleSettingOps = append(roleSettingOps, settingOps(r.Settings, r.Username(dbName), dbName)...)
+	}
+	if len(roleSettingOps) > 0 {
+		steps = append(steps, stepOps{
+			name:      "Set role parameters",
+			connectAs: "superuser",
+			connectDB: "default",
+			ops:       roleSettingOps,
+		})
+	}
 
 	return steps
 }
+
+// settingOps sets parameters of dbName, or of role in dbName when role is
+// set, in name order.
+func settingOps(settings Settings, role, dbName string) []op {
+	var ops []op
+	for _, name := range slices.Sorted(maps.Keys(settings)) {
+		value := settings[name]
+		literals := make([]string, len(value))
+		for i, v := range value {
+			literals[i] = literal(v)
+		}
+		target := "DATABASE " + ident(dbName)
+		kind := opDatabaseSetting
+		if role != "" {
+			target = fmt.Sprintf("ROLE %s IN DATABASE %s", ident(role), ident(dbName))
+			kind = opRoleSetting
+		}
+		ops = append(ops, op{
+			kind:   kind,
+			sql:    fmt.Sprintf("ALTER %s SET %s = %s", target, name, strings.Join(literals, ", ")),
+			role:   role,
+			object: name,
+			value:  settingDisplay(name, value),
+		})
+	}
+	return ops
+}
+
+// listQuoted are the parameters whose list elements PostgreSQL stores as
+// quoted identifiers.
+var listQuoted = []string{"search_path", "temp_tablespaces", "local_preload_libraries", "session_preload_libraries", "shared_preload_libraries"}
+
+// settingDisplay renders value as pg_db_role_setting stores it.
+func settingDisplay(name string, value SettingValue) string {
+	if !slices.Contains(listQuoted, name) {
+		return strings.Join(value, ", ")
+	}
+	quoted := make([]string, len(value))
+	for i, v := range value {
+		quoted[i] = v
+		if !lowerIdentifier.MatchString(v) {
+			quoted[i] = ident(v)
+		}
+	}
+	return strings.Join(quoted, ", ")
+}
+
+func membershipOps(tmpl *Template, r RoleTemplate, dbName string) []op {
+	var ops []op
+	for _, name := range r.MemberOf {
+		parent, _ := tmpl.Role(name)
+		ops = append(ops, op{
+			kind:   opMembership,
+			sql:    fmt.Sprintf(`GRANT %s TO %s`, ident(parent.Username(dbName)), ident(r.Username(dbName))),
+			role:   r.Username(dbName),
+			parent: parent.Username(dbName),
+		})
+	}
+	return ops
+}
+
+func databaseGrantOps(r RoleTemplate, dbName string) []op {
+	if len(r.Database) == 0 {
+		return nil
+	}
+	return []op{{
+		kind:   opDatabaseGrant,
+		sql:    fmt.Sprintf(`GRANT %s ON DATABASE %s TO %s`, privilegeList(r.Database), ident(dbName), ident(r.Username(dbName))),
+		role:   r.Username(dbName),
+		object: dbName,
+		privs:  r.Database,
+	}}
+}
+
+// schemaGrantOps grants r's schema privileges on schema. A role with a
+// privilege set in a schema other than public also gets USAGE on it, which
+// public grants to everyone.
+func schemaGrantOps(tmpl *Template, r RoleTemplate, dbName, schema string) []op {
+	privs := r.Schema
+	usage := slices.ContainsFunc(privs, func(p string) bool {
+		return strings.EqualFold(p, "USAGE") || strings.EqualFold(p, "ALL")
+	})
+	if schema != DefaultSchema && tmpl.PrivilegesIn(r, schema) != "" && !usage {
+		privs = append([]string{"USAGE"}, privs...)
+	}
+	if len(privs) == 0 {
+		return nil
+	}
+	return []op{{
+		kind:   opSchemaGrant,
+		sql:    fmt.Sprintf(`GRANT %s ON SCHEMA %s TO %s`, privilegeList(privs), ident(schema), ident(r.Username(dbName))),
+		role:   r.Username(dbName),
+		object: schema,
+		privs:  privs,
+	}}
+}
+
+// privilegeOps grants privilege set setName to r on the existing objects
+// in schema and, unless disabled, as default privileges for objects
+// grantor creates there (the connecting user when grantor is empty).
+func privilegeOps(tmpl *Template, r RoleTemplate, setName, dbName, schema, grantor string) []op {
+	set := tmpl.PrivilegeSets[setName]
+	user := r.Username(dbName)
+	objects := []struct {
+		kind  string
+		privs []string
+	}{
+		{"TABLES", set.Tables},
+		{"SEQUENCES", set.Sequences},
+		{"FUNCTIONS", set.Functions},
+	}
+	forRole := ""
+	if grantor != "" {
+		forRole = fmt.Sprintf(`FOR ROLE %s `, ident(grantor))
+	}
+
+	var grants, defaults []op
+	for _, o := range objects {
+		if len(o.privs) == 0 {
+			continue
+		}
+		grants = append(grants, op{
+			kind:    opObjectGrant,
+			schema:  schema,
+			sql:     fmt.Sprintf(`GRANT %s ON ALL %s IN SCHEMA %s TO %s`, privilegeList(o.privs), o.kind, ident(schema), ident(user)),
+			role:    user,
+			object:  o.kind,
+			privs:   o.privs,
+			grantor: grantor,
+		})
+		if r.defaultPrivileges() {
+			defaults = append(defaults, op{
+				kind:   opDefaultPrivileges,
+				schema: schema,
+				sql: fmt.Sprintf(`ALTER DEFAULT PRIVILEGES %sIN SCHEMA %s GRANT %s ON %s TO %s`,
+					forRole, ident(schema), privilegeList(o.privs), o.kind, ident(user)),
+				role:    user,
+				object:  o.kind,
+				privs:   o.privs,
+				grantor: grantor,
+			})
+		}
+	}
+	return append(grants, defaults...)
+}
+
+// describe renders o for a plan.
+func (o op) describe() string {
+	switch o.kind {
+	case opCreateDatabase:
+		return fmt.Sprintf("database %q", o.role)
+	case opCreateRole:
+		limit := "unlimited"
+		if o.connLimit != nil && *o.connLimit >= 0 {
+			limit = fmt.Sprint(*o.connLimit)
+		}
+		return fmt.Sprintf("role %q (login, conn_limit=%s)", o.role, limit)
+	case opMembership:
+		return fmt.Sprintf("membership %q in %q", o.role, o.parent)
+	case opDatabaseGrant:
+		return fmt.Sprintf("grant %s on database %q to %q", privilegeList(o.privs), o.object, o.role)
+	case opRevokePublicCreate:
+		return fmt.Sprintf("grant CREATE on schema %q to PUBLIC", o.object)
+	case opSchemaGrant:
+		return fmt.Sprintf("grant %s on schema %q to %q", privilegeList(o.privs), o.object, o.role)
+	case opObjectGrant:
+		return fmt.Sprintf("grant %s on all %s in %q to %q", privilegeList(o.privs), strings.ToLower(o.object), o.schema, o.role)
+	case opDefaultPrivileges:
+		grantor := "the superuser"
+		if o.grantor != "" {
+			grantor = fmt.Sprintf("%q", o.grantor)
+		}
+		return fmt.Sprintf("default privileges %s on %s in %q created by %s to %q", privilegeList(o.privs), strings.ToLower(o.object), o.schema, grantor, o.role)
+	case opCreateSchema:
+		return fmt.Sprintf("schema %q owned by %q", o.object, o.role)
+	case opCreateExtension:
+		return fmt.Sprintf("extension %q", o.object)
+	case opDatabaseSetting:
+		return fmt.Sprintf("parameter %s = %s on the database", o.object, o.value)
+	case opRoleSetting:
+		return fmt.Sprintf("parameter %s = %s for %q", o.object, o.value, o.role)
+	}
+	return o.sql
+}
@@ -1,6 +1,7 @@
 package createdb
 
 import (
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -14,7 +15,7 @@ func TestBuildSteps_StepCount(t *testing.T) {
 		{Username: "testdb_rw_v2", Password: "pw5", Role: "read-write", ConnLimit: 10},
 	}
 
-	steps := BuildSteps("testdb", "public", users)
+	steps := BuildSteps(DefaultTemplate(), "testdb", users)
 	if len(steps) != 8 {
 		t.Errorf("BuildSteps: got %d steps, want 8", len(steps))
 	}
@@ -25,7 +26,7 @@ func TestBuildSteps_CreateDatabaseStep(t *testing.T) {
 		{Username: "myapp", Password: "pw1", Role: "migration", ConnLimit: 10},
 	}
 
-	steps := BuildSteps("myapp", "public", users)
+	steps := BuildSteps(DefaultTemplate(), "myapp", users)
 	if len(steps) == 0 {
 		t.Fatal("BuildSteps: returned no steps")
 	}
@@ -48,7 +49,7 @@ func TestBuildSteps_CreateUsersStep(t *testing.T) {
 		{Username: "app_ro_v1", Password: "pw2", Role: "read-only", ConnLimit: 3},
 	}
 
-	steps := BuildSteps("app", "public", users)
+	steps := BuildSteps(DefaultTemplate(), "app", users)
 	if len(steps) < 2 {
 		t.Fatal("BuildSteps: not enough steps")
 	}
@@ -72,7 +73,7 @@ func TestBuildSteps_IAMUserStep(t *testing.T) {
 		{Username: "svc", Password: "pw1", Role: "migration", ConnLimit: 10},
 	}
 
-	steps := BuildSteps("svc", "public", users)
+	steps := BuildSteps(DefaultTemplate(), "svc", users)
 	lastStep := steps[len(steps)-1]
 
 	if !strings.Contains(lastStep.Name, "svc_iam") {
@@ -98,7 +99,11 @@ func TestBuildSteps_CustomSchema(t *testing.T) {
 		{Username: "app", Password: "pw1", Role: "migration", ConnLimit: 10},
 	}
 
-	steps := BuildSteps("app", "myschema", users)
+	tmpl := DefaultTemplate()
+	if err := tmpl.AddSchemas([]string{"myschema"}); err != nil {
+		t.Fatal(err)
+	}
+	steps := BuildSteps(tmpl, "app", users)
 	found := false
 	for _, step := range steps {
 		for _, stmt := range step.Statements {
@@ -111,4 +116,16 @@ func TestBuildSteps_CustomSchema(t *testing.T) {
 	if !found {
 		t.Error("custom schema 'myschema' not found in any statement")
 	}
+
+	// The owner created the schema, so it grants the IAM user its schema
+	// privileges.
+	for _, step := range steps {
+		if step.Name == `Grant schema privileges to IAM user "app_iam"` {
+			if step.ConnectAs != "migration" || !reflect.DeepEqual(step.Statements, []string{`GRANT USAGE, CREATE ON SCHEMA "myschema" TO "app_iam"`}) {
+				t.Errorf("IAM schema grants = %+v", step)
+			}
+			return
+		}
+	}
+	t.Error("no schema grant step for the IAM user")
 }
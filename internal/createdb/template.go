@@ -0,0 +1,448 @@
+package createdb
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+//go:embed default_template.yaml
+var defaultTemplate []byte
+
+// DefaultTemplateYAML returns the built-in template source.
+func DefaultTemplateYAML() []byte {
+	return defaultTemplate
+}
+
+// Login methods of a template role.
+const (
+	LoginPassword = "password"
+	LoginIAM      = "iam"
+)
+
+// Template declares the roles `db create` provisions for a database and
+// the privileges they get.
+type Template struct {
+	// Extensions are created in the database.
+	Extensions []string `yaml:"extensions,omitempty"`
+	// Settings are the database's parameters (ALTER DATABASE ... SET).
+	Settings Settings `yaml:"settings,omitempty"`
+	// Schemas are the schemas the roles get privileges in; schemas other
+	// than public are created, owned by the owner role. Without any, the
+	// public schema is used.
+	Schemas       []SchemaTemplate        `yaml:"schemas,omitempty"`
+	PrivilegeSets map[string]PrivilegeSet `yaml:"privilege_sets"`
+	Roles         []RoleTemplate          `yaml:"roles"`
+}
+
+// SchemaTemplate declares one schema.
+type SchemaTemplate struct {
+	Name string `yaml:"name"`
+	// Privileges maps role names to the privilege set they get in this
+	// schema instead of their own privileges; "none" grants nothing.
+	Privileges map[string]string `yaml:"privileges,omitempty"`
+}
+
+// NoPrivileges in a schema's privileges withholds a role's privilege set.
+const NoPrivileges = "none"
+
+// DefaultSchema is the schema of templates that declare none.
+const DefaultSchema = "public"
+
+// Settings maps configuration parameters to their values.
+type Settings map[string]SettingValue
+
+// SettingValue is a parameter value: a scalar, or a list for parameters
+// such as search_path that take several.
+type SettingValue []string
+
+// UnmarshalYAML accepts a scalar or a sequence of scalars.
+func (v *SettingValue) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		*v = SettingValue{node.Value}
+		return nil
+	case yaml.SequenceNode:
+		var list []string
+		if err := node.Decode(&list); err != nil {
+			return err
+		}
+		*v = list
+		return nil
+	}
+	return fmt.Errorf("line %d: a setting is a value or a list of values", node.Line)
+}
+
+// MarshalYAML writes single values as scalars.
+func (v SettingValue) MarshalYAML() (any, error) {
+	if len(v) == 1 {
+		return v[0], nil
+	}
+	return []string(v), nil
+}
+
+// PrivilegeSet lists the privileges granted on every table, sequence and
+// function in the schema.
+type PrivilegeSet struct {
+	Tables    []string `yaml:"tables"`
+	Sequences []string `yaml:"sequences"`
+	Functions []string `yaml:"functions"`
+}
+
+// RoleTemplate declares one role.
+type RoleTemplate struct {
+	// Name identifies the role within the template (member_of, schema
+	// privileges, --emit-key).
+	Name string `yaml:"name"`
+	// Suffix is appended to the database name to form the username.
+	Suffix string `yaml:"suffix"`
+	// Label describes the role in summaries; it defaults to Name.
+	Label string `yaml:"label"`
+	// Owner marks the role that owns the schema objects. Exactly one role
+	// is the owner; it owns the schemas other than public, gets CREATE on
+	// public, runs the privilege grants and is the grantor of default
+	// privileges.
+	Owner bool `yaml:"owner"`
+	// Login is "password" (the default) or "iam".
+	Login string `yaml:"login"`
+	// ConnLimit is the connection limit; unset means unlimited.
+	ConnLimit *int `yaml:"conn_limit"`
+	// MemberOf lists template roles whose privileges this role inherits.
+	MemberOf []string `yaml:"member_of"`
+	// Database and Schema are privileges on the database and on every
+	// schema.
+	Database []string `yaml:"database"`
+	Schema   []string `yaml:"schema"`
+	// Privileges names the privilege set for objects in every schema that
+	// does not set another for the role.
+	Privileges string `yaml:"privileges"`
+	// DefaultPrivileges extends Privileges to objects created later; it
+	// defaults to true.
+	DefaultPrivileges *bool `yaml:"default_privileges"`
+	// Settings are the role's parameters in the database
+	// (ALTER ROLE ... IN DATABASE ... SET).
+	Settings Settings `yaml:"settings,omitempty"`
+}
+
+// Privileges accepted per object type.
+var allowedPrivileges = map[string][]string{
+	"database":  {"ALL", "CONNECT", "CREATE", "TEMPORARY", "TEMP"},
+	"schema":    {"ALL", "USAGE", "CREATE"},
+	"tables":    {"ALL", "SELECT", "INSERT", "UPDATE", "DELETE", "TRUNCATE", "REFERENCES", "TRIGGER"},
+	"sequences": {"ALL", "USAGE", "SELECT", "UPDATE"},
+	"functions": {"ALL", "EXECUTE"},
+}
+
+// DefaultTemplate returns the built-in template: a migration owner,
+// read-only and read-write users in two versions each, and an IAM user.
+func DefaultTemplate() *Template {
+	t, err := ParseTemplate(defaultTemplate)
+	if err != nil {
+		panic("createdb: invalid built-in template: " + err.Error())
+	}
+	return t
+}
+
+// LoadTemplate reads and validates a template file.
+func LoadTemplate(path string) (*Template, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read template: %w", err)
+	}
+	t, err := ParseTemplate(data)
+	if err != nil {
+		return nil, fmt.Errorf("template %s: %w", path, err)
+	}
+	return t, nil
+}
+
+// ParseTemplate decodes and validates a YAML template. Unknown keys are
+// rejected so that typos do not silently drop privileges.
+func ParseTemplate(data []byte) (*Template, error) {
+	var t Template
+	dec := yaml.NewDecoder(strings.NewReader(string(data)))
+	dec.KnownFields(true)
+	if err := dec.Decode(&t); err != nil {
+		return nil, err
+	}
+	if err := t.validate(); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (t *Template) validate() error {
+	if len(t.Roles) == 0 {
+		return errors.New("no roles declared")
+	}
+	for i, ext := range t.Extensions {
+		if !extensionPattern.MatchString(ext) {
+			return fmt.Errorf("extension %q: not a valid extension name", ext)
+		}
+		if slices.Contains(t.Extensions[:i], ext) {
+			return fmt.Errorf("extension %q declared twice", ext)
+		}
+	}
+	if err := t.Settings.normalize(); err != nil {
+		return fmt.Errorf("settings: %w", err)
+	}
+	for name, set := range t.PrivilegeSets {
+		if name == NoPrivileges {
+			return fmt.Errorf("privilege set %q: the name is reserved", name)
+		}
+		for kind, privs := range map[string][]string{"tables": set.Tables, "sequences": set.Sequences, "functions": set.Functions} {
+			if err := checkPrivileges(kind, privs); err != nil {
+				return fmt.Errorf("privilege set %q: %w", name, err)
+			}
+		}
+	}
+
+	names := map[string]RoleTemplate{}
+	suffixes := map[string]string{}
+	owners := 0
+	for i := range t.Roles {
+		r := &t.Roles[i]
+		if r.Name == "" {
+			return fmt.Errorf("role %d: name is required", i+1)
+		}
+		if _, dup := names[r.Name]; dup {
+			return fmt.Errorf("role %q declared twice", r.Name)
+		}
+		if !suffixPattern.MatchString(r.Suffix) {
+			return fmt.Errorf("role %q: suffix %q may only contain letters, digits, _ and $", r.Name, r.Suffix)
+		}
+		if other, dup := suffixes[r.Suffix]; dup {
+			return fmt.Errorf("roles %q and %q have the same suffix %q", other, r.Name, r.Suffix)
+		}
+		switch r.Login {
+		case "":
+			r.Login = LoginPassword
+		case LoginPassword, LoginIAM:
+		default:
+			return fmt.Errorf("role %q: login must be %q or %q", r.Name, LoginPassword, LoginIAM)
+		}
+		if r.Owner {
+			owners++
+			if r.Login != LoginPassword {
+				return fmt.Errorf("role %q: the owner needs password login", r.Name)
+			}
+		}
+		if r.ConnLimit != nil && *r.ConnLimit < -1 {
+			return fmt.Errorf("role %q: conn_limit must be -1 (unlimited) or more", r.Name)
+		}
+		if r.Privileges != "" {
+			if _, ok := t.PrivilegeSets[r.Privileges]; !ok {
+				return fmt.Errorf("role %q: unknown privilege set %q", r.Name, r.Privileges)
+			}
+		}
+		if err := checkPrivileges("database", r.Database); err != nil {
+			return fmt.Errorf("role %q: %w", r.Name, err)
+		}
+		if err := checkPrivileges("schema", r.Schema); err != nil {
+			return fmt.Errorf("role %q: %w", r.Name, err)
+		}
+		if err := r.Settings.normalize(); err != nil {
+			return fmt.Errorf("role %q settings: %w", r.Name, err)
+		}
+		for _, parent := range r.MemberOf {
+			p, ok := names[parent]
+			if !ok {
+				return fmt.Errorf("role %q: member_of %q must name a role declared before it", r.Name, parent)
+			}
+			// IAM roles are created last, after the password roles'
+			// memberships are granted.
+			if p.Login == LoginIAM && r.Login != LoginIAM {
+				return fmt.Errorf("role %q: cannot be a member of IAM role %q", r.Name, parent)
+			}
+		}
+		names[r.Name] = *r
+		suffixes[r.Suffix] = r.Name
+	}
+	if owners != 1 {
+		return fmt.Errorf("exactly one role must be the owner, found %d", owners)
+	}
+	for i, schema := range t.Schemas {
+		if err := validateIdentifier("schema", schema.Name); err != nil {
+			return err
+		}
+		for _, other := range t.Schemas[:i] {
+			if other.Name == schema.Name {
+				return fmt.Errorf("schema %q declared twice", schema.Name)
+			}
+		}
+		for role, set := range schema.Privileges {
+			if _, ok := names[role]; !ok {
+				return fmt.Errorf("schema %q: unknown role %q", schema.Name, role)
+			}
+			if _, ok := t.PrivilegeSets[set]; !ok && set != NoPrivileges {
+				return fmt.Errorf("schema %q: role %q: unknown privilege set %q", schema.Name, role, set)
+			}
+		}
+	}
+	return nil
+}
+
+// normalize lower-cases parameter names, as PostgreSQL does, and checks
+// them and their values.
+func (s Settings) normalize() error {
+	for name, value := range s {
+		lower := strings.ToLower(name)
+		if !settingPattern.MatchString(lower) {
+			return fmt.Errorf("%q is not a valid parameter name", name)
+		}
+		if len(value) == 0 {
+			return fmt.Errorf("%s: no value", name)
+		}
+		if lower != name {
+			if _, dup := s[lower]; dup {
+				return fmt.Errorf("%s set twice", lower)
+			}
+			delete(s, name)
+			s[lower] = value
+		}
+	}
+	return nil
+}
+
+// SchemaNames returns the names of the schemas, or public when the
+// template declares none.
+func (t *Template) SchemaNames() []string {
+	if len(t.Schemas) == 0 {
+		return []string{DefaultSchema}
+	}
+	names := make([]string, len(t.Schemas))
+	for i, s := range t.Schemas {
+		names[i] = s.Name
+	}
+	return names
+}
+
+// AddSchemas adds the schemas tmpl does not declare yet, where every role
+// gets its own privileges.
+func (t *Template) AddSchemas(names []string) error {
+	for _, name := range names {
+		if err := validateIdentifier("schema", name); err != nil {
+			return err
+		}
+		if !slices.ContainsFunc(t.Schemas, func(s SchemaTemplate) bool { return s.Name == name }) {
+			t.Schemas = append(t.Schemas, SchemaTemplate{Name: name})
+		}
+	}
+	return nil
+}
+
+// PrivilegesIn returns the privilege set r gets in schema, or "" for none.
+func (t *Template) PrivilegesIn(r RoleTemplate, schema string) string {
+	for _, s := range t.Schemas {
+		if s.Name != schema {
+			continue
+		}
+		if set, ok := s.Privileges[r.Name]; ok {
+			if set == NoPrivileges {
+				return ""
+			}
+			return set
+		}
+	}
+	return r.Privileges
+}
+
+// AddExtensions adds the extensions tmpl does not declare yet.
+func (t *Template) AddExtensions(names []string) error {
+	for _, name := range names {
+		if !extensionPattern.MatchString(name) {
+			return fmt.Errorf("extension %q: not a valid extension name", name)
+		}
+		if !slices.Contains(t.Extensions, name) {
+			t.Extensions = append(t.Extensions, name)
+		}
+	}
+	return nil
+}
+
+func checkPrivileges(kind string, privs []string) error {
+	for _, p := range privs {
+		if !slices.Contains(allowedPrivileges[kind], strings.ToUpper(p)) {
+			return fmt.Errorf("unknown %s privilege %q (want %s)", kind, p, strings.Join(allowedPrivileges[kind], ", "))
+		}
+	}
+	return nil
+}
+
+// Owner returns the owning role.
+func (t *Template) Owner() RoleTemplate {
+	for _, r := range t.Roles {
+		if r.Owner {
+			return r
+		}
+	}
+	return RoleTemplate{}
+}
+
+// Role returns the role called name.
+func (t *Template) Role(name string) (RoleTemplate, bool) {
+	for _, r := range t.Roles {
+		if r.Name == name {
+			return r, true
+		}
+	}
+	return RoleTemplate{}, false
+}
+
+// SetConnLimits applies the --*-conn-limit flags to the built-in template:
+// the owner gets migration, and password roles labelled read-only or
+// read-write get ro and rw.
+func (t *Template) SetConnLimits(migration, ro, rw int) {
+	for i := range t.Roles {
+		r := &t.Roles[i]
+		if r.Login != LoginPassword {
+			continue
+		}
+		switch {
+		case r.Owner:
+			r.ConnLimit = &migration
+		case r.Label == "read-only":
+			r.ConnLimit = &ro
+		case r.Label == "read-write":
+			r.ConnLimit = &rw
+		}
+	}
+}
+
+// Username is the role's username for dbName.
+func (r RoleTemplate) Username(dbName string) string {
+	return dbName + r.Suffix
+}
+
+// DisplayLabel is Label, or Name when no label is set.
+func (r RoleTemplate) DisplayLabel() string {
+	if r.Label != "" {
+		return r.Label
+	}
+	return r.Name
+}
+
+// Limit returns the connection limit, -1 when unset.
+func (r RoleTemplate) Limit() int {
+	if r.ConnLimit == nil {
+		return -1
+	}
+	return *r.ConnLimit
+}
+
+func (r RoleTemplate) defaultPrivileges() bool {
+	return r.DefaultPrivileges == nil || *r.DefaultPrivileges
+}
+
+func privilegeList(privs []string) string {
+	upper := make([]string, len(privs))
+	for i, p := range privs {
+		upper[i] = strings.ToUpper(p)
+	}
+	return strings.Join(upper, ", ")
+}
@@ -0,0 +1,338 @@
+package createdb
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.yaml.in/yaml/v3"
+)
+
+func TestDefaultTemplate(t *testing.T) {
+	tmpl := DefaultTemplate()
+	tmpl.SetConnLimits(20, 5, 8)
+
+	users, err := generateCredentials("pricing", tmpl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, u := range users {
+		got = append(got, fmt.Sprintf("%s/%s/%d", u.Username, u.Role, u.ConnLimit))
+	}
+	want := []string{
+		"pricing/migration/20",
+		"pricing_ro_v1/read-only/5",
+		"pricing_ro_v2/read-only/5",
+		"pricing_rw_v1/read-write/8",
+		"pricing_rw_v2/read-write/8",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("users = %v, want %v", got, want)
+	}
+	if owner := tmpl.Owner(); owner.Name != "migration" {
+		t.Errorf("owner = %q", owner.Name)
+	}
+}
+
+func TestBuildSteps_CustomTemplate(t *testing.T) {
+	tmpl, err := ParseTemplate([]byte(`
+privilege_sets:
+  reporting:
+    tables: [select]
+    functions: [EXECUTE]
+roles:
+  - name: owner
+    suffix: _owner
+    owner: true
+    database: [CONNECT, CREATE]
+  - name: analytics
+    suffix: _analytics
+    conn_limit: 3
+    database: [CONNECT]
+    schema: [USAGE]
+    privileges: reporting
+    default_privileges: false
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	users, err := generateCredentials("app", tmpl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 2 || users[1].Username != "app_analytics" || users[1].ConnLimit != 3 || users[0].ConnLimit != -1 {
+		t.Fatalf("users = %+v", users)
+	}
+
+	steps := BuildSteps(tmpl, "app", users)
+	var names []string
+	for _, s := range steps {
+		names = append(names, s.Name)
+	}
+	wantNames := []string{
+		`Create database "app"`,
+		"Create users",
+		"Grant database privileges",
+		`Configure schema "public" permissions`,
+		`Grant reporting privileges in "public" to "app_analytics"`,
+	}
+	if !reflect.DeepEqual(names, wantNames) {
+		t.Fatalf("steps = %q, want %q", names, wantNames)
+	}
+
+	if got := steps[2].Statements; !reflect.DeepEqual(got, []string{
+		`GRANT CONNECT, CREATE ON DATABASE "app" TO "app_owner"`,
+		`GRANT CONNECT ON DATABASE "app" TO "app_analytics"`,
+	}) {
+		t.Errorf("database grants = %q", got)
+	}
+	if got := steps[3].Statements; !reflect.DeepEqual(got, []string{
+		`REVOKE CREATE ON SCHEMA "public" FROM PUBLIC`,
+		`GRANT CREATE ON SCHEMA "public" TO "app_owner"`,
+		`GRANT USAGE ON SCHEMA "public" TO "app_analytics"`,
+	}) {
+		t.Errorf("schema grants = %q", got)
+	}
+	if got := steps[4].Statements; !reflect.DeepEqual(got, []string{
+		`GRANT SELECT ON ALL TABLES IN SCHEMA "public" TO "app_analytics"`,
+		`GRANT EXECUTE ON ALL FUNCTIONS IN SCHEMA "public" TO "app_analytics"`,
+	}) {
+		t.Errorf("privilege grants = %q", got)
+	}
+}
+
+func TestParseTemplate_Errors(t *testing.T) {
+	tests := []struct {
+		name, yaml, want string
+	}{
+		{"no roles", `roles: []`, "no roles"},
+		{"no owner", "roles:\n  - name: a\n", "exactly one role must be the owner"},
+		{"two owners", "roles:\n  - {name: a, owner: true}\n  - {name: b, suffix: _b, owner: true}\n", "exactly one"},
+		{"duplicate name", "roles:\n  - {name: a, owner: true}\n  - {name: a, suffix: _b}\n", "declared twice"},
+		{"duplicate suffix", "roles:\n  - {name: a, owner: true}\n  - {name: b}\n", "same suffix"},
+		{"iam owner", "roles:\n  - {name: a, owner: true, login: iam}\n", "needs password login"},
+		{"bad login", "roles:\n  - {name: a, owner: true, login: kerberos}\n", "login must be"},
+		{"unknown set", "roles:\n  - {name: a, owner: true, privileges: nope}\n", "unknown privilege set"},
+		{"bad privilege", "roles:\n  - {name: a, owner: true, database: [SELECT]}\n", "unknown database privilege"},
+		{"bad set privilege", "privilege_sets:\n  x: {tables: [DROP]}\nroles:\n  - {name: a, owner: true}\n", "unknown tables privilege"},
+		{"forward member_of", "roles:\n  - {name: a, owner: true, member_of: [b]}\n  - {name: b, suffix: _b}\n", "declared before"},
+		{"member of iam", "roles:\n  - {name: a, owner: true}\n  - {name: i, suffix: _i, login: iam}\n  - {name: b, suffix: _b, member_of: [i]}\n", "cannot be a member of IAM role"},
+		{"unknown key", "roles:\n  - {name: a, owner: true, conn_limt: 5}\n", "conn_limt"},
+		{"bad extension", "extensions: [\"x; DROP\"]\nroles:\n  - {name: a, owner: true}\n", "not a valid extension name"},
+		{"duplicate extension", "extensions: [pgcrypto, pgcrypto]\nroles:\n  - {name: a, owner: true}\n", "declared twice"},
+		{"bad setting", "settings: {\"work_mem = 1; x\": 1}\nroles:\n  - {name: a, owner: true}\n", "not a valid parameter name"},
+		{"setting twice", "roles:\n  - {name: a, owner: true, settings: {Work_Mem: 1MB, work_mem: 2MB}}\n", "set twice"},
+		{"empty setting", "settings: {timezone: []}\nroles:\n  - {name: a, owner: true}\n", "no value"},
+		{"bad schema", "schemas: [{name: pg_x}]\nroles:\n  - {name: a, owner: true}\n", "reserved pg_ prefix"},
+		{"schema twice", "schemas: [{name: app}, {name: app}]\nroles:\n  - {name: a, owner: true}\n", "declared twice"},
+		{"schema role", "schemas: [{name: app, privileges: {b: none}}]\nroles:\n  - {name: a, owner: true}\n", "unknown role"},
+		{"schema set", "schemas: [{name: app, privileges: {a: nope}}]\nroles:\n  - {name: a, owner: true}\n", "unknown privilege set"},
+		{"reserved set", "privilege_sets:\n  none: {tables: [SELECT]}\nroles:\n  - {name: a, owner: true}\n", "reserved"},
+	}
+	for _, tt := range tests {
+		_, err := ParseTemplate([]byte(tt.yaml))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestLoadTemplate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "roles.yaml")
+	if err := os.WriteFile(path, DefaultTemplateYAML(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tmpl, err := LoadTemplate(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tmpl.Roles) != 6 {
+		t.Errorf("roles = %d, want 6", len(tmpl.Roles))
+	}
+	if _, err := LoadTemplate(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+const settingsTemplate = `
+extensions: [pg_stat_statements, uuid-ossp]
+settings:
+  TimeZone: UTC
+privilege_sets:
+  read: {tables: [SELECT]}
+roles:
+  - name: owner
+    owner: true
+  - name: ro
+    suffix: _ro
+    privileges: read
+    settings:
+      statement_timeout: 30s
+      search_path: [app, "$user", public]
+`
+
+func TestParseTemplate_ExtensionsAndSettings(t *testing.T) {
+	tmpl, err := ParseTemplate([]byte(settingsTemplate))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(tmpl.Settings, Settings{"timezone": {"UTC"}}) {
+		t.Errorf("settings = %v, want lower-cased timezone", tmpl.Settings)
+	}
+	ro, _ := tmpl.Role("ro")
+	if !reflect.DeepEqual(ro.Settings["search_path"], SettingValue{"app", "$user", "public"}) {
+		t.Errorf("search_path = %v", ro.Settings["search_path"])
+	}
+
+	// Journals store the template as YAML; it must parse back the same.
+	data, err := yaml.Marshal(tmpl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := ParseTemplate(data)
+	if err != nil {
+		t.Fatalf("re-parse:\n%s\n%v", data, err)
+	}
+	if !reflect.DeepEqual(again.Extensions, tmpl.Extensions) || !reflect.DeepEqual(again.Roles[1].Settings, ro.Settings) {
+		t.Errorf("round trip changed the template:\n%s", data)
+	}
+
+	if err := tmpl.AddExtensions([]string{"pgcrypto", "uuid-ossp"}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(tmpl.Extensions, []string{"pg_stat_statements", "uuid-ossp", "pgcrypto"}) {
+		t.Errorf("extensions = %v", tmpl.Extensions)
+	}
+	if err := tmpl.AddExtensions([]string{"bad name"}); err == nil {
+		t.Error("AddExtensions accepted an invalid name")
+	}
+}
+
+func TestBuildSteps_ExtensionsAndSettings(t *testing.T) {
+	tmpl, err := ParseTemplate([]byte(settingsTemplate))
+	if err != nil {
+		t.Fatal(err)
+	}
+	users, err := generateCredentials("app", tmpl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byName := map[string]Step{}
+	var names []string
+	for _, s := range BuildSteps(tmpl, "app", users) {
+		byName[s.Name] = s
+		names = append(names, s.Name)
+	}
+
+	wantOrder := []string{`Create database "app"`, "Create users", "Set database parameters", "Create extensions",
+		`Configure schema "public" permissions`, `Grant read privileges in "public" to "app_ro"`, "Set role parameters"}
+	if !reflect.DeepEqual(names, wantOrder) {
+		t.Errorf("steps = %q, want %q", names, wantOrder)
+	}
+	if got := byName["Set database parameters"].Statements; !reflect.DeepEqual(got, []string{`ALTER DATABASE "app" SET timezone = 'UTC'`}) {
+		t.Errorf("database parameters = %q", got)
+	}
+	if got := byName["Create extensions"].Statements; !reflect.DeepEqual(got, []string{
+		`CREATE EXTENSION IF NOT EXISTS "pg_stat_statements"`,
+		`CREATE EXTENSION IF NOT EXISTS "uuid-ossp"`,
+	}) {
+		t.Errorf("extensions = %q", got)
+	}
+	if got := byName["Set role parameters"].Statements; !reflect.DeepEqual(got, []string{
+		`ALTER ROLE "app_ro" IN DATABASE "app" SET search_path = 'app', '$user', 'public'`,
+		`ALTER ROLE "app_ro" IN DATABASE "app" SET statement_timeout = '30s'`,
+	}) {
+		t.Errorf("role parameters = %q", got)
+	}
+}
+
+const schemasTemplate = `
+privilege_sets:
+  read: {tables: [SELECT]}
+  write: {tables: [SELECT, INSERT]}
+schemas:
+  - name: app
+  - name: audit
+    privileges: {rw: read, ro: none}
+roles:
+  - name: owner
+    owner: true
+  - name: ro
+    suffix: _ro
+    privileges: read
+  - name: rw
+    suffix: _rw
+    privileges: write
+  - name: ro2
+    suffix: _ro2
+    member_of: [ro]
+`
+
+func TestBuildSteps_Schemas(t *testing.T) {
+	tmpl, err := ParseTemplate([]byte(schemasTemplate))
+	if err != nil {
+		t.Fatal(err)
+	}
+	users, err := generateCredentials("app", tmpl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byName := map[string]Step{}
+	var names []string
+	for _, s := range BuildSteps(tmpl, "app", users) {
+		byName[s.Name] = s
+		names = append(names, s.Name)
+	}
+	wantNames := []string{`Create database "app"`, "Create users", "Grant role memberships",
+		`Configure schema "app" permissions`, `Configure schema "audit" permissions`,
+		`Grant read privileges in "app" to "app_ro"`, `Grant write privileges in "app" to "app_rw"`,
+		`Grant read privileges in "audit" to "app_rw"`}
+	if !reflect.DeepEqual(names, wantNames) {
+		t.Fatalf("steps = %q, want %q", names, wantNames)
+	}
+	if got := byName[`Configure schema "audit" permissions`].Statements; !reflect.DeepEqual(got, []string{
+		`CREATE SCHEMA IF NOT EXISTS "audit"`,
+		`GRANT USAGE ON SCHEMA "audit" TO "app_rw"`,
+	}) {
+		t.Errorf("audit schema = %q", got)
+	}
+	// The RDS master user cannot create schemas for the owner or grant on
+	// them; the owner does both.
+	for _, name := range []string{`Configure schema "app" permissions`, `Configure schema "audit" permissions`} {
+		if got := byName[name].ConnectAs; got != "migration" {
+			t.Errorf("%s connects as %q, want migration", name, got)
+		}
+	}
+	if got := byName[`Grant read privileges in "audit" to "app_rw"`].Statements; !reflect.DeepEqual(got, []string{
+		`GRANT SELECT ON ALL TABLES IN SCHEMA "audit" TO "app_rw"`,
+		`ALTER DEFAULT PRIVILEGES FOR ROLE "app" IN SCHEMA "audit" GRANT SELECT ON TABLES TO "app_rw"`,
+	}) {
+		t.Errorf("audit grants = %q", got)
+	}
+}
+
+func TestPrintAccessMatrix(t *testing.T) {
+	tmpl, err := ParseTemplate([]byte(schemasTemplate))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf strings.Builder
+	printAccessMatrix(&buf, tmpl, "app")
+	want := `  USER     app     audit
+  app      owner   owner
+  app_ro   read    -
+  app_rw   write   read
+  app_ro2  via ro  -
+`
+	if buf.String() != want {
+		t.Errorf("matrix:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	if got := DefaultTemplate().SchemaNames(); !reflect.DeepEqual(got, []string{DefaultSchema}) {
+		t.Errorf("default schemas = %v", got)
+	}
+}
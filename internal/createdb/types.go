@@ -2,32 +2,82 @@ package createdb
 
 // Options configures a db create run.
 type Options struct {
-	Profile            string
-	Region             string
-	DBName             string
-	Host               string
-	Port               int
-	Schema             string
-	DefaultDB          string
+	Profile   string
+	Region    string
+	DBName    string
+	Host      string
+	Port      int
+	DefaultDB string
+	// Schemas are provisioned in addition to the template's; with neither,
+	// the roles get their privileges in public.
+	Schemas []string
+	// Template is a role template file; empty uses the built-in template
+	// with the conn limits below.
+	Template           string
 	MigrationConnLimit int
-	RWConnLimit        int
-	ROConnLimit        int
-	DryRun             bool
-	Force              bool
-	Args               []string
+	// Extensions are created in addition to the template's.
+	Extensions  []string
+	RWConnLimit int
+	ROConnLimit int
+	DryRun      bool
+	// Plan shows the changes against the live instance without applying
+	// them.
+	Plan  bool
+	Force bool
+	// Resume continues the failed run with this ID.
+	Resume string
+	// RollbackOnFailure drops what the run created if a step fails.
+	RollbackOnFailure bool
+	// Yes answers every question with yes.
+	Yes bool
+	// StoreSecrets stores the new credentials in Secrets Manager (true) or
+	// not (false) without asking; nil asks.
+	StoreSecrets *bool
+	// Output is OutputText or OutputJSON; the JSON report goes to stdout
+	// and progress to stderr.
+	Output string
+	// ShowPasswords includes the new passwords in the JSON report.
+	ShowPasswords bool
+	// Emit lists credential sinks as KIND=PATH, and EmitKeys the key prefix
+	// of template roles as ROLE=PREFIX.
+	Emit     []string
+	EmitKeys []string
+	// Secrets configures the stored secrets.
+	Secrets SecretOptions
+	// SecretStore is the ClusterSecretStore of emitted ExternalSecrets.
+	SecretStore string
+	// Instance is the ID of the instance a resumed run provisions;
+	// otherwise the instance is selected by Host or picked interactively.
+	Instance string
 }
 
 // UserCredentials holds a generated username, password, and role metadata.
 type UserCredentials struct {
-	Username  string `json:"username"`
-	Password  string `json:"password"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	// RoleName is the template role the user was generated for.
+	RoleName string `json:"-"`
+	// Verifier is the SCRAM-SHA-256 verifier of Password, the only form
+	// of it sent to the server.
+	Verifier  string `json:"-"`
 	Role      string `json:"role"`
 	ConnLimit int    `json:"-"`
 }
 
 // StepResult tracks the outcome of a single orchestration step.
 type StepResult struct {
-	Name   string
-	Status string // "done", "FAILED", "SKIPPED"
-	Error  error
+	Name   string `json:"name"`
+	Status string `json:"status"` // "done", "FAILED", "SKIPPED"
+	Error  error  `json:"-"`
+	// Message is Error as text, for the run journal.
+	Message string `json:"error,omitempty"`
+}
+
+// verifier returns the user's SCRAM-SHA-256 verifier, computing one when
+// none was generated with the password.
+func (u UserCredentials) verifier() string {
+	if u.Verifier != "" {
+		return u.Verifier
+	}
+	return ScramVerifier(u.Password)
 }
@@ -0,0 +1,190 @@
+package guard
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Check returns a description of every destructive statement in sql: DROP,
+// TRUNCATE, and DELETE or UPDATE without a WHERE clause. Strings, quoted
+// identifiers, dollar-quoted bodies and comments are skipped, so keywords
+// inside them do not count. Only the top level of each statement is
+// inspected; a DELETE inside a CTE is not caught.
+func Check(sql string) []string {
+	var reasons []string
+	for _, stmt := range statements(sql) {
+		if reason := destructive(stmt); reason != "" {
+			reasons = append(reasons, reason)
+		}
+	}
+	return reasons
+}
+
+// destructive inspects the top-level words of one statement.
+func destructive(words []string) string {
+	verb, i := "", 0
+	for ; i < len(words); i++ {
+		switch words[i] {
+		case "drop", "truncate", "delete", "update", "select", "insert", "create", "alter", "merge", "grant", "revoke":
+			verb = words[i]
+		}
+		if verb != "" {
+			break
+		}
+	}
+
+	switch verb {
+	case "drop":
+		object := "object"
+		if i+1 < len(words) {
+			object = strings.ToUpper(words[i+1])
+			if object == "MATERIALIZED" || object == "FOREIGN" {
+				if i+2 < len(words) {
+					object += " " + strings.ToUpper(words[i+2])
+				}
+			}
+		}
+		return "DROP " + object
+	case "truncate":
+		return "TRUNCATE"
+	case "delete", "update":
+		for _, w := range words[i+1:] {
+			if w == "where" {
+				return ""
+			}
+		}
+		return strings.ToUpper(verb) + " without WHERE"
+	}
+	return ""
+}
+
+// statements splits sql into statements of lower-cased top-level words
+// (words inside parentheses are left out).
+func statements(sql string) [][]string {
+	var stmts [][]string
+	var cur []string
+	depth := 0
+	rs := []rune(sql)
+	for i := 0; i < len(rs); i++ {
+		c := rs[i]
+		next := rune(0)
+		if i+1 < len(rs) {
+			next = rs[i+1]
+		}
+		switch {
+		case c == '-' && next == '-':
+			for i < len(rs) && rs[i] != '\n' {
+				i++
+			}
+		case c == '/' && next == '*':
+			nest := 0
+			for ; i+1 < len(rs); i++ {
+				if rs[i] == '/' && rs[i+1] == '*' {
+					nest++
+					i++
+				} else if rs[i] == '*' && rs[i+1] == '/' {
+					nest--
+					i++
+					if nest == 0 {
+						break
+					}
+				}
+			}
+		case c == '\'' || c == '"':
+			escape := c == '\'' && i > 0 && (rs[i-1] == 'e' || rs[i-1] == 'E')
+			for i++; i < len(rs); i++ {
+				if escape && rs[i] == '\\' {
+					i++
+					continue
+				}
+				if rs[i] == c {
+					if i+1 < len(rs) && rs[i+1] == c {
+						i++
+						continue
+					}
+					break
+				}
+			}
+		case c == '$':
+			if t, ok := DollarTag(rs, i); ok {
+				tag := []rune(t)
+				j := i + len(tag)
+				for j < len(rs) && !slices.Equal(rs[j:min(j+len(tag), len(rs))], tag) {
+					j++
+				}
+				i = j + len(tag) - 1
+			}
+		case c == '(':
+			depth++
+		case c == ')':
+			if depth > 0 {
+				depth--
+			}
+		case c == ';' && depth == 0:
+			if len(cur) > 0 {
+				stmts = append(stmts, cur)
+			}
+			cur = nil
+		case unicode.IsLetter(c) || c == '_':
+			j := i
+			for j < len(rs) && (unicode.IsLetter(rs[j]) || unicode.IsDigit(rs[j]) || rs[j] == '_' || rs[j] == '$') {
+				j++
+			}
+			if depth == 0 {
+				cur = append(cur, strings.ToLower(string(rs[i:j])))
+			}
+			i = j - 1
+		}
+	}
+	if len(cur) > 0 {
+		stmts = append(stmts, cur)
+	}
+	return stmts
+}
+
+// DollarTag returns the $tag$ opening at rs[i], if any. Positional
+// parameters ($1) and identifiers containing $ are not dollar quotes.
+func DollarTag(rs []rune, i int) (string, bool) {
+	if i > 0 {
+		if p := rs[i-1]; unicode.IsLetter(p) || unicode.IsDigit(p) || p == '_' || p == '$' {
+			return "", false
+		}
+	}
+	for j := i + 1; j < len(rs); j++ {
+		c := rs[j]
+		if c == '$' {
+			return string(rs[i : j+1]), true
+		}
+		if !(unicode.IsLetter(c) || c == '_' || (j > i+1 && unicode.IsDigit(c))) {
+			return "", false
+		}
+	}
+	return "", false
+}
+
+// Warning describes the destructive statements found by Check.
+func Warning(reasons []string) string {
+	return "⚠️  Destructive statement on a protected database: " + strings.Join(reasons, ", ")
+}
+
+// Prompt asks for the typed confirmation.
+func Prompt(expected string) string {
+	return fmt.Sprintf("Type the database name (%s) to continue: ", expected)
+}
+
+// Confirm lists reasons and asks the user to type expected (the database
+// name) to go ahead. Anything else, including EOF, declines.
+func Confirm(in io.Reader, out io.Writer, reasons []string, expected string) bool {
+	fmt.Fprintln(out, Warning(reasons))
+	fmt.Fprint(out, Prompt(expected))
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && line == "" {
+		fmt.Fprintln(out)
+		return false
+	}
+	return strings.TrimSpace(line) == expected
+}
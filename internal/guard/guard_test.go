@@ -0,0 +1,86 @@
+package guard
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want []string
+	}{
+		{"select * from users", nil},
+		{"delete from users where id = 1", nil},
+		{"DELETE FROM users", []string{"DELETE without WHERE"}},
+		{"update users set active = false", []string{"UPDATE without WHERE"}},
+		{"UPDATE users SET active = false WHERE id = 7", nil},
+		{"update users set a = (select 1 where true)", []string{"UPDATE without WHERE"}},
+		{"drop table users", []string{"DROP TABLE"}},
+		{"DROP MATERIALIZED VIEW mv", []string{"DROP MATERIALIZED VIEW"}},
+		{"truncate orders", []string{"TRUNCATE"}},
+		{"with old as (select id from t) delete from t", []string{"DELETE without WHERE"}},
+		{"explain analyze delete from t", []string{"DELETE without WHERE"}},
+		{"select 'drop table users; delete from t'", nil},
+		{`select 1 as "drop"`, nil},
+		{"select 1 -- drop table users", nil},
+		{"select /* truncate t; */ 1", nil},
+		{"do $$ begin delete from t; end $$", nil},
+		{"create function f() returns void as $body$ drop table t $body$ language sql", nil},
+		{"select 1; drop table a; delete from b where x; truncate c", []string{"DROP TABLE", "TRUNCATE"}},
+		{"insert into t select * from u", nil},
+		{"select updated_at from t", nil},
+	}
+	for _, tt := range tests {
+		if got := Check(tt.sql); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Check(%q) = %q, want %q", tt.sql, got, tt.want)
+		}
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{"pricing\n", true},
+		{"  pricing  \n", true},
+		{"pricing", true},
+		{"yes\n", false},
+		{"\n", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		got := Confirm(strings.NewReader(tt.input), &out, []string{"TRUNCATE"}, "pricing")
+		if got != tt.want {
+			t.Errorf("Confirm(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+		if !strings.Contains(out.String(), "TRUNCATE") || !strings.Contains(out.String(), "(pricing)") {
+			t.Errorf("Confirm prompt: got %q", out.String())
+		}
+	}
+}
+
+func TestDollarTag(t *testing.T) {
+	tests := []struct {
+		sql    string
+		at     int
+		want   string
+		wantOK bool
+	}{
+		{"$$ body $$", 0, "$$", true},
+		{"$fn$ body $fn$", 0, "$fn$", true},
+		{"WHERE id = $1", 11, "", false},
+		{"a$b$ c", 1, "", false},
+		{"$1x$", 0, "", false},
+	}
+	for _, tt := range tests {
+		got, ok := DollarTag([]rune(tt.sql), tt.at)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("DollarTag(%q, %d) = %q, %v; want %q, %v", tt.sql, tt.at, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
@@ -0,0 +1,293 @@
+package query
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/PraveenPrabhuT/rds/internal/connect"
+	"github.com/PraveenPrabhuT/rds/internal/core"
+	"github.com/PraveenPrabhuT/rds/internal/render"
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// DefaultParallel is the default number of targets queried concurrently.
+const DefaultParallel = 8
+
+// textOID is the type of the instance and database columns.
+const textOID = 25
+
+// target is one instance/database pair of a fan-out run.
+type target struct {
+	inst  core.InstanceInfo
+	db    string
+	creds core.RDSCreds
+}
+
+func (t target) String() string { return t.inst.ID + "/" + t.db }
+
+// fanOut runs sql on every selected instance (and, with EachDB, every
+// database on it) through a bounded worker pool. Rows from all targets are
+// merged into one output with instance and database columns prepended.
+// Failing targets are reported at the end without stopping the others.
+func fanOut(ctx context.Context, opts Options, sql string, w render.Writer, safety connect.Safety) error {
+	if opts.As != "" && (opts.EachDB || opts.DB == "postgres" || opts.DB == "") {
+		return errors.New("--as requires a single --db and cannot be combined with --each-db")
+	}
+	if safety.Guard {
+		expected := opts.DB
+		if opts.EachDB {
+			expected = "all databases"
+		}
+		if err := confirmDestructive(sql, expected); err != nil {
+			return err
+		}
+	}
+
+	cfg, homeRegion, instances, err := selectInstances(ctx, opts)
+	if err != nil {
+		return err
+	}
+	if len(instances) == 0 {
+		return errors.New("no instances match")
+	}
+
+	parallel := opts.Parallel
+	if parallel <= 0 {
+		parallel = DefaultParallel
+	}
+	var failures failureList
+
+	// Phase 1: credentials per instance and, with --each-db, its databases.
+	perInstance := make([][]target, len(instances))
+	forEach(len(instances), parallel, func(i int) {
+		targets, err := expand(ctx, cfg, homeRegion, instances[i], opts)
+		if err != nil {
+			failures.add(instances[i].ID, err)
+			return
+		}
+		perInstance[i] = targets
+	})
+	instanceFailures := len(failures.items)
+	var targets []target
+	for _, ts := range perInstance {
+		targets = append(targets, ts...)
+	}
+	fmt.Fprintf(os.Stderr, "🚀 Running on %d target(s) across %d instance(s)\n", len(targets), len(instances))
+
+	// Phase 2: the query itself.
+	merged := &mergedWriter{w: w}
+	forEach(len(targets), parallel, func(i int) {
+		t := targets[i]
+		var status bytes.Buffer
+		err := runTarget(ctx, t, sql, safety.ReadOnly, merged, &status)
+		for _, line := range strings.Split(strings.TrimSpace(status.String()), "\n") {
+			if line != "" {
+				fmt.Fprintf(os.Stderr, "[%s] %s\n", t, line)
+			}
+		}
+		if err != nil {
+			failures.add(t.String(), err)
+		}
+	})
+	if err := merged.end(); err != nil {
+		return err
+	}
+	return failures.err(len(targets) + instanceFailures)
+}
+
+// selectInstances loads the instance list and applies --filter; without
+// --all or a filter it falls back to the single instance chosen the usual
+// way (name, --host, --last or picker).
+func selectInstances(ctx context.Context, opts Options) (aws.Config, string, []core.InstanceInfo, error) {
+	if !opts.All && len(opts.Filters) == 0 {
+		t, err := connect.Resolve(ctx, opts.Options)
+		if err != nil {
+			return aws.Config{}, "", nil, err
+		}
+		return t.Config, t.HomeRegion, []core.InstanceInfo{t.Instance}, nil
+	}
+	if len(opts.Args) > 0 || opts.Host != "" || opts.LastConnected {
+		return aws.Config{}, "", nil, errors.New("--all/--filter cannot be combined with an instance name, --host or --last")
+	}
+
+	if err := core.CheckVPNWithPritunl(opts.Profile); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  VPN check: %v (continuing anyway)\n", err)
+	}
+	cfg, homeRegion, err := core.LoadAWSConfig(ctx, opts.Profile, opts.Region)
+	if err != nil {
+		return aws.Config{}, "", nil, err
+	}
+	instances, err := core.GetInstancesWithCache(ctx, cfg, opts.Profile)
+	if err != nil {
+		return aws.Config{}, "", nil, fmt.Errorf("fetch instances: %w", err)
+	}
+	instances, err = core.FilterInstances(instances, opts.Filters)
+	return cfg, homeRegion, instances, err
+}
+
+// expand fetches the credentials for inst and returns its targets: the
+// --db database, or every connectable database with EachDB.
+func expand(ctx context.Context, cfg aws.Config, homeRegion string, inst core.InstanceInfo, opts Options) ([]target, error) {
+	db := opts.DB
+	if db == "" {
+		db = "postgres"
+	}
+
+	var creds core.RDSCreds
+	var err error
+	if opts.As != "" {
+		creds, err = core.GetDatabaseUserCredentials(ctx, cfg, inst, homeRegion, db, opts.As)
+	} else {
+		creds, err = core.GetRDSCredentials(ctx, cfg, inst, homeRegion)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("secrets: %w", err)
+	}
+	if opts.Port != 0 {
+		inst.Port = int32(opts.Port)
+	}
+	if !opts.EachDB {
+		return []target{{inst: inst, db: db, creds: creds}}, nil
+	}
+
+	conn, err := core.NewPgxConn(ctx, inst.Host, inst.Port, creds.Username, creds.Password, db)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close(context.Background())
+	rows, err := conn.Query(ctx, `SELECT datname FROM pg_catalog.pg_database
+WHERE datallowconn AND NOT datistemplate AND datname <> 'rdsadmin'
+ORDER BY 1`)
+	if err != nil {
+		return nil, fmt.Errorf("list databases: %w", err)
+	}
+	defer rows.Close()
+
+	var targets []target
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		targets = append(targets, target{inst: inst, db: name, creds: creds})
+	}
+	return targets, rows.Err()
+}
+
+func runTarget(ctx context.Context, t target, sql string, readOnly bool, merged *mergedWriter, status *bytes.Buffer) error {
+	conn, err := core.NewPgxSession(ctx, t.inst.Host, t.inst.Port, t.creds.Username, t.creds.Password, t.db,
+		core.SessionOptions{ReadOnly: readOnly})
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+	return Stream(ctx, conn.PgConn(), sql, merged.forTarget(t.inst.ID, t.db), status)
+}
+
+// forEach calls fn for 0..n-1 with at most parallel calls in flight.
+func forEach(n, parallel int, fn func(i int)) {
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
+
+// mergedWriter serializes the rows of concurrent targets into one Writer.
+// The first result set to arrive fixes the columns; results from other
+// targets must have the same column names.
+type mergedWriter struct {
+	mu   sync.Mutex
+	w    render.Writer
+	cols []render.Column
+}
+
+func (m *mergedWriter) begin(cols []render.Column) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.cols == nil {
+		m.cols = cols
+		prefixed := append([]render.Column{{Name: "instance", TypeOID: textOID}, {Name: "database", TypeOID: textOID}}, cols...)
+		return m.w.Begin(prefixed)
+	}
+	if columnNames(cols) != columnNames(m.cols) {
+		return fmt.Errorf("result columns (%s) differ from other targets (%s)", columnNames(cols), columnNames(m.cols))
+	}
+	return nil
+}
+
+func (m *mergedWriter) row(prefix, row []*string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.w.Row(append(append(prefix[:0:0], prefix...), row...))
+}
+
+// end finishes the merged output, if any target produced a result set.
+func (m *mergedWriter) end() error {
+	if m.cols == nil {
+		return nil
+	}
+	return m.w.End()
+}
+
+// forTarget returns a Writer that feeds m with rows tagged by instance and
+// database.
+func (m *mergedWriter) forTarget(instance, db string) render.Writer {
+	return &targetWriter{m: m, prefix: []*string{&instance, &db}}
+}
+
+type targetWriter struct {
+	m      *mergedWriter
+	prefix []*string
+}
+
+func (t *targetWriter) Begin(cols []render.Column) error { return t.m.begin(cols) }
+func (t *targetWriter) Row(row []*string) error          { return t.m.row(t.prefix, row) }
+func (t *targetWriter) End() error                       { return nil }
+
+func columnNames(cols []render.Column) string {
+	names := make([]string, len(cols))
+	for i, c := range cols {
+		names[i] = c.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// failureList collects per-target errors from concurrent workers.
+type failureList struct {
+	mu    sync.Mutex
+	items []string
+}
+
+func (f *failureList) add(name string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.items = append(f.items, fmt.Sprintf("%s: %v", name, err))
+}
+
+// err prints the collected failures to stderr and summarizes them as an
+// error, or returns nil when every target succeeded.
+func (f *failureList) err(total int) error {
+	if len(f.items) == 0 {
+		return nil
+	}
+	fmt.Fprintln(os.Stderr, "❌ Failed targets:")
+	for _, item := range f.items {
+		fmt.Fprintf(os.Stderr, "   %s\n", item)
+	}
+	return fmt.Errorf("%d of %d target(s) failed", len(f.items), total)
+}
@@ -0,0 +1,96 @@
+package query
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/PraveenPrabhuT/rds/internal/render"
+)
+
+func TestForEach_BoundsConcurrency(t *testing.T) {
+	var inFlight, peak int32
+	var mu sync.Mutex
+	seen := map[int]bool{}
+
+	forEach(20, 3, func(i int) {
+		n := atomic.AddInt32(&inFlight, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		mu.Lock()
+		seen[i] = true
+		mu.Unlock()
+		atomic.AddInt32(&inFlight, -1)
+	})
+
+	if len(seen) != 20 {
+		t.Errorf("forEach visited %d items, want 20", len(seen))
+	}
+	if peak > 3 {
+		t.Errorf("forEach ran %d calls at once, want at most 3", peak)
+	}
+}
+
+func TestMergedWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := render.NewWriter("csv", &buf, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &mergedWriter{w: w}
+	cols := []render.Column{{Name: "setting", TypeOID: 25}}
+	val := func(s string) []*string { return []*string{&s} }
+
+	a := m.forTarget("db-a", "postgres")
+	b := m.forTarget("db-b", "app")
+	if err := a.Begin(cols); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Begin(cols); err != nil {
+		t.Fatal(err)
+	}
+	a.Row(val("100"))
+	b.Row(val("200"))
+	a.End()
+	b.End()
+
+	other := m.forTarget("db-c", "postgres")
+	if err := other.Begin([]render.Column{{Name: "x"}}); err == nil || !strings.Contains(err.Error(), "differ") {
+		t.Errorf("mismatched columns: got %v", err)
+	}
+
+	if err := m.end(); err != nil {
+		t.Fatal(err)
+	}
+	want := "instance,database,setting\ndb-a,postgres,100\ndb-b,app,200\n"
+	if buf.String() != want {
+		t.Errorf("merged output:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestMergedWriter_NoResults(t *testing.T) {
+	var buf bytes.Buffer
+	w, _ := render.NewWriter("json", &buf, true)
+	m := &mergedWriter{w: w}
+	if err := m.end(); err != nil || buf.Len() != 0 {
+		t.Errorf("end without results: err %v, output %q", err, buf.String())
+	}
+}
+
+func TestFailureList(t *testing.T) {
+	var f failureList
+	if err := f.err(3); err != nil {
+		t.Errorf("no failures: got %v", err)
+	}
+	f.add("db-a/postgres", errors.New("boom"))
+	if err := f.err(3); err == nil || err.Error() != "1 of 3 target(s) failed" {
+		t.Errorf("failures: got %v", err)
+	}
+}
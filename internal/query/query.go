@@ -0,0 +1,172 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/PraveenPrabhuT/rds/internal/connect"
+	"github.com/PraveenPrabhuT/rds/internal/core"
+	"github.com/PraveenPrabhuT/rds/internal/guard"
+	"github.com/PraveenPrabhuT/rds/internal/render"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Options configures an `rds query` run.
+type Options struct {
+	connect.Options
+	// Command is SQL given with -c.
+	Command string
+	// File is a SQL script given with -f; "-" reads stdin.
+	File     string
+	Format   string
+	NoHeader bool
+	// All, Filters and EachDB select a fan-out run over many targets.
+	All      bool
+	Filters  []string
+	EachDB   bool
+	Parallel int
+}
+
+// Run resolves the target like connect does, executes the SQL over pgx and
+// writes every result set to stdout in the requested format. Command tags
+// of statements without results go to stderr. A failing statement is
+// returned as an error so the process exits non-zero. With All, Filters or
+// EachDB it fans out over many targets instead (see fanOut).
+func Run(ctx context.Context, opts Options) error {
+	w, err := render.NewWriter(opts.Format, os.Stdout, !opts.NoHeader)
+	if err != nil {
+		return err
+	}
+	sql, err := readSQL(opts.Command, opts.File, os.Stdin)
+	if err != nil {
+		return err
+	}
+
+	safety := connect.ResolveSafety(opts.ReadOnly, opts.Profile)
+	if opts.All || len(opts.Filters) > 0 || opts.EachDB {
+		return fanOut(ctx, opts, sql, w, safety)
+	}
+
+	target, err := connect.Resolve(ctx, opts.Options)
+	if err != nil {
+		return err
+	}
+	if safety.Guard {
+		if err := confirmDestructive(sql, target.DB); err != nil {
+			return err
+		}
+	}
+	conn, err := core.NewPgxSession(ctx, target.Host, target.Port, target.Creds.Username, target.Creds.Password, target.DB,
+		core.SessionOptions{ReadOnly: safety.ReadOnly})
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	return Stream(ctx, conn.PgConn(), sql, w, os.Stderr)
+}
+
+// confirmDestructive asks for typed confirmation of destructive statements
+// in sql on the controlling terminal, since stdin may carry the SQL itself.
+func confirmDestructive(sql, expected string) error {
+	reasons := guard.Check(sql)
+	if len(reasons) == 0 {
+		return nil
+	}
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("destructive statement on a protected profile (%s) needs confirmation on a terminal", strings.Join(reasons, ", "))
+	}
+	defer tty.Close()
+	if !guard.Confirm(tty, tty, reasons, expected) {
+		return errors.New("cancelled")
+	}
+	return nil
+}
+
+// readSQL returns the SQL from -c or -f; exactly one must be given.
+func readSQL(command, file string, stdin io.Reader) (string, error) {
+	switch {
+	case command != "" && file != "":
+		return "", errors.New("use either -c or -f, not both")
+	case command != "":
+		return command, nil
+	case file == "":
+		return "", errors.New("no SQL given (use -c \"SQL\" or -f file.sql)")
+	}
+
+	var data []byte
+	var err error
+	if file == "-" {
+		data, err = io.ReadAll(stdin)
+	} else {
+		data, err = os.ReadFile(file)
+	}
+	if err != nil {
+		return "", fmt.Errorf("read SQL: %w", err)
+	}
+	if strings.TrimSpace(string(data)) == "" {
+		return "", fmt.Errorf("no SQL in %s", file)
+	}
+	return string(data), nil
+}
+
+// Stream runs sql with the simple query protocol, so several statements
+// may be given and results arrive in text format, and writes each row to w
+// as it is received. Several statements run as one implicit transaction
+// unless the SQL contains explicit transaction control.
+func Stream(ctx context.Context, pg *pgconn.PgConn, sql string, w render.Writer, status io.Writer) error {
+	mrr := pg.Exec(ctx, sql)
+	for mrr.NextResult() {
+		if err := streamResult(mrr.ResultReader(), w, status); err != nil {
+			mrr.Close()
+			return err
+		}
+	}
+	return mrr.Close()
+}
+
+func streamResult(rr *pgconn.ResultReader, w render.Writer, status io.Writer) error {
+	fields := rr.FieldDescriptions()
+	if len(fields) == 0 {
+		tag, err := rr.Close()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(status, tag.String())
+		return nil
+	}
+
+	cols := make([]render.Column, len(fields))
+	for i, fd := range fields {
+		cols[i] = render.Column{Name: fd.Name, TypeOID: fd.DataTypeOID}
+	}
+	if err := w.Begin(cols); err != nil {
+		rr.Close()
+		return err
+	}
+	row := make([]*string, len(cols))
+	for rr.NextRow() {
+		for i, v := range rr.Values() {
+			row[i] = nil
+			if v != nil {
+				text := string(v)
+				row[i] = &text
+			}
+		}
+		if err := w.Row(row); err != nil {
+			rr.Close()
+			return err
+		}
+	}
+	if _, err := rr.Close(); err != nil {
+		// Flush what was written so far before reporting the error.
+		w.End()
+		return err
+	}
+	return w.End()
+}
@@ -0,0 +1,54 @@
+package query
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadSQL(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "report.sql")
+	if err := os.WriteFile(script, []byte("select 1;\nselect 2;\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	empty := filepath.Join(dir, "empty.sql")
+	if err := os.WriteFile(empty, []byte("\n  \n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		command string
+		file    string
+		stdin   string
+		want    string
+		wantErr string
+	}{
+		{name: "command", command: "select now()", want: "select now()"},
+		{name: "file", file: script, want: "select 1;\nselect 2;\n"},
+		{name: "stdin", file: "-", stdin: "select 3", want: "select 3"},
+		{name: "both", command: "select 1", file: script, wantErr: "either -c or -f"},
+		{name: "neither", wantErr: "no SQL given"},
+		{name: "missing file", file: filepath.Join(dir, "nope.sql"), wantErr: "read SQL"},
+		{name: "empty file", file: empty, wantErr: "no SQL in"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readSQL(tt.command, tt.file, strings.NewReader(tt.stdin))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("readSQL: got err %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readSQL: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("readSQL: got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,283 @@
+package render
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Formats lists the output formats supported by NewWriter.
+var Formats = []string{"table", "csv", "tsv", "json", "jsonl", "markdown"}
+
+// PostgreSQL type OIDs that map to native JSON values.
+const (
+	oidBool  = 16
+	oidJSON  = 114
+	oidJSONB = 3802
+)
+
+// Writer renders one result set. Every format except table writes each
+// row as it arrives, so large results are never held in memory.
+type Writer interface {
+	// Begin starts a result set with the given columns.
+	Begin(cols []Column) error
+	// Row writes one row; a nil cell is NULL. The caller may reuse row
+	// once Row returns.
+	Row(row []*string) error
+	// End finishes the result set and flushes the output.
+	End() error
+}
+
+// NewWriter returns a Writer for format. header controls the column names
+// line (and, for table, the row count footer); it has no effect on JSON
+// formats, whose objects are keyed by column name.
+func NewWriter(format string, w io.Writer, header bool) (Writer, error) {
+	bw := bufio.NewWriter(w)
+	switch format {
+	case "table":
+		return &tableWriter{w: bw, header: header}, nil
+	case "csv":
+		return &csvWriter{w: bw, csv: csv.NewWriter(bw), header: header}, nil
+	case "tsv":
+		return &tsvWriter{w: bw, header: header}, nil
+	case "json":
+		return &jsonWriter{w: bw, array: true}, nil
+	case "jsonl":
+		return &jsonWriter{w: bw}, nil
+	case "markdown":
+		return &markdownWriter{w: bw, header: header}, nil
+	}
+	return nil, fmt.Errorf("unknown output format %q (want %s)", format, strings.Join(Formats, ", "))
+}
+
+// tableWriter buffers the result set, since column widths depend on every
+// row, and prints it like the native REPL does.
+type tableWriter struct {
+	w      *bufio.Writer
+	header bool
+	res    Result
+}
+
+func (t *tableWriter) Begin(cols []Column) error {
+	t.res = Result{Columns: cols}
+	return nil
+}
+
+func (t *tableWriter) Row(row []*string) error {
+	t.res.Rows = append(t.res.Rows, append([]*string(nil), row...))
+	return nil
+}
+
+func (t *tableWriter) End() error {
+	Table(t.w, t.res, TableOptions{Footer: t.header, TuplesOnly: !t.header})
+	return t.w.Flush()
+}
+
+type csvWriter struct {
+	w      *bufio.Writer
+	csv    *csv.Writer
+	header bool
+	record []string
+}
+
+func (c *csvWriter) Begin(cols []Column) error {
+	c.record = make([]string, len(cols))
+	if !c.header {
+		return nil
+	}
+	for i, col := range cols {
+		c.record[i] = col.Name
+	}
+	return c.csv.Write(c.record)
+}
+
+// Row writes NULL as an empty field, like psql --csv.
+func (c *csvWriter) Row(row []*string) error {
+	for i, v := range row {
+		c.record[i] = ""
+		if v != nil {
+			c.record[i] = *v
+		}
+	}
+	return c.csv.Write(c.record)
+}
+
+func (c *csvWriter) End() error {
+	c.csv.Flush()
+	if err := c.csv.Error(); err != nil {
+		return err
+	}
+	return c.w.Flush()
+}
+
+// tsvWriter writes PostgreSQL COPY text format: tab separated, NULL as \N
+// and backslash escapes for tabs, newlines and backslashes.
+type tsvWriter struct {
+	w      *bufio.Writer
+	header bool
+}
+
+var tsvEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+func (t *tsvWriter) Begin(cols []Column) error {
+	if !t.header {
+		return nil
+	}
+	names := make([]string, len(cols))
+	for i, col := range cols {
+		names[i] = tsvEscaper.Replace(col.Name)
+	}
+	_, err := fmt.Fprintln(t.w, strings.Join(names, "\t"))
+	return err
+}
+
+func (t *tsvWriter) Row(row []*string) error {
+	fields := make([]string, len(row))
+	for i, v := range row {
+		fields[i] = `\N`
+		if v != nil {
+			fields[i] = tsvEscaper.Replace(*v)
+		}
+	}
+	_, err := fmt.Fprintln(t.w, strings.Join(fields, "\t"))
+	return err
+}
+
+func (t *tsvWriter) End() error { return t.w.Flush() }
+
+// jsonWriter writes one object per row, either as a JSON array or as JSON
+// Lines. Numbers, booleans and json/jsonb values keep their JSON type;
+// everything else is a string.
+type jsonWriter struct {
+	w     *bufio.Writer
+	array bool
+	cols  []Column
+	keys  []string
+	rows  int
+}
+
+func (j *jsonWriter) Begin(cols []Column) error {
+	j.cols, j.rows = cols, 0
+	j.keys = make([]string, len(cols))
+	for i, col := range cols {
+		key, err := json.Marshal(col.Name)
+		if err != nil {
+			return err
+		}
+		j.keys[i] = string(key)
+	}
+	if j.array {
+		_, err := j.w.WriteString("[")
+		return err
+	}
+	return nil
+}
+
+func (j *jsonWriter) Row(row []*string) error {
+	if j.array {
+		if j.rows > 0 {
+			j.w.WriteString(",")
+		}
+		j.w.WriteString("\n  ")
+	}
+	j.rows++
+
+	j.w.WriteString("{")
+	for i, v := range row {
+		if i > 0 {
+			j.w.WriteString(",")
+		}
+		j.w.WriteString(j.keys[i])
+		j.w.WriteString(":")
+		value, err := jsonValue(v, j.cols[i].TypeOID)
+		if err != nil {
+			return err
+		}
+		j.w.WriteString(value)
+	}
+	j.w.WriteString("}")
+	if !j.array {
+		j.w.WriteString("\n")
+	}
+	return nil
+}
+
+func (j *jsonWriter) End() error {
+	if j.array {
+		if j.rows > 0 {
+			j.w.WriteString("\n")
+		}
+		j.w.WriteString("]\n")
+	}
+	return j.w.Flush()
+}
+
+// jsonValue encodes a text-format cell as a JSON value.
+func jsonValue(v *string, oid uint32) (string, error) {
+	if v == nil {
+		return "null", nil
+	}
+	switch {
+	case oid == oidBool:
+		if *v == "t" {
+			return "true", nil
+		}
+		return "false", nil
+	case oid == oidJSON || oid == oidJSONB:
+		return *v, nil
+	case IsNumeric(oid) && json.Valid([]byte(*v)):
+		// NaN, Infinity and money values are not valid JSON numbers and
+		// fall through to strings.
+		return *v, nil
+	}
+	b, err := json.Marshal(*v)
+	return string(b), err
+}
+
+// markdownWriter writes a GitHub-flavored Markdown table with numeric
+// columns right-aligned.
+type markdownWriter struct {
+	w      *bufio.Writer
+	header bool
+}
+
+var markdownEscaper = strings.NewReplacer(`|`, `\|`, "\r\n", "<br>", "\n", "<br>")
+
+func (m *markdownWriter) Begin(cols []Column) error {
+	names := make([]string, len(cols))
+	rule := make([]string, len(cols))
+	for i, col := range cols {
+		if m.header {
+			names[i] = markdownEscaper.Replace(col.Name)
+		}
+		rule[i] = "---"
+		if IsNumeric(col.TypeOID) {
+			rule[i] = "--:"
+		}
+	}
+	// A Markdown table always needs a header row; without a header it is
+	// left blank.
+	fmt.Fprintln(m.w, markdownRow(names))
+	_, err := fmt.Fprintln(m.w, markdownRow(rule))
+	return err
+}
+
+func (m *markdownWriter) Row(row []*string) error {
+	cells := make([]string, len(row))
+	for i, v := range row {
+		if v != nil {
+			cells[i] = markdownEscaper.Replace(*v)
+		}
+	}
+	_, err := fmt.Fprintln(m.w, markdownRow(cells))
+	return err
+}
+
+func (m *markdownWriter) End() error { return m.w.Flush() }
+
+func markdownRow(cells []string) string {
+	return "| " + strings.Join(cells, " | ") + " |"
+}
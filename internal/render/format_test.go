@@ -0,0 +1,114 @@
+package render
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func formatResult() Result {
+	return Result{
+		Columns: []Column{
+			{Name: "id", TypeOID: oidInt4},
+			{Name: "name", TypeOID: 25},
+			{Name: "active", TypeOID: oidBool},
+			{Name: "meta", TypeOID: oidJSONB},
+			{Name: "score", TypeOID: oidFloat8},
+		},
+		Rows: [][]*string{
+			{str("1"), str("a,b"), str("t"), str(`{"k": 1}`), str("1.5")},
+			{str("2"), str("tab\there|pipe"), str("f"), nil, str("NaN")},
+		},
+	}
+}
+
+func writeAll(t *testing.T, format string, header bool, res Result) string {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := NewWriter(format, &buf, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Begin(res.Columns); err != nil {
+		t.Fatal(err)
+	}
+	row := make([]*string, len(res.Columns))
+	for _, r := range res.Rows {
+		// Reuse the row slice like the streaming caller does.
+		copy(row, r)
+		if err := w.Row(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.End(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestNewWriter_Formats(t *testing.T) {
+	tests := []struct {
+		format string
+		header bool
+		want   []string
+	}{
+		{"csv", true, []string{
+			"id,name,active,meta,score",
+			`1,"a,b",t,"{""k"": 1}",1.5`,
+			"2,tab\there|pipe,f,,NaN",
+		}},
+		{"csv", false, []string{
+			`1,"a,b",t,"{""k"": 1}",1.5`,
+			"2,tab\there|pipe,f,,NaN",
+		}},
+		{"tsv", true, []string{
+			"id\tname\tactive\tmeta\tscore",
+			"1\ta,b\tt\t{\"k\": 1}\t1.5",
+			"2\ttab\\there|pipe\tf\t\\N\tNaN",
+		}},
+		{"jsonl", true, []string{
+			`{"id":1,"name":"a,b","active":true,"meta":{"k": 1},"score":1.5}`,
+			`{"id":2,"name":"tab\there|pipe","active":false,"meta":null,"score":"NaN"}`,
+		}},
+		{"json", true, []string{
+			"[",
+			`  {"id":1,"name":"a,b","active":true,"meta":{"k": 1},"score":1.5},`,
+			`  {"id":2,"name":"tab\there|pipe","active":false,"meta":null,"score":"NaN"}`,
+			"]",
+		}},
+		{"markdown", true, []string{
+			"| id | name | active | meta | score |",
+			"| --: | --- | --- | --- | --: |",
+			`| 1 | a,b | t | {"k": 1} | 1.5 |`,
+			"| 2 | tab\there\\|pipe | f |  | NaN |",
+		}},
+	}
+	for _, tt := range tests {
+		got := writeAll(t, tt.format, tt.header, formatResult())
+		want := strings.Join(tt.want, "\n") + "\n"
+		if got != want {
+			t.Errorf("%s (header=%v):\n%s\nwant:\n%s", tt.format, tt.header, got, want)
+		}
+	}
+}
+
+func TestNewWriter_TableKeepsRows(t *testing.T) {
+	got := writeAll(t, "table", false, testResult())
+	want := "  1 | alice\n 42 |\n"
+	if got != want {
+		t.Errorf("table without header:\n%q\nwant:\n%q", got, want)
+	}
+}
+
+func TestNewWriter_EmptyJSONArray(t *testing.T) {
+	got := writeAll(t, "json", true, Result{Columns: []Column{{Name: "x"}}})
+	if got != "[]\n" {
+		t.Errorf("empty json: got %q", got)
+	}
+}
+
+func TestNewWriter_UnknownFormat(t *testing.T) {
+	if _, err := NewWriter("xml", &bytes.Buffer{}, true); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,228 @@
+package render
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// Column describes one result column.
+type Column struct {
+	Name    string
+	TypeOID uint32
+}
+
+// Result is a buffered query result in PostgreSQL text format, as psql
+// would receive it. A nil cell is NULL.
+type Result struct {
+	// Title, when set, is printed centered above the table.
+	Title   string
+	Columns []Column
+	Rows    [][]*string
+}
+
+// TableOptions controls aligned and expanded rendering.
+type TableOptions struct {
+	// Null is shown for NULL cells.
+	Null string
+	// MaxBinaryWidth truncates bytea values longer than this many
+	// characters (0 disables truncation).
+	MaxBinaryWidth int
+	// Footer prints the "(N rows)" line.
+	Footer bool
+	// TuplesOnly prints just the rows, like psql -t: no title, header or
+	// footer.
+	TuplesOnly bool
+}
+
+// DefaultTableOptions are the settings used by the native REPL.
+var DefaultTableOptions = TableOptions{Null: "<null>", MaxBinaryWidth: 64, Footer: true}
+
+// PostgreSQL type OIDs that need special treatment when rendering.
+const (
+	oidBytea   = 17
+	oidInt8    = 20
+	oidInt2    = 21
+	oidInt4    = 23
+	oidOID     = 26
+	oidFloat4  = 700
+	oidFloat8  = 701
+	oidMoney   = 790
+	oidNumeric = 1700
+)
+
+// IsNumeric reports whether values of the type are right-aligned (and
+// emitted unquoted in JSON).
+func IsNumeric(oid uint32) bool {
+	switch oid {
+	case oidInt2, oidInt4, oidInt8, oidOID, oidFloat4, oidFloat8, oidNumeric, oidMoney:
+		return true
+	}
+	return false
+}
+
+// cellText returns the display text of a cell.
+func cellText(v *string, col Column, opts TableOptions) string {
+	if v == nil {
+		return opts.Null
+	}
+	if col.TypeOID == oidBytea && opts.MaxBinaryWidth > 0 && len(*v) > opts.MaxBinaryWidth {
+		// Text-format bytea is \x followed by two hex digits per byte.
+		return fmt.Sprintf("%s… (%d bytes)", (*v)[:opts.MaxBinaryWidth], (len(*v)-2)/2)
+	}
+	return *v
+}
+
+// Table writes res as a psql-style aligned table. Multi-line values are
+// continued on the following lines with a "+" marker, like psql does.
+func Table(w io.Writer, res Result, opts TableOptions) {
+	n := len(res.Columns)
+	widths := make([]int, n)
+	for i, c := range res.Columns {
+		widths[i] = runewidth.StringWidth(c.Name)
+	}
+
+	cells := make([][][]string, len(res.Rows))
+	for r, row := range res.Rows {
+		cells[r] = make([][]string, n)
+		for i := 0; i < n && i < len(row); i++ {
+			lines := strings.Split(cellText(row[i], res.Columns[i], opts), "\n")
+			cells[r][i] = lines
+			for _, l := range lines {
+				if lw := runewidth.StringWidth(l); lw > widths[i] {
+					widths[i] = lw
+				}
+			}
+		}
+	}
+
+	header := make([]string, n)
+	rule := make([]string, n)
+	for i, c := range res.Columns {
+		header[i] = center(c.Name, widths[i])
+		rule[i] = strings.Repeat("-", widths[i]+2)
+	}
+	if !opts.TuplesOnly {
+		if res.Title != "" {
+			fmt.Fprintln(w, strings.TrimRight(center(res.Title, len(strings.Join(rule, "+"))), " "))
+		}
+		fmt.Fprintln(w, strings.TrimRight(" "+strings.Join(header, " | "), " "))
+		fmt.Fprintln(w, strings.Join(rule, "+"))
+	}
+
+	for _, row := range cells {
+		height := 1
+		for _, lines := range row {
+			if len(lines) > height {
+				height = len(lines)
+			}
+		}
+		for l := 0; l < height; l++ {
+			var b strings.Builder
+			for i := 0; i < n; i++ {
+				text, more := "", false
+				if l < len(row[i]) {
+					text = row[i][l]
+					more = l < len(row[i])-1
+				}
+				if i > 0 {
+					b.WriteString("|")
+				}
+				b.WriteString(" ")
+				if IsNumeric(res.Columns[i].TypeOID) {
+					b.WriteString(runewidth.FillLeft(text, widths[i]))
+				} else {
+					b.WriteString(runewidth.FillRight(text, widths[i]))
+				}
+				if more {
+					b.WriteString("+")
+				} else {
+					b.WriteString(" ")
+				}
+			}
+			fmt.Fprintln(w, strings.TrimRight(b.String(), " "))
+		}
+	}
+
+	if opts.Footer && !opts.TuplesOnly {
+		fmt.Fprintln(w, RowCount(len(res.Rows)))
+	}
+}
+
+// Expanded writes res in psql's expanded (\x) layout, one record per block.
+func Expanded(w io.Writer, res Result, opts TableOptions) {
+	if res.Title != "" {
+		fmt.Fprintln(w, res.Title)
+	}
+	if len(res.Rows) == 0 {
+		fmt.Fprintln(w, "(0 rows)")
+		return
+	}
+
+	keyWidth, valWidth := 0, 0
+	for _, c := range res.Columns {
+		if cw := runewidth.StringWidth(c.Name); cw > keyWidth {
+			keyWidth = cw
+		}
+	}
+	for _, row := range res.Rows {
+		for i, v := range row {
+			for _, l := range strings.Split(cellText(v, res.Columns[i], opts), "\n") {
+				if lw := runewidth.StringWidth(l); lw > valWidth {
+					valWidth = lw
+				}
+			}
+		}
+	}
+
+	for r, row := range res.Rows {
+		fmt.Fprintln(w, recordHeader(r+1, keyWidth, valWidth))
+		for i, v := range row {
+			lines := strings.Split(cellText(v, res.Columns[i], opts), "\n")
+			for l, line := range lines {
+				key := ""
+				if l == 0 {
+					key = res.Columns[i].Name
+				}
+				suffix := ""
+				if l < len(lines)-1 {
+					suffix = "+"
+				}
+				fmt.Fprintln(w, strings.TrimRight(runewidth.FillRight(key, keyWidth)+" | "+line+suffix, " "))
+			}
+		}
+	}
+}
+
+// recordHeader builds "-[ RECORD n ]" padded with dashes, with a "+" where
+// the key/value separator falls when the label is short enough.
+func recordHeader(n, keyWidth, valWidth int) string {
+	label := fmt.Sprintf("-[ RECORD %d ]", n)
+	total := keyWidth + 3 + valWidth
+	if len(label) < keyWidth+1 {
+		label += strings.Repeat("-", keyWidth+1-len(label)) + "+"
+	}
+	if len(label) < total {
+		label += strings.Repeat("-", total-len(label))
+	}
+	return label
+}
+
+// RowCount renders psql's row count footer.
+func RowCount(n int) string {
+	if n == 1 {
+		return "(1 row)"
+	}
+	return fmt.Sprintf("(%d rows)", n)
+}
+
+func center(s string, width int) string {
+	pad := width - runewidth.StringWidth(s)
+	if pad <= 0 {
+		return s
+	}
+	left := pad / 2
+	return strings.Repeat(" ", left) + s + strings.Repeat(" ", pad-left)
+}
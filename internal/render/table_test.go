@@ -0,0 +1,114 @@
+package render
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func str(s string) *string { return &s }
+
+func testResult() Result {
+	return Result{
+		Columns: []Column{{Name: "id", TypeOID: oidInt4}, {Name: "name", TypeOID: 25}},
+		Rows: [][]*string{
+			{str("1"), str("alice")},
+			{str("42"), nil},
+		},
+	}
+}
+
+func TestTable(t *testing.T) {
+	var buf bytes.Buffer
+	Table(&buf, testResult(), DefaultTableOptions)
+	want := strings.Join([]string{
+		" id |  name",
+		"----+--------",
+		"  1 | alice",
+		" 42 | <null>",
+		"(2 rows)",
+		"",
+	}, "\n")
+	if buf.String() != want {
+		t.Errorf("Table:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestTable_MultiLineCell(t *testing.T) {
+	res := Result{
+		Columns: []Column{{Name: "body", TypeOID: 25}, {Name: "n", TypeOID: oidInt4}},
+		Rows:    [][]*string{{str("line1\nline2"), str("7")}},
+	}
+	var buf bytes.Buffer
+	Table(&buf, res, TableOptions{Footer: false})
+	want := strings.Join([]string{
+		" body  | n",
+		"-------+---",
+		" line1+| 7",
+		" line2 |",
+		"",
+	}, "\n")
+	if buf.String() != want {
+		t.Errorf("Table (multi-line):\n%q\nwant:\n%q", buf.String(), want)
+	}
+}
+
+func TestTable_TruncatesBytea(t *testing.T) {
+	long := `\x` + strings.Repeat("ab", 100)
+	res := Result{
+		Columns: []Column{{Name: "data", TypeOID: oidBytea}},
+		Rows:    [][]*string{{&long}},
+	}
+	var buf bytes.Buffer
+	Table(&buf, res, TableOptions{MaxBinaryWidth: 10})
+	if !strings.Contains(buf.String(), `\xabababab… (100 bytes)`) {
+		t.Errorf("Table (bytea): got %q", buf.String())
+	}
+}
+
+func TestExpanded(t *testing.T) {
+	var buf bytes.Buffer
+	Expanded(&buf, testResult(), DefaultTableOptions)
+	want := strings.Join([]string{
+		"-[ RECORD 1 ]",
+		"id   | 1",
+		"name | alice",
+		"-[ RECORD 2 ]",
+		"id   | 42",
+		"name | <null>",
+		"",
+	}, "\n")
+	if buf.String() != want {
+		t.Errorf("Expanded:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestRecordHeader_SeparatorMarker(t *testing.T) {
+	got := recordHeader(1, 20, 5)
+	if got != "-[ RECORD 1 ]--------+------" {
+		t.Errorf("recordHeader: got %q", got)
+	}
+}
+
+func TestRowCount(t *testing.T) {
+	if RowCount(1) != "(1 row)" || RowCount(0) != "(0 rows)" || RowCount(3) != "(3 rows)" {
+		t.Error("RowCount: unexpected pluralisation")
+	}
+}
+
+func TestTable_Title(t *testing.T) {
+	res := testResult()
+	res.Title = "List of people"
+	var buf bytes.Buffer
+	Table(&buf, res, TableOptions{Null: "", Footer: false})
+	first := strings.SplitN(buf.String(), "\n", 2)[0]
+	if first != "List of people" {
+		t.Errorf("title line: got %q", first)
+	}
+	res.Title = "T"
+	buf.Reset()
+	Table(&buf, res, TableOptions{})
+	if first := strings.SplitN(buf.String(), "\n", 2)[0]; first != "     T" {
+		t.Errorf("centered title: got %q", first)
+	}
+}
@@ -0,0 +1,55 @@
+package repl
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+)
+
+// cancelTimeout bounds how long sending a cancel request may take.
+const cancelTimeout = 5 * time.Second
+
+// interruptible runs fn with SIGINT turned into a Postgres cancel request
+// for the statement in flight. Without it Ctrl+C kills rds and leaves the
+// backend running the query on the server.
+func (s *session) interruptible(fn func()) {
+	// Capture the connection now: fn may replace s.conn (\c).
+	pg := s.conn.PgConn()
+	onInterrupt(pg.CancelRequest, fn)
+}
+
+// onInterrupt runs fn, calling cancel for every SIGINT received meanwhile.
+func onInterrupt(cancel func(context.Context) error, fn func()) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt)
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-sigs:
+				ctx, stop := context.WithTimeout(context.Background(), cancelTimeout)
+				if err := cancel(ctx); err != nil {
+					fmt.Fprintf(os.Stderr, "Could not send cancel request: %v\n", err)
+				} else {
+					fmt.Fprintln(os.Stderr, "Cancel request sent")
+				}
+				stop()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	defer func() {
+		signal.Stop(sigs)
+		close(done)
+		wg.Wait()
+	}()
+	fn()
+}
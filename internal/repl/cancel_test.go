@@ -0,0 +1,29 @@
+//go:build unix
+
+package repl
+
+import (
+	"context"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestOnInterrupt_SendsCancel(t *testing.T) {
+	cancelled := make(chan struct{}, 1)
+	cancel := func(context.Context) error {
+		cancelled <- struct{}{}
+		return nil
+	}
+
+	onInterrupt(cancel, func() {
+		if err := syscall.Kill(syscall.Getpid(), syscall.SIGINT); err != nil {
+			t.Fatalf("kill: %v", err)
+		}
+		select {
+		case <-cancelled:
+		case <-time.After(2 * time.Second):
+			t.Error("SIGINT during fn did not send a cancel request")
+		}
+	})
+}
@@ -0,0 +1,396 @@
+package repl
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/PraveenPrabhuT/rds/internal/core"
+	"github.com/PraveenPrabhuT/rds/internal/render"
+)
+
+// statementKeywords can start a statement.
+var statementKeywords = []string{
+	"ALTER", "ANALYZE", "BEGIN", "COMMIT", "COPY", "CREATE", "DELETE", "DROP",
+	"EXPLAIN", "GRANT", "INSERT", "RESET", "REVOKE", "ROLLBACK", "SELECT", "SET",
+	"SHOW", "TABLE", "TRUNCATE", "UPDATE", "VACUUM", "VALUES", "WITH",
+}
+
+// keywords are offered anywhere else in a statement.
+var keywords = []string{
+	"ALL", "AND", "AS", "ASC", "BETWEEN", "BY", "CASE", "CROSS", "DEFAULT",
+	"DESC", "DISTINCT", "ELSE", "END", "EXISTS", "FROM", "FULL", "GROUP",
+	"HAVING", "ILIKE", "IN", "INDEX", "INNER", "INTO", "IS", "JOIN", "LEFT",
+	"LIKE", "LIMIT", "NOT", "NULL", "OFFSET", "ON", "OR", "ORDER", "OUTER",
+	"RETURNING", "RIGHT", "SELECT", "SET", "TABLE", "THEN", "UNION", "USING",
+	"VALUES", "VIEW", "WHEN", "WHERE", "WITH",
+}
+
+// relationKeywords are followed by a table name.
+var relationKeywords = map[string]bool{
+	"FROM": true, "JOIN": true, "INTO": true, "UPDATE": true,
+	"TABLE": true, "TRUNCATE": true, "ONLY": true,
+}
+
+// relationMeta are the meta-commands whose argument is a relation.
+var relationMeta = map[string]bool{`\d`: true, `\dt`: true, `\di`: true, `\dv`: true, `\dp`: true}
+
+// relation is a table-like object known to the completer.
+type relation struct {
+	schema, name string
+	// visible is true when the relation is reachable through search_path
+	// and can be completed unqualified.
+	visible bool
+}
+
+// catalog is the completion data of one database.
+type catalog struct {
+	schemas   []string
+	relations []relation
+	// columns maps unqualified and schema-qualified relation names to
+	// their columns.
+	columns   map[string][]string
+	functions []string
+}
+
+// completer implements readline.AutoCompleter with keywords and objects
+// from pg_catalog. Catalogs are loaded on first use and cached per
+// database until \refresh.
+type completer struct {
+	// db returns the current database.
+	db func() string
+	// pending returns the unfinished statement text from earlier lines.
+	pending func() string
+	load    func() (*catalog, error)
+	cache   map[string]*catalog
+}
+
+func newCompleter(s *session, sc *scanner) *completer {
+	return &completer{
+		db:      func() string { return s.opts.DB },
+		pending: func() string { return sc.buf.String() },
+		load:    func() (*catalog, error) { return s.loadCatalog(context.Background()) },
+		cache:   map[string]*catalog{},
+	}
+}
+
+// refresh drops the cached catalog of the current database and reloads it.
+func (c *completer) refresh() error {
+	delete(c.cache, c.db())
+	_, err := c.catalog()
+	return err
+}
+
+func (c *completer) catalog() (*catalog, error) {
+	if cat, ok := c.cache[c.db()]; ok {
+		return cat, nil
+	}
+	cat, err := c.load()
+	if err != nil {
+		return nil, err
+	}
+	c.cache[c.db()] = cat
+	return cat, nil
+}
+
+// Do returns the completions for the word before the cursor, as suffixes
+// of that word.
+func (c *completer) Do(line []rune, pos int) ([][]rune, int) {
+	before := string(line[:pos])
+	word := currentWord(before)
+	head := before[:len(before)-len(word)]
+
+	var out [][]rune
+	for _, cand := range c.candidates(head, word) {
+		if len(cand) >= len(word) && strings.EqualFold(cand[:len(word)], word) {
+			out = append(out, []rune(cand[len(word):]))
+		}
+	}
+	return out, len([]rune(word))
+}
+
+// candidates lists the full words that may complete word, given the text
+// before it on the line.
+func (c *completer) candidates(head, word string) []string {
+	if strings.HasPrefix(strings.TrimLeft(head, " \t"), `\`) {
+		return c.metaCandidates(head)
+	}
+
+	stmt := c.pending() + head
+	prev := previousToken(stmt)
+	if prev == "" {
+		return matchCase(statementKeywords, word)
+	}
+
+	cat, err := c.catalog()
+	if err != nil {
+		// Catalog unavailable (e.g. aborted transaction): keywords only.
+		return matchCase(keywords, word)
+	}
+
+	if i := strings.LastIndex(word, "."); i >= 0 {
+		return cat.qualified(word[:i])
+	}
+	if relationKeywords[prev] || (prev == "," && relationKeywords[lastKeyword(stmt)]) {
+		return cat.relationNames()
+	}
+
+	var cands []string
+	if tables := referencedTables(stmt); len(tables) > 0 {
+		for _, t := range tables {
+			cands = append(cands, cat.columns[t]...)
+		}
+	} else {
+		for _, r := range cat.relations {
+			if r.visible {
+				cands = append(cands, cat.columns[r.name]...)
+			}
+		}
+	}
+	cands = append(cands, cat.functions...)
+	return append(dedupe(cands), matchCase(keywords, word)...)
+}
+
+func (c *completer) metaCandidates(head string) []string {
+	fields := strings.Fields(head)
+	if len(fields) != 1 || !strings.HasSuffix(head, " ") {
+		return nil
+	}
+	cat, err := c.catalog()
+	if err != nil {
+		return nil
+	}
+	switch {
+	case relationMeta[fields[0]]:
+		return cat.relationNames()
+	case fields[0] == `\dn`:
+		return quoteAll(cat.schemas)
+	case fields[0] == `\df`:
+		return cat.functions
+	}
+	return nil
+}
+
+// relationNames lists the visible relations unqualified plus every schema
+// with a trailing dot, to continue with a qualified name.
+func (cat *catalog) relationNames() []string {
+	var names []string
+	for _, r := range cat.relations {
+		if r.visible {
+			names = append(names, quoteIdent(r.name))
+		}
+	}
+	for _, s := range cat.schemas {
+		names = append(names, quoteIdent(s)+".")
+	}
+	return dedupe(names)
+}
+
+// qualified completes "qualifier.": the relations of a schema or the
+// columns of a relation.
+func (cat *catalog) qualified(qualifier string) []string {
+	name := unquoteIdent(qualifier)
+	var names []string
+	for _, r := range cat.relations {
+		if r.schema == name {
+			names = append(names, qualifier+"."+quoteIdent(r.name))
+		}
+	}
+	for _, col := range cat.columns[name] {
+		names = append(names, qualifier+"."+quoteIdent(col))
+	}
+	return names
+}
+
+// catalogTimeout bounds loading a catalog so a slow server does not hold
+// up the prompt.
+const catalogTimeout = 5 * time.Second
+
+// loadCatalog reads schemas, relations, columns and functions from
+// pg_catalog. It uses a short-lived connection of its own: a failing query
+// on the session's connection would abort the user's open transaction.
+func (s *session) loadCatalog(ctx context.Context) (*catalog, error) {
+	ctx, cancel := context.WithTimeout(ctx, catalogTimeout)
+	defer cancel()
+
+	conn, err := core.NewPgxSession(ctx, s.opts.Host, s.opts.Port, s.opts.User, s.opts.Password, s.opts.DB, core.SessionOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close(context.Background())
+	query := func(ctx context.Context, sql string) (render.Result, error) {
+		res, _, err := collect(conn.PgConn().ExecParams(ctx, sql, nil, nil, nil, nil))
+		return res, err
+	}
+
+	cat := &catalog{columns: map[string][]string{}}
+
+	res, err := query(ctx, `SELECT n.nspname FROM pg_catalog.pg_namespace n
+WHERE n.nspname !~ '^pg_' AND n.nspname <> 'information_schema' ORDER BY 1`)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range res.Rows {
+		cat.schemas = append(cat.schemas, *row[0])
+	}
+
+	res, err = query(ctx, `SELECT n.nspname, c.relname, pg_catalog.pg_table_is_visible(c.oid)::text
+FROM pg_catalog.pg_class c
+JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+WHERE c.relkind IN ('r','p','v','m','f') AND `+systemSchemas+`
+ORDER BY 2, 1`)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range res.Rows {
+		cat.relations = append(cat.relations, relation{schema: *row[0], name: *row[1], visible: *row[2] == "true"})
+	}
+
+	res, err = query(ctx, `SELECT n.nspname, c.relname, a.attname
+FROM pg_catalog.pg_attribute a
+JOIN pg_catalog.pg_class c ON c.oid = a.attrelid
+JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+WHERE c.relkind IN ('r','p','v','m','f') AND a.attnum > 0 AND NOT a.attisdropped AND `+systemSchemas+`
+ORDER BY n.nspname, c.relname, a.attnum`)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range res.Rows {
+		schema, table, col := *row[0], *row[1], *row[2]
+		cat.columns[table] = append(cat.columns[table], col)
+		cat.columns[schema+"."+table] = append(cat.columns[schema+"."+table], col)
+	}
+
+	res, err = query(ctx, `SELECT DISTINCT p.proname FROM pg_catalog.pg_proc p
+WHERE pg_catalog.pg_function_is_visible(p.oid) AND p.proname !~ '^_' ORDER BY 1`)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range res.Rows {
+		cat.functions = append(cat.functions, *row[0])
+	}
+	return cat, nil
+}
+
+// currentWord returns the identifier-like word ending at the cursor.
+func currentWord(before string) string {
+	rs := []rune(before)
+	i := len(rs)
+	for i > 0 {
+		c := rs[i-1]
+		if !(isIdentRune(c) || c == '.' || c == '"' || c == '\\') {
+			break
+		}
+		i--
+	}
+	return string(rs[i:])
+}
+
+var tokenRe = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_$]*|"[^"]*"|\S`)
+
+// previousToken returns the last complete token of stmt, upper-cased when
+// it is a word, or "" at the start of a statement.
+func previousToken(stmt string) string {
+	tokens := tokenRe.FindAllString(stmt, -1)
+	if len(tokens) == 0 {
+		return ""
+	}
+	last := tokens[len(tokens)-1]
+	if last == ";" {
+		return ""
+	}
+	return strings.ToUpper(last)
+}
+
+// lastKeyword returns the last relation keyword or other SQL keyword of
+// stmt, so a comma after "FROM a" still completes relations.
+func lastKeyword(stmt string) string {
+	tokens := tokenRe.FindAllString(stmt, -1)
+	for i := len(tokens) - 1; i >= 0; i-- {
+		t := strings.ToUpper(tokens[i])
+		if relationKeywords[t] || t == "SELECT" || t == "WHERE" || t == "SET" || t == "ON" {
+			return t
+		}
+	}
+	return ""
+}
+
+var tableRefRe = regexp.MustCompile(`(?i)\b(?:from|join|update|into)\s+((?:"[^"]+"|[\w$]+)(?:\.(?:"[^"]+"|[\w$]+))?)`)
+
+// referencedTables returns the relations named after FROM, JOIN, UPDATE or
+// INTO in stmt, normalized to catalog names.
+func referencedTables(stmt string) []string {
+	var tables []string
+	for _, m := range tableRefRe.FindAllStringSubmatch(stmt, -1) {
+		parts := strings.SplitN(m[1], ".", 2)
+		for i := range parts {
+			parts[i] = unquoteIdent(parts[i])
+		}
+		tables = append(tables, strings.Join(parts, "."))
+	}
+	return tables
+}
+
+var plainIdentRe = regexp.MustCompile(`^[a-z_][a-z0-9_$]*$`)
+
+// quoteIdent double-quotes an identifier unless it is a plain lower-case
+// name.
+func quoteIdent(name string) string {
+	if plainIdentRe.MatchString(name) {
+		return name
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// unquoteIdent turns a typed identifier into its catalog name: quoted
+// identifiers keep their case, plain ones are folded to lower case.
+func unquoteIdent(ident string) string {
+	if len(ident) >= 2 && strings.HasPrefix(ident, `"`) && strings.HasSuffix(ident, `"`) {
+		return strings.ReplaceAll(ident[1:len(ident)-1], `""`, `"`)
+	}
+	return strings.ToLower(ident)
+}
+
+func quoteAll(names []string) []string {
+	out := make([]string, len(names))
+	for i, n := range names {
+		out[i] = quoteIdent(n)
+	}
+	return out
+}
+
+// matchCase returns the keywords in lower case when the user is typing in
+// lower case, and upper case otherwise.
+func matchCase(words []string, typed string) []string {
+	lower := typed != ""
+	for _, r := range typed {
+		if unicode.IsUpper(r) {
+			lower = false
+			break
+		}
+	}
+	if !lower {
+		return words
+	}
+	out := make([]string, len(words))
+	for i, w := range words {
+		out[i] = strings.ToLower(w)
+	}
+	return out
+}
+
+func dedupe(words []string) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, w := range words {
+		if !seen[w] {
+			seen[w] = true
+			out = append(out, w)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,121 @@
+package repl
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func testCompleter(pending string) (*completer, *int) {
+	loads := 0
+	cat := &catalog{
+		schemas: []string{"app", "public"},
+		relations: []relation{
+			{schema: "public", name: "users", visible: true},
+			{schema: "public", name: "orders", visible: true},
+			{schema: "app", name: "events", visible: false},
+			{schema: "app", name: "Audit", visible: false},
+		},
+		columns: map[string][]string{
+			"users":      {"id", "email"},
+			"orders":     {"id", "user_id", "total"},
+			"events":     {"id", "kind"},
+			"app.events": {"id", "kind"},
+		},
+		functions: []string{"now", "count"},
+	}
+	db := "main"
+	return &completer{
+		db:      func() string { return db },
+		pending: func() string { return pending },
+		load: func() (*catalog, error) {
+			loads++
+			return cat, nil
+		},
+		cache: map[string]*catalog{},
+	}, &loads
+}
+
+func complete(c *completer, line string) []string {
+	out, _ := c.Do([]rune(line), len([]rune(line)))
+	var got []string
+	for _, s := range out {
+		got = append(got, string(s))
+	}
+	sort.Strings(got)
+	return got
+}
+
+func TestCompleter(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want []string
+	}{
+		{"statement keyword", "SEL", []string{"ECT"}},
+		{"lower-case keyword", "sel", []string{"ect"}},
+		{"relation after FROM", "SELECT * FROM u", []string{"sers"}},
+		{"relation after comma", "SELECT * FROM users, o", []string{"rders"}},
+		{"schema after FROM", "SELECT * FROM ap", []string{"p."}},
+		{"schema-qualified relation", "SELECT * FROM app.e", []string{"vents"}},
+		{"quoted relation", `SELECT * FROM app."A`, []string{"udit\""}},
+		{"columns of referenced table", "SELECT * FROM orders WHERE t", []string{"able", "hen", "otal"}},
+		{"qualified column", "SELECT users.e", []string{"mail"}},
+		{"function", "SELECT n", []string{"ot", "ow", "ull"}},
+		{"meta relation", `\d or`, []string{"ders"}},
+		{"meta schema", `\dn a`, []string{"pp"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := testCompleter("")
+			if got := complete(c, tt.line); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("complete(%q) = %q, want %q", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompleter_UsesPendingLines(t *testing.T) {
+	c, _ := testCompleter("SELECT *\nFROM orders\n")
+	if got := complete(c, "WHERE us"); !reflect.DeepEqual(got, []string{"er_id", "ing"}) {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestCompleter_CachesAndRefreshes(t *testing.T) {
+	c, loads := testCompleter("")
+	complete(c, "SELECT * FROM u")
+	complete(c, "SELECT * FROM o")
+	if *loads != 1 {
+		t.Fatalf("catalog loaded %d times, want 1", *loads)
+	}
+	if err := c.refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if *loads != 2 {
+		t.Errorf("refresh: catalog loaded %d times, want 2", *loads)
+	}
+}
+
+func TestCompleter_LoadErrorFallsBackToKeywords(t *testing.T) {
+	c, _ := testCompleter("")
+	c.load = func() (*catalog, error) { return nil, errors.New("boom") }
+	if got := complete(c, "SELECT * FROM users WHE"); !reflect.DeepEqual(got, []string{"N", "RE"}) {
+		t.Errorf("got %q", got)
+	}
+	if _, ok := c.cache["main"]; ok {
+		t.Error("a failed load must not be cached")
+	}
+}
+
+func TestQuoteIdent(t *testing.T) {
+	for in, want := range map[string]string{"users": "users", "Audit": `"Audit"`, "my table": `"my table"`, `a"b`: `"a""b"`} {
+		if got := quoteIdent(in); got != want {
+			t.Errorf("quoteIdent(%q) = %q, want %q", in, got, want)
+		}
+		if got := unquoteIdent(quoteIdent(in)); got != in {
+			t.Errorf("unquoteIdent(quoteIdent(%q)) = %q", in, got)
+		}
+	}
+}
@@ -0,0 +1,442 @@
+package repl
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/PraveenPrabhuT/rds/internal/render"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// relkindName maps pg_class.relkind to the type names psql prints.
+const relkindName = `CASE c.relkind
+	WHEN 'r' THEN 'table' WHEN 'v' THEN 'view' WHEN 'm' THEN 'materialized view'
+	WHEN 'i' THEN 'index' WHEN 'S' THEN 'sequence' WHEN 't' THEN 'TOAST table'
+	WHEN 'f' THEN 'foreign table' WHEN 'p' THEN 'partitioned table'
+	WHEN 'I' THEN 'partitioned index' END`
+
+// listing is the catalog query behind one of psql's list commands.
+type listing struct {
+	title string
+	// query has a single %s for the pattern filter.
+	query string
+	// schema and name are the columns a pattern is matched against; schema
+	// is empty for objects that do not live in a schema.
+	schema, name string
+	// visible restricts unqualified patterns to the search_path.
+	visible string
+	// system hides catalog objects when no pattern is given.
+	system string
+	// relations marks the \d family, which reports an empty result in
+	// words instead of printing an empty table.
+	relations bool
+}
+
+const systemSchemas = `n.nspname <> 'pg_catalog' AND n.nspname <> 'information_schema' AND n.nspname !~ '^pg_toast'`
+
+func relationListing(kinds string, withTable bool) listing {
+	cols := `n.nspname AS "Schema", c.relname AS "Name", ` + relkindName + ` AS "Type",
+		pg_catalog.pg_get_userbyid(c.relowner) AS "Owner"`
+	from := `pg_catalog.pg_class c
+		LEFT JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace`
+	if withTable {
+		cols += `, c2.relname AS "Table"`
+		from += `
+		LEFT JOIN pg_catalog.pg_index i ON i.indexrelid = c.oid
+		LEFT JOIN pg_catalog.pg_class c2 ON i.indrelid = c2.oid`
+	}
+	return listing{
+		title:     "List of relations",
+		query:     "SELECT " + cols + "\nFROM " + from + "\nWHERE c.relkind IN (" + kinds + ") AND %s\nORDER BY 1, 2",
+		schema:    "n.nspname",
+		name:      "c.relname",
+		visible:   "pg_catalog.pg_table_is_visible(c.oid)",
+		system:    systemSchemas,
+		relations: true,
+	}
+}
+
+var listings = map[string]listing{
+	`\l`: {
+		title: "List of databases",
+		query: `SELECT d.datname AS "Name",
+		pg_catalog.pg_get_userbyid(d.datdba) AS "Owner",
+		pg_catalog.pg_encoding_to_char(d.encoding) AS "Encoding",
+		d.datcollate AS "Collate",
+		d.datctype AS "Ctype",
+		COALESCE(pg_catalog.array_to_string(d.datacl, E'\n'), '') AS "Access privileges"
+FROM pg_catalog.pg_database d
+WHERE %s
+ORDER BY 1`,
+		name: "d.datname",
+	},
+	`\dn`: {
+		title: "List of schemas",
+		query: `SELECT n.nspname AS "Name", pg_catalog.pg_get_userbyid(n.nspowner) AS "Owner"
+FROM pg_catalog.pg_namespace n
+WHERE %s
+ORDER BY 1`,
+		name:   "n.nspname",
+		system: `n.nspname !~ '^pg_' AND n.nspname <> 'information_schema'`,
+	},
+	`\d`:  relationListing("'r','p','v','m','S','f'", false),
+	`\dt`: relationListing("'r','p'", false),
+	`\di`: relationListing("'i','I'", true),
+	`\dv`: relationListing("'v'", false),
+	`\df`: {
+		title: "List of functions",
+		query: `SELECT n.nspname AS "Schema", p.proname AS "Name",
+		COALESCE(pg_catalog.pg_get_function_result(p.oid), '') AS "Result data type",
+		pg_catalog.pg_get_function_arguments(p.oid) AS "Argument data types",
+		CASE p.prokind WHEN 'a' THEN 'agg' WHEN 'w' THEN 'window' WHEN 'p' THEN 'proc' ELSE 'func' END AS "Type"
+FROM pg_catalog.pg_proc p
+LEFT JOIN pg_catalog.pg_namespace n ON n.oid = p.pronamespace
+WHERE %s
+ORDER BY 1, 2, 4`,
+		schema:  "n.nspname",
+		name:    "p.proname",
+		visible: "pg_catalog.pg_function_is_visible(p.oid)",
+		system:  `n.nspname <> 'pg_catalog' AND n.nspname <> 'information_schema'`,
+	},
+	`\du`: {
+		title: "List of roles",
+		query: `SELECT r.rolname AS "Role name",
+		pg_catalog.array_to_string(ARRAY[
+			CASE WHEN r.rolsuper THEN 'Superuser' END,
+			CASE WHEN NOT r.rolinherit THEN 'No inheritance' END,
+			CASE WHEN r.rolcreaterole THEN 'Create role' END,
+			CASE WHEN r.rolcreatedb THEN 'Create DB' END,
+			CASE WHEN NOT r.rolcanlogin THEN 'Cannot login' END,
+			CASE WHEN r.rolreplication THEN 'Replication' END,
+			CASE WHEN r.rolbypassrls THEN 'Bypass RLS' END,
+			CASE WHEN r.rolconnlimit = 1 THEN '1 connection'
+			     WHEN r.rolconnlimit >= 0 THEN r.rolconnlimit || ' connections' END,
+			CASE WHEN r.rolvaliduntil IS NOT NULL THEN 'Password valid until ' || r.rolvaliduntil END
+		], ', ') AS "Attributes"
+FROM pg_catalog.pg_roles r
+WHERE %s
+ORDER BY 1`,
+		name:   "r.rolname",
+		system: `r.rolname !~ '^pg_'`,
+	},
+	`\dp`: {
+		title: "Access privileges",
+		query: `SELECT n.nspname AS "Schema", c.relname AS "Name", ` + relkindName + ` AS "Type",
+		COALESCE(pg_catalog.array_to_string(c.relacl, E'\n'), '') AS "Access privileges",
+		pg_catalog.array_to_string(ARRAY(
+			SELECT a.attname || E':\n  ' || pg_catalog.array_to_string(a.attacl, E'\n  ')
+			FROM pg_catalog.pg_attribute a
+			WHERE a.attrelid = c.oid AND NOT a.attisdropped AND a.attacl IS NOT NULL
+		), E'\n') AS "Column privileges",
+		pg_catalog.array_to_string(ARRAY(
+			SELECT pol.polname
+				|| CASE WHEN pol.polcmd <> '*' THEN ' (' || pol.polcmd::text || ')' ELSE '' END || ':'
+				|| CASE WHEN pol.polqual IS NOT NULL
+				        THEN E'\n  (u): ' || pg_catalog.pg_get_expr(pol.polqual, pol.polrelid) ELSE '' END
+				|| CASE WHEN pol.polwithcheck IS NOT NULL
+				        THEN E'\n  (c): ' || pg_catalog.pg_get_expr(pol.polwithcheck, pol.polrelid) ELSE '' END
+			FROM pg_catalog.pg_policy pol
+			WHERE pol.polrelid = c.oid
+		), E'\n') AS "Policies"
+FROM pg_catalog.pg_class c
+LEFT JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+WHERE c.relkind IN ('r','v','m','S','f','p') AND %s
+ORDER BY 1, 2`,
+		schema:  "n.nspname",
+		name:    "c.relname",
+		visible: "pg_catalog.pg_table_is_visible(c.oid)",
+		system:  systemSchemas,
+	},
+	`\dx`: {
+		title: "List of installed extensions",
+		query: `SELECT e.extname AS "Name", e.extversion AS "Version", n.nspname AS "Schema",
+		COALESCE(d.description, '') AS "Description"
+FROM pg_catalog.pg_extension e
+LEFT JOIN pg_catalog.pg_namespace n ON n.oid = e.extnamespace
+LEFT JOIN pg_catalog.pg_description d
+	ON d.objoid = e.oid AND d.classoid = 'pg_catalog.pg_extension'::pg_catalog.regclass
+WHERE %s
+ORDER BY 1`,
+		name: "e.extname",
+	},
+}
+
+// describe runs one of the describe meta-commands.
+func (s *session) describe(ctx context.Context, cmd string, args []string) {
+	if cmd == `\list` {
+		cmd = `\l`
+	}
+	pattern := ""
+	if len(args) > 0 {
+		pattern = args[0]
+	}
+	if cmd == `\d` && pattern != "" {
+		s.describeRelations(ctx, pattern)
+		return
+	}
+
+	l := listings[cmd]
+	where, params := l.filter(pattern)
+	res, err := s.query(ctx, fmt.Sprintf(l.query, where), params...)
+	if err != nil {
+		printError(s.out, err)
+		return
+	}
+	if l.relations && len(res.Rows) == 0 {
+		if pattern == "" {
+			fmt.Fprintln(s.out, "Did not find any relations.")
+		} else {
+			fmt.Fprintf(s.out, "Did not find any relation named %q.\n", pattern)
+		}
+		return
+	}
+	res.Title = l.title
+	s.printResult(res, pgconn.CommandTag{})
+}
+
+// filter builds the WHERE condition and parameters that scope l to
+// pattern, the way psql does: no pattern hides system objects, an
+// unqualified pattern only sees the search_path and a schema-qualified one
+// matches the schema explicitly.
+func (l listing) filter(pattern string) (string, []string) {
+	var conds, params []string
+	add := func(col, re string) {
+		params = append(params, re)
+		conds = append(conds, fmt.Sprintf("%s OPERATOR(pg_catalog.~) $%d COLLATE pg_catalog.default", col, len(params)))
+	}
+
+	schemaRe, nameRe, qualified := patternRegex(pattern)
+	if nameRe != "" {
+		add(l.name, nameRe)
+	}
+	switch {
+	case pattern == "":
+		if l.system != "" {
+			conds = append(conds, l.system)
+		}
+		if l.visible != "" {
+			conds = append(conds, l.visible)
+		}
+	case qualified && l.schema != "":
+		if schemaRe != "" {
+			add(l.schema, schemaRe)
+		}
+	case l.visible != "":
+		conds = append(conds, l.visible)
+	}
+
+	if len(conds) == 0 {
+		return "true", params
+	}
+	return strings.Join(conds, " AND "), params
+}
+
+// patternRegex converts a psql object pattern into anchored regular
+// expressions for the schema and object name. Unquoted text is folded to
+// lower case, * and ? are wildcards, "." separates schema from name and
+// double quotes preserve case and special characters. An empty result
+// means "match anything".
+func patternRegex(pattern string) (schema, name string, qualified bool) {
+	var parts []string
+	var cur strings.Builder
+	inQuotes := false
+	rs := []rune(pattern)
+	for i := 0; i < len(rs); i++ {
+		c := rs[i]
+		switch {
+		case c == '"':
+			if inQuotes && i+1 < len(rs) && rs[i+1] == '"' {
+				cur.WriteRune('"')
+				i++
+			} else {
+				inQuotes = !inQuotes
+			}
+		case inQuotes:
+			cur.WriteString(regexp.QuoteMeta(string(c)))
+		case c == '.':
+			parts = append(parts, cur.String())
+			cur.Reset()
+		case c == '*':
+			cur.WriteString(".*")
+		case c == '?':
+			cur.WriteRune('.')
+		case c == '$':
+			cur.WriteString(`\$`)
+		default:
+			cur.WriteRune(unicode.ToLower(c))
+		}
+	}
+	parts = append(parts, cur.String())
+
+	anchor := func(re string) string {
+		if re == "" || re == ".*" {
+			return ""
+		}
+		return "^(" + re + ")$"
+	}
+	name = anchor(parts[len(parts)-1])
+	if len(parts) > 1 {
+		schema = anchor(parts[len(parts)-2])
+		qualified = true
+	}
+	return schema, name, qualified
+}
+
+// relkindTitle maps pg_class.relkind to the title psql gives \d output.
+var relkindTitle = map[string]string{
+	"r": "Table",
+	"p": "Partitioned table",
+	"v": "View",
+	"m": "Materialized view",
+	"i": "Index",
+	"I": "Partitioned index",
+	"S": "Sequence",
+	"f": "Foreign table",
+	"t": "TOAST table",
+	"c": "Composite type",
+}
+
+const columnsQuery = `SELECT a.attname AS "Column",
+		pg_catalog.format_type(a.atttypid, a.atttypmod) AS "Type",
+		COALESCE((SELECT co.collname FROM pg_catalog.pg_collation co
+			WHERE co.oid = a.attcollation AND a.attcollation <> t.typcollation), '') AS "Collation",
+		CASE WHEN a.attnotnull THEN 'not null' ELSE '' END AS "Nullable",
+		CASE WHEN a.attidentity = 'a' THEN 'generated always as identity'
+		     WHEN a.attidentity = 'd' THEN 'generated by default as identity'
+		     WHEN a.attgenerated = 's' THEN 'generated always as (' || pg_catalog.pg_get_expr(ad.adbin, ad.adrelid) || ') stored'
+		     ELSE COALESCE(pg_catalog.pg_get_expr(ad.adbin, ad.adrelid), '') END AS "Default"
+FROM pg_catalog.pg_attribute a
+JOIN pg_catalog.pg_type t ON t.oid = a.atttypid
+LEFT JOIN pg_catalog.pg_attrdef ad ON ad.adrelid = a.attrelid AND ad.adnum = a.attnum AND a.atthasdef
+WHERE a.attrelid = $1::pg_catalog.oid AND a.attnum > 0 AND NOT a.attisdropped
+ORDER BY a.attnum`
+
+const indexColumnsQuery = `SELECT a.attname AS "Column",
+		pg_catalog.format_type(a.atttypid, a.atttypmod) AS "Type",
+		CASE WHEN a.attnum <= i.indnkeyatts THEN 'yes' ELSE 'no' END AS "Key?",
+		pg_catalog.pg_get_indexdef(a.attrelid, a.attnum, true) AS "Definition"
+FROM pg_catalog.pg_attribute a
+JOIN pg_catalog.pg_index i ON i.indexrelid = a.attrelid
+WHERE a.attrelid = $1::pg_catalog.oid AND a.attnum > 0 AND NOT a.attisdropped
+ORDER BY a.attnum`
+
+const indexFooterQuery = `SELECT CASE WHEN i.indisprimary THEN 'primary key, '
+		WHEN i.indisunique THEN 'unique, ' ELSE '' END
+		|| am.amname || ', for table "' || tn.nspname || '.' || t.relname || '"'
+FROM pg_catalog.pg_index i
+JOIN pg_catalog.pg_class c ON c.oid = i.indexrelid
+JOIN pg_catalog.pg_am am ON am.oid = c.relam
+JOIN pg_catalog.pg_class t ON t.oid = i.indrelid
+JOIN pg_catalog.pg_namespace tn ON tn.oid = t.relnamespace
+WHERE i.indexrelid = $1::pg_catalog.oid`
+
+// tableFooters are the sections psql prints under a table's columns.
+var tableFooters = []struct {
+	heading string
+	query   string
+}{
+	{"Indexes:", `SELECT '"' || c2.relname || '" '
+		|| CASE WHEN i.indisprimary THEN 'PRIMARY KEY, '
+		        WHEN con.contype = 'u' THEN 'UNIQUE CONSTRAINT, '
+		        WHEN i.indisunique THEN 'UNIQUE, ' ELSE '' END
+		|| substring(pg_catalog.pg_get_indexdef(i.indexrelid, 0, true) from ' USING (.*)$')
+FROM pg_catalog.pg_index i
+JOIN pg_catalog.pg_class c2 ON c2.oid = i.indexrelid
+LEFT JOIN pg_catalog.pg_constraint con
+	ON con.conrelid = i.indrelid AND con.conindid = i.indexrelid AND con.contype IN ('p', 'u', 'x')
+WHERE i.indrelid = $1::pg_catalog.oid
+ORDER BY i.indisprimary DESC, c2.relname`},
+	{"Check constraints:", `SELECT '"' || conname || '" ' || pg_catalog.pg_get_constraintdef(oid, true)
+FROM pg_catalog.pg_constraint
+WHERE conrelid = $1::pg_catalog.oid AND contype = 'c'
+ORDER BY conname`},
+	{"Foreign-key constraints:", `SELECT '"' || conname || '" ' || pg_catalog.pg_get_constraintdef(oid, true)
+FROM pg_catalog.pg_constraint
+WHERE conrelid = $1::pg_catalog.oid AND contype = 'f'
+ORDER BY conname`},
+	{"Referenced by:", `SELECT 'TABLE "' || conrelid::pg_catalog.regclass::text || '" CONSTRAINT "' || conname || '" '
+		|| pg_catalog.pg_get_constraintdef(oid, true)
+FROM pg_catalog.pg_constraint
+WHERE confrelid = $1::pg_catalog.oid AND contype = 'f'
+ORDER BY conname`},
+}
+
+// describeRelations prints the \d detail view of every relation matching
+// pattern.
+func (s *session) describeRelations(ctx context.Context, pattern string) {
+	l := listing{
+		schema:  "n.nspname",
+		name:    "c.relname",
+		visible: "pg_catalog.pg_table_is_visible(c.oid)",
+	}
+	where, params := l.filter(pattern)
+	rels, err := s.query(ctx, `SELECT c.oid, n.nspname, c.relname, c.relkind::text
+FROM pg_catalog.pg_class c
+LEFT JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+WHERE `+where+`
+ORDER BY 2, 3`, params...)
+	if err != nil {
+		printError(s.out, err)
+		return
+	}
+	if len(rels.Rows) == 0 {
+		fmt.Fprintf(s.out, "Did not find any relation named %q.\n", pattern)
+		return
+	}
+
+	for _, rel := range rels.Rows {
+		oid, schema, name, kind := *rel[0], *rel[1], *rel[2], *rel[3]
+		if err := s.describeRelation(ctx, oid, schema, name, kind); err != nil {
+			printError(s.out, err)
+			return
+		}
+	}
+}
+
+func (s *session) describeRelation(ctx context.Context, oid, schema, name, kind string) error {
+	index := kind == "i" || kind == "I"
+	query := columnsQuery
+	if index {
+		query = indexColumnsQuery
+	}
+	res, err := s.query(ctx, query, oid)
+	if err != nil {
+		return err
+	}
+	res.Title = fmt.Sprintf("%s \"%s.%s\"", relkindTitle[kind], schema, name)
+	opts := s.table
+	opts.Footer = false
+	render.Table(s.out, res, opts)
+
+	switch {
+	case index:
+		footer, err := s.query(ctx, indexFooterQuery, oid)
+		if err != nil {
+			return err
+		}
+		for _, row := range footer.Rows {
+			fmt.Fprintln(s.out, *row[0])
+		}
+	case kind == "r" || kind == "p":
+		for _, f := range tableFooters {
+			lines, err := s.query(ctx, f.query, oid)
+			if err != nil {
+				return err
+			}
+			if len(lines.Rows) == 0 {
+				continue
+			}
+			fmt.Fprintln(s.out, f.heading)
+			for _, row := range lines.Rows {
+				if row[0] != nil {
+					fmt.Fprintln(s.out, "    "+*row[0])
+				}
+			}
+		}
+	}
+	fmt.Fprintln(s.out)
+	return nil
+}
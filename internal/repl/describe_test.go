@@ -0,0 +1,74 @@
+package repl
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPatternRegex(t *testing.T) {
+	tests := []struct {
+		pattern      string
+		schema, name string
+		qualified    bool
+	}{
+		{"", "", "", false},
+		{"users", "", "^(users)$", false},
+		{"Users", "", "^(users)$", false},
+		{`"Users"`, "", "^(Users)$", false},
+		{"user*", "", "^(user.*)$", false},
+		{"user?", "", "^(user.)$", false},
+		{"public.users", "^(public)$", "^(users)$", true},
+		{"public.*", "^(public)$", "", true},
+		{"*.users", "", "^(users)$", true},
+		{`"my.schema".t`, `^(my\.schema)$`, "^(t)$", true},
+		{`"a""b"`, "", `^(a"b)$`, false},
+		{"cost$", "", `^(cost\$)$`, false},
+	}
+	for _, tt := range tests {
+		schema, name, qualified := patternRegex(tt.pattern)
+		if schema != tt.schema || name != tt.name || qualified != tt.qualified {
+			t.Errorf("patternRegex(%q) = %q, %q, %v; want %q, %q, %v",
+				tt.pattern, schema, name, qualified, tt.schema, tt.name, tt.qualified)
+		}
+	}
+}
+
+func TestListingFilter(t *testing.T) {
+	l := listings[`\dt`]
+	tests := []struct {
+		pattern string
+		want    []string
+		params  []string
+	}{
+		{"", []string{systemSchemas, l.visible}, nil},
+		{"users", []string{"c.relname OPERATOR(pg_catalog.~) $1", l.visible}, []string{"^(users)$"}},
+		{"app.users", []string{"c.relname OPERATOR(pg_catalog.~) $1", "n.nspname OPERATOR(pg_catalog.~) $2"}, []string{"^(users)$", "^(app)$"}},
+		{"app.*", []string{"n.nspname OPERATOR(pg_catalog.~) $1"}, []string{"^(app)$"}},
+	}
+	for _, tt := range tests {
+		where, params := l.filter(tt.pattern)
+		for _, frag := range tt.want {
+			if !strings.Contains(where, frag) {
+				t.Errorf("filter(%q) = %q, missing %q", tt.pattern, where, frag)
+			}
+		}
+		if tt.pattern != "" && strings.Contains(where, systemSchemas) {
+			t.Errorf("filter(%q) should not hide system schemas", tt.pattern)
+		}
+		if !reflect.DeepEqual(params, tt.params) {
+			t.Errorf("filter(%q) params = %q, want %q", tt.pattern, params, tt.params)
+		}
+	}
+}
+
+func TestListingFilter_Unqualified(t *testing.T) {
+	where, params := listings[`\l`].filter("")
+	if where != "true" || params != nil {
+		t.Errorf(`\l filter = %q, %q; want "true", nil`, where, params)
+	}
+	where, _ = listings[`\du`].filter("")
+	if where != `r.rolname !~ '^pg_'` {
+		t.Errorf(`\du filter = %q`, where)
+	}
+}
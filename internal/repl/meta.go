@@ -0,0 +1,118 @@
+package repl
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/PraveenPrabhuT/rds/internal/core"
+)
+
+const helpText = `General
+  \q                     quit
+  \? or \h               show this help
+  \c[onnect] DBNAME      connect to another database on the same server
+  \conninfo              show the current connection
+  \refresh               reload tab-completion names from the catalog
+
+Informational (options: PATTERN)
+  \l[ist]                list databases
+  \dn                    list schemas
+  \dt, \di, \dv          list tables, indexes, views
+  \d [NAME]              list relations, or describe table, view or index
+  \df                    list functions
+  \du                    list roles
+  \dp                    list table, view and sequence access privileges
+  \dx                    list installed extensions
+
+Formatting
+  \x [on|off]            toggle expanded output
+  \timing [on|off]       toggle timing of statements
+
+Statements may span several lines and run when terminated by ";".
+Ctrl+C cancels the running statement; twice at an empty prompt exits.
+History is kept in %s.
+`
+
+// meta runs a backslash command and reports whether the REPL should exit.
+func (s *session) meta(ctx context.Context, line string) bool {
+	fields := strings.Fields(line)
+	cmd, args := fields[0], fields[1:]
+
+	switch cmd {
+	case `\q`:
+		return true
+	case `\?`, `\h`:
+		fmt.Fprintf(s.out, helpText, HistoryFile())
+	case `\x`:
+		if v, ok := toggle(s.expanded, args); ok {
+			s.expanded = v
+			fmt.Fprintf(s.out, "Expanded display is %s.\n", onOff(v))
+		} else {
+			fmt.Fprintf(s.out, "\\x: unrecognized value %q: Boolean expected\n", args[0])
+		}
+	case `\timing`:
+		if v, ok := toggle(s.timing, args); ok {
+			s.timing = v
+			fmt.Fprintf(s.out, "Timing is %s.\n", onOff(v))
+		} else {
+			fmt.Fprintf(s.out, "\\timing: unrecognized value %q: Boolean expected\n", args[0])
+		}
+	case `\c`, `\connect`:
+		if len(args) == 0 {
+			fmt.Fprintf(s.out, "You are connected to database %q as user %q.\n", s.opts.DB, s.opts.User)
+			return false
+		}
+		s.reconnect(ctx, args[0])
+	case `\l`, `\list`, `\dn`, `\d`, `\dt`, `\di`, `\dv`, `\df`, `\du`, `\dp`, `\dx`:
+		s.describe(ctx, cmd, args)
+	case `\refresh`:
+		if err := s.complete.refresh(); err != nil {
+			printError(s.out, err)
+			return false
+		}
+		fmt.Fprintln(s.out, "Completion cache refreshed.")
+	case `\conninfo`:
+		fmt.Fprintf(s.out, "You are connected to database %q as user %q on host %q at port \"%d\".\n",
+			s.opts.DB, s.opts.User, s.opts.Host, s.opts.Port)
+	default:
+		fmt.Fprintf(s.out, "invalid command %s\nTry \\? for help.\n", cmd)
+	}
+	return false
+}
+
+// reconnect switches the session to dbname, keeping the old connection if
+// the new one cannot be established.
+func (s *session) reconnect(ctx context.Context, dbname string) {
+	conn, err := core.NewPgxSession(ctx, s.opts.Host, s.opts.Port, s.opts.User, s.opts.Password, dbname, core.SessionOptions{ReadOnly: s.opts.ReadOnly})
+	if err != nil {
+		fmt.Fprintf(s.out, "%v\nPrevious connection kept\n", err)
+		return
+	}
+	s.conn.Close(ctx)
+	s.conn = conn
+	s.opts.DB = dbname
+	fmt.Fprintf(s.out, "You are now connected to database %q as user %q.\n", dbname, s.opts.User)
+}
+
+// toggle interprets an optional on/off argument, flipping current when
+// none is given.
+func toggle(current bool, args []string) (bool, bool) {
+	if len(args) == 0 {
+		return !current, true
+	}
+	switch strings.ToLower(args[0]) {
+	case "on", "true", "yes", "1":
+		return true, true
+	case "off", "false", "no", "0":
+		return false, true
+	}
+	return current, false
+}
+
+func onOff(v bool) string {
+	if v {
+		return "on"
+	}
+	return "off"
+}
@@ -0,0 +1,272 @@
+package repl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/PraveenPrabhuT/rds/internal/core"
+	"github.com/PraveenPrabhuT/rds/internal/guard"
+	"github.com/PraveenPrabhuT/rds/internal/render"
+	"github.com/chzyer/readline"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Options configures a native REPL session.
+type Options struct {
+	Host     string
+	Port     int32
+	User     string
+	Password string
+	DB       string
+	// ReadOnly starts the session with default_transaction_read_only=on.
+	ReadOnly bool
+	// Guard asks for the database name before running DROP, TRUNCATE or
+	// DELETE/UPDATE without WHERE.
+	Guard bool
+	// Label prefixes the prompt, e.g. "[PROD profile/instance]", in the
+	// SGR color LabelColor when set.
+	Label      string
+	LabelColor string
+}
+
+// session is the state of one native REPL: the live connection plus the
+// display toggles changed by meta-commands.
+type session struct {
+	opts     Options
+	conn     *pgx.Conn
+	out      io.Writer
+	expanded bool
+	timing   bool
+	table    render.TableOptions
+	complete *completer
+}
+
+// HistoryFile returns the path of the persistent REPL history.
+func HistoryFile() string {
+	return filepath.Join(core.GetCacheDir(), "native_history")
+}
+
+// Run connects and runs the interactive loop until \q, exit/quit or EOF.
+func Run(ctx context.Context, opts Options) error {
+	conn, err := core.NewPgxSession(ctx, opts.Host, opts.Port, opts.User, opts.Password, opts.DB, core.SessionOptions{ReadOnly: opts.ReadOnly})
+	if err != nil {
+		return err
+	}
+	s := &session{opts: opts, conn: conn, out: os.Stdout, table: render.DefaultTableOptions}
+	defer func() { s.conn.Close(context.Background()) }()
+
+	var sc scanner
+	s.complete = newCompleter(s, &sc)
+
+	os.MkdirAll(core.GetCacheDir(), 0755)
+	rl, err := readline.NewEx(&readline.Config{
+		Prompt:                 s.prompt(&sc),
+		AutoComplete:           s.complete,
+		HistoryFile:            HistoryFile(),
+		DisableAutoSaveHistory: true,
+		InterruptPrompt:        "^C",
+	})
+	if err != nil {
+		return fmt.Errorf("init readline: %w", err)
+	}
+	defer rl.Close()
+
+	fmt.Printf("✅ Connected to %s (Native Mode)\n", opts.Host)
+	fmt.Println(`Type \? for help, \q to quit. Statements end with ";".`)
+
+	interrupted := false
+	for {
+		rl.SetPrompt(s.prompt(&sc))
+		line, err := rl.Readline()
+		if errors.Is(err, readline.ErrInterrupt) {
+			// Ctrl+C discards a partial statement; a second one at an
+			// empty prompt leaves the REPL.
+			if sc.pending() || line != "" {
+				sc.reset()
+				interrupted = false
+				continue
+			}
+			if interrupted {
+				break
+			}
+			interrupted = true
+			fmt.Fprintln(s.out, `(press Ctrl+C again to exit, or type \q)`)
+			continue
+		}
+		if err != nil {
+			break
+		}
+		interrupted = false
+
+		trimmed := strings.TrimSpace(line)
+		if !sc.pending() {
+			if trimmed == "" {
+				continue
+			}
+			if trimmed == "exit" || trimmed == "quit" {
+				break
+			}
+			if strings.HasPrefix(trimmed, `\`) {
+				rl.SaveHistory(trimmed)
+				quit := false
+				s.interruptible(func() { quit = s.meta(ctx, trimmed) })
+				if quit {
+					break
+				}
+				continue
+			}
+		}
+
+		for _, stmt := range sc.feed(line) {
+			rl.SaveHistory(stmt + ";")
+			if !s.allow(rl, stmt) {
+				fmt.Fprintln(s.out, "Cancelled.")
+				continue
+			}
+			s.interruptible(func() { s.execute(ctx, stmt) })
+		}
+	}
+	return nil
+}
+
+// allow runs the destructive-statement guard: on a guarded session the user
+// must type the database name before stmt runs.
+func (s *session) allow(rl *readline.Instance, stmt string) bool {
+	if !s.opts.Guard {
+		return true
+	}
+	reasons := guard.Check(stmt)
+	if len(reasons) == 0 {
+		return true
+	}
+	fmt.Fprintln(s.out, guard.Warning(reasons))
+	rl.SetPrompt(guard.Prompt(s.opts.DB))
+	line, err := rl.Readline()
+	return err == nil && strings.TrimSpace(line) == s.opts.DB
+}
+
+// prompt renders the psql-style prompt: db=> when idle, db=*> inside a
+// transaction, db=!> in a failed transaction and db-> (or the open quote
+// character) on continuation lines. Superusers get # instead of >.
+func (s *session) prompt(sc *scanner) string {
+	marker := ">"
+	if s.conn.PgConn().ParameterStatus("is_superuser") == "on" {
+		marker = "#"
+	}
+	if sc.pending() {
+		return s.label() + s.opts.DB + sc.promptChar() + marker + " "
+	}
+	status := ""
+	switch s.conn.PgConn().TxStatus() {
+	case 'T':
+		status = "*"
+	case 'E':
+		status = "!"
+	}
+	return s.label() + s.opts.DB + "=" + status + marker + " "
+}
+
+// label returns the environment label that starts the prompt, if any.
+func (s *session) label() string {
+	switch {
+	case s.opts.Label == "":
+		return ""
+	case s.opts.LabelColor == "":
+		return s.opts.Label + " "
+	}
+	return "\x1b[" + s.opts.LabelColor + "m" + s.opts.Label + "\x1b[0m "
+}
+
+// execute runs sql with the simple query protocol so results arrive in
+// text format, exactly as psql would show them, and prints each result.
+func (s *session) execute(ctx context.Context, sql string) {
+	start := time.Now()
+	mrr := s.conn.PgConn().Exec(ctx, sql)
+	for mrr.NextResult() {
+		res, tag, err := collect(mrr.ResultReader())
+		if err != nil {
+			break
+		}
+		s.printResult(res, tag)
+	}
+	if err := mrr.Close(); err != nil {
+		printError(s.out, err)
+	}
+	if s.timing {
+		fmt.Fprintf(s.out, "Time: %.3f ms\n", float64(time.Since(start).Microseconds())/1000)
+	}
+}
+
+func (s *session) printResult(res render.Result, tag pgconn.CommandTag) {
+	if len(res.Columns) == 0 {
+		fmt.Fprintln(s.out, tag.String())
+		return
+	}
+	if s.expanded {
+		render.Expanded(s.out, res, s.table)
+	} else {
+		render.Table(s.out, res, s.table)
+	}
+	fmt.Fprintln(s.out)
+}
+
+// query runs a single statement with text parameters, as the describe
+// commands do, and buffers its result.
+func (s *session) query(ctx context.Context, sql string, args ...string) (render.Result, error) {
+	params := make([][]byte, len(args))
+	for i, a := range args {
+		params[i] = []byte(a)
+	}
+	res, _, err := collect(s.conn.PgConn().ExecParams(ctx, sql, params, nil, nil, nil))
+	return res, err
+}
+
+// collect buffers one result set.
+func collect(rr *pgconn.ResultReader) (render.Result, pgconn.CommandTag, error) {
+	var res render.Result
+	for _, fd := range rr.FieldDescriptions() {
+		res.Columns = append(res.Columns, render.Column{Name: fd.Name, TypeOID: fd.DataTypeOID})
+	}
+	for rr.NextRow() {
+		res.Rows = append(res.Rows, copyRow(rr.Values()))
+	}
+	tag, err := rr.Close()
+	return res, tag, err
+}
+
+// copyRow copies a row out of the reader's buffer, which is reused for the
+// next row. A nil value is NULL.
+func copyRow(values [][]byte) []*string {
+	row := make([]*string, len(values))
+	for i, v := range values {
+		if v != nil {
+			text := string(v)
+			row[i] = &text
+		}
+	}
+	return row
+}
+
+// printError prints a server error the way psql does, including DETAIL and
+// HINT lines.
+func printError(w io.Writer, err error) {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		fmt.Fprintf(w, "ERROR:  %v\n", err)
+		return
+	}
+	fmt.Fprintf(w, "%s:  %s\n", pgErr.Severity, pgErr.Message)
+	if pgErr.Detail != "" {
+		fmt.Fprintf(w, "DETAIL:  %s\n", pgErr.Detail)
+	}
+	if pgErr.Hint != "" {
+		fmt.Fprintf(w, "HINT:  %s\n", pgErr.Hint)
+	}
+}
@@ -0,0 +1,162 @@
+package repl
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/PraveenPrabhuT/rds/internal/guard"
+)
+
+type lexState int
+
+const (
+	stateNormal  lexState = iota
+	stateSingle           // '...' string literal
+	stateDouble           // "..." quoted identifier
+	stateDollar           // $tag$...$tag$ body
+	stateComment          // /* ... */ block comment (nestable)
+)
+
+// scanner accumulates input lines and splits them into statements on
+// semicolons that are outside quotes, comments, dollar-quoted bodies and
+// parentheses, the way psql does.
+type scanner struct {
+	buf       strings.Builder
+	state     lexState
+	escape    bool // inside an E'...' literal, where \' does not terminate
+	dollarTag string
+	depth     int // block comment nesting
+	parens    int
+}
+
+// feed appends one input line and returns the statements it completed,
+// without their terminating semicolons.
+func (s *scanner) feed(line string) []string {
+	var stmts []string
+	rs := []rune(line + "\n")
+	for i := 0; i < len(rs); i++ {
+		c := rs[i]
+		next := rune(0)
+		if i+1 < len(rs) {
+			next = rs[i+1]
+		}
+
+		switch s.state {
+		case stateNormal:
+			switch {
+			case c == '-' && next == '-':
+				// Line comment: drop the rest of the line.
+				i = len(rs) - 2
+				continue
+			case c == '/' && next == '*':
+				s.state, s.depth = stateComment, 1
+				s.buf.WriteString("/*")
+				i++
+				continue
+			case c == '\'':
+				s.state = stateSingle
+				s.escape = isEscapePrefix(rs, i)
+			case c == '"':
+				s.state = stateDouble
+			case c == '$':
+				if tag, ok := guard.DollarTag(rs, i); ok {
+					s.state, s.dollarTag = stateDollar, tag
+					s.buf.WriteString(tag)
+					i += len([]rune(tag)) - 1
+					continue
+				}
+			case c == '(':
+				s.parens++
+			case c == ')':
+				if s.parens > 0 {
+					s.parens--
+				}
+			case c == ';' && s.parens == 0:
+				if stmt := strings.TrimSpace(s.buf.String()); stmt != "" {
+					stmts = append(stmts, stmt)
+				}
+				s.buf.Reset()
+				continue
+			}
+		case stateSingle:
+			if s.escape && c == '\\' && next != 0 {
+				s.buf.WriteRune(c)
+				s.buf.WriteRune(next)
+				i++
+				continue
+			}
+			if c == '\'' {
+				s.state = stateNormal
+			}
+		case stateDouble:
+			if c == '"' {
+				s.state = stateNormal
+			}
+		case stateDollar:
+			if c == '$' && strings.HasPrefix(string(rs[i:]), s.dollarTag) {
+				s.buf.WriteString(s.dollarTag)
+				i += len([]rune(s.dollarTag)) - 1
+				s.state, s.dollarTag = stateNormal, ""
+				continue
+			}
+		case stateComment:
+			if c == '/' && next == '*' {
+				s.depth++
+				s.buf.WriteString("/*")
+				i++
+				continue
+			}
+			if c == '*' && next == '/' {
+				s.depth--
+				s.buf.WriteString("*/")
+				i++
+				if s.depth == 0 {
+					s.state = stateNormal
+				}
+				continue
+			}
+		}
+		s.buf.WriteRune(c)
+	}
+	return stmts
+}
+
+// pending reports whether an unterminated statement is buffered.
+func (s *scanner) pending() bool {
+	return s.state != stateNormal || strings.TrimSpace(s.buf.String()) != ""
+}
+
+func (s *scanner) reset() {
+	*s = scanner{}
+}
+
+// promptChar returns the psql continuation prompt marker for the current
+// state: ' " $ * ( or - for a plain unfinished statement.
+func (s *scanner) promptChar() string {
+	switch s.state {
+	case stateSingle:
+		return "'"
+	case stateDouble:
+		return `"`
+	case stateDollar:
+		return "$"
+	case stateComment:
+		return "*"
+	}
+	if s.parens > 0 {
+		return "("
+	}
+	return "-"
+}
+
+// isEscapePrefix reports whether the quote at rs[i] opens an E'...' literal.
+func isEscapePrefix(rs []rune, i int) bool {
+	if i == 0 || (rs[i-1] != 'E' && rs[i-1] != 'e') {
+		return false
+	}
+	return i < 2 || !isIdentRune(rs[i-2])
+}
+
+func isIdentRune(c rune) bool {
+	return unicode.IsLetter(c) || unicode.IsDigit(c) || c == '_' || c == '$'
+}
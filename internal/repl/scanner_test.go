@@ -0,0 +1,91 @@
+package repl
+
+import (
+	"reflect"
+	"testing"
+)
+
+func feedAll(lines ...string) ([]string, *scanner) {
+	var sc scanner
+	var stmts []string
+	for _, l := range lines {
+		stmts = append(stmts, sc.feed(l)...)
+	}
+	return stmts, &sc
+}
+
+func TestScanner_SplitsStatements(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  []string
+	}{
+		{"single", []string{"select 1;"}, []string{"select 1"}},
+		{"two on one line", []string{"select 1; select 2;"}, []string{"select 1", "select 2"}},
+		{"multi-line", []string{"select *", "from t", "where id = 1;"}, []string{"select *\nfrom t\nwhere id = 1"}},
+		{"semicolon in string", []string{"select 'a;b';"}, []string{"select 'a;b'"}},
+		{"doubled quote", []string{"select 'it''s;';"}, []string{"select 'it''s;'"}},
+		{"escape string", []string{`select E'a\';b';`}, []string{`select E'a\';b'`}},
+		{"quoted identifier", []string{`select 1 as "x;y";`}, []string{`select 1 as "x;y"`}},
+		{"line comment", []string{"select 1 -- trailing; comment", ";"}, []string{"select 1"}},
+		{"block comment", []string{"select /* ; /* nested ; */ */ 1;"}, []string{"select /* ; /* nested ; */ */ 1"}},
+		{"dollar quote", []string{"do $$ begin", "perform 1;", "end $$;"}, []string{"do $$ begin\nperform 1;\nend $$"}},
+		{"tagged dollar quote", []string{"select $fn$ ; $$ ; $fn$;"}, []string{"select $fn$ ; $$ ; $fn$"}},
+		{"positional param", []string{"prepare p as select $1;"}, []string{"prepare p as select $1"}},
+		{"parens", []string{"create rule r as on insert to t do (select 1; select 2);"}, []string{"create rule r as on insert to t do (select 1; select 2)"}},
+		{"empty statements", []string{";;", "select 1;"}, []string{"select 1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, sc := feedAll(tt.lines...)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("statements:\n  got  %q\n  want %q", got, tt.want)
+			}
+			if sc.pending() {
+				t.Errorf("scanner should have nothing pending, buffer %q", sc.buf.String())
+			}
+		})
+	}
+}
+
+func TestScanner_PendingAndPromptChar(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"select 1", "-"},
+		{"select 'abc", "'"},
+		{`select "abc`, `"`},
+		{"do $$ begin", "$"},
+		{"select /* comment", "*"},
+		{"select (1", "("},
+	}
+	for _, tt := range tests {
+		_, sc := feedAll(tt.line)
+		if !sc.pending() {
+			t.Errorf("%q: expected pending input", tt.line)
+		}
+		if got := sc.promptChar(); got != tt.want {
+			t.Errorf("%q: promptChar got %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestScanner_CommentOnlyIsNotPending(t *testing.T) {
+	_, sc := feedAll("-- just a comment")
+	if sc.pending() {
+		t.Error("comment-only input should not be pending")
+	}
+}
+
+func TestToggle(t *testing.T) {
+	if v, ok := toggle(false, nil); !ok || !v {
+		t.Errorf("toggle(false): got %v, %v", v, ok)
+	}
+	if v, ok := toggle(true, []string{"off"}); !ok || v {
+		t.Errorf("toggle(true, off): got %v, %v", v, ok)
+	}
+	if _, ok := toggle(true, []string{"maybe"}); ok {
+		t.Error("toggle(maybe): expected failure")
+	}
+}
@@ -0,0 +1,155 @@
+package whoami
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/PraveenPrabhuT/rds/internal/core"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// Options configures a whoami run.
+type Options struct {
+	Profile string
+	Region  string
+}
+
+// RequiredActions lists the IAM actions the rds CLI calls on behalf of the user.
+var RequiredActions = []string{
+	"rds:DescribeDBInstances",
+	"secretsmanager:GetSecretValue",
+	"secretsmanager:CreateSecret",
+	"rds-db:connect",
+}
+
+// ActionResult holds the simulated decision for one IAM action.
+type ActionResult struct {
+	Action   string
+	Decision string // "allowed", "explicitDeny", "implicitDeny"
+}
+
+// Run prints the caller identity, resolved regions, mapped VPN and the
+// policy simulation result for every action in RequiredActions.
+func Run(ctx context.Context, opts Options) error {
+	cfg, homeRegion, err := core.LoadAWSConfig(ctx, opts.Profile, opts.Region)
+	if err != nil {
+		return err
+	}
+
+	ident, err := sts.NewFromConfig(cfg).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return fmt.Errorf("get caller identity: %w", err)
+	}
+	callerARN := aws.ToString(ident.Arn)
+
+	fmt.Println()
+	fmt.Println("=== Identity ===")
+	fmt.Printf("  Profile:      %s\n", opts.Profile)
+	fmt.Printf("  Account:      %s\n", aws.ToString(ident.Account))
+	fmt.Printf("  ARN:          %s\n", callerARN)
+	fmt.Printf("  User ID:      %s\n", aws.ToString(ident.UserId))
+	fmt.Printf("  Region:       %s\n", cfg.Region)
+	fmt.Printf("  Home region:  %s\n", homeRegion)
+	fmt.Printf("  VPN:          %s\n", vpnStatus(opts.Profile))
+	fmt.Println()
+
+	results, err := simulate(ctx, cfg, callerARN)
+	if err != nil {
+		fmt.Printf("⚠️  Policy simulation unavailable: %v\n\n", err)
+		return nil
+	}
+	printResults(results)
+	return nil
+}
+
+// vpnStatus describes the Pritunl VPN mapped to the profile and whether the
+// current connection state satisfies it.
+func vpnStatus(profile string) string {
+	vpn, ok := core.RequiredVPN(profile)
+	if !ok {
+		vpn = "(no mapping, any connection)"
+	}
+	if err := core.CheckVPNWithPritunl(profile); err != nil {
+		return fmt.Sprintf("%s ⚠️  %v", vpn, err)
+	}
+	return vpn
+}
+
+// simulate runs IAM policy simulation for RequiredActions against the
+// principal behind callerARN.
+func simulate(ctx context.Context, cfg aws.Config, callerARN string) ([]ActionResult, error) {
+	client := iam.NewFromConfig(cfg)
+
+	sourceARN := callerARN
+	if roleName, ok := assumedRoleName(callerARN); ok {
+		// Role paths (e.g. /aws-reserved/sso.amazonaws.com/) are not part of
+		// the session ARN, so look up the real role ARN.
+		out, err := client.GetRole(ctx, &iam.GetRoleInput{RoleName: &roleName})
+		if err != nil {
+			return nil, fmt.Errorf("get role %q: %w", roleName, err)
+		}
+		sourceARN = aws.ToString(out.Role.Arn)
+	}
+
+	out, err := client.SimulatePrincipalPolicy(ctx, &iam.SimulatePrincipalPolicyInput{
+		PolicySourceArn: &sourceARN,
+		ActionNames:     RequiredActions,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("simulate principal policy for %s: %w", sourceARN, err)
+	}
+	return collectResults(out.EvaluationResults), nil
+}
+
+// collectResults orders evaluation results by RequiredActions. Actions the
+// simulator did not report are returned as implicitDeny.
+func collectResults(evals []iamtypes.EvaluationResult) []ActionResult {
+	decisions := make(map[string]string, len(evals))
+	for _, e := range evals {
+		decisions[aws.ToString(e.EvalActionName)] = string(e.EvalDecision)
+	}
+
+	results := make([]ActionResult, 0, len(RequiredActions))
+	for _, action := range RequiredActions {
+		decision, ok := decisions[action]
+		if !ok {
+			decision = string(iamtypes.PolicyEvaluationDecisionTypeImplicitDeny)
+		}
+		results = append(results, ActionResult{Action: action, Decision: decision})
+	}
+	return results
+}
+
+// assumedRoleName extracts the role name from an STS assumed-role ARN
+// (arn:aws:sts::123456789012:assumed-role/RoleName/session).
+func assumedRoleName(arn string) (string, bool) {
+	parts := strings.SplitN(arn, ":", 6)
+	if len(parts) != 6 || parts[2] != "sts" {
+		return "", false
+	}
+	resource := strings.Split(parts[5], "/")
+	if len(resource) < 2 || resource[0] != "assumed-role" || resource[1] == "" {
+		return "", false
+	}
+	return resource[1], true
+}
+
+func printResults(results []ActionResult) {
+	fmt.Println("=== Permissions ===")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, r := range results {
+		icon := "❌"
+		if r.Decision == string(iamtypes.PolicyEvaluationDecisionTypeAllowed) {
+			icon = "✅"
+		}
+		fmt.Fprintf(w, "  %s %s\t%s\n", icon, r.Action, r.Decision)
+	}
+	w.Flush()
+	fmt.Println()
+}
@@ -0,0 +1,58 @@
+package whoami
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+)
+
+func TestAssumedRoleName(t *testing.T) {
+	tests := []struct {
+		name   string
+		arn    string
+		want   string
+		wantOK bool
+	}{
+		{"SSO session", "arn:aws:sts::123456789012:assumed-role/AWSReservedSSO_Dev_abc/jane@example.com", "AWSReservedSSO_Dev_abc", true},
+		{"plain role", "arn:aws:sts::123456789012:assumed-role/deployer/ci", "deployer", true},
+		{"IAM user", "arn:aws:iam::123456789012:user/jane", "", false},
+		{"root", "arn:aws:iam::123456789012:root", "", false},
+		{"federated user", "arn:aws:sts::123456789012:federated-user/jane", "", false},
+		{"empty", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := assumedRoleName(tt.arn)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("assumedRoleName(%q) = %q, %v; want %q, %v", tt.arn, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestCollectResults_OrderAndMissing(t *testing.T) {
+	evals := []iamtypes.EvaluationResult{
+		{EvalActionName: aws.String("rds-db:connect"), EvalDecision: iamtypes.PolicyEvaluationDecisionTypeExplicitDeny},
+		{EvalActionName: aws.String("rds:DescribeDBInstances"), EvalDecision: iamtypes.PolicyEvaluationDecisionTypeAllowed},
+	}
+
+	got := collectResults(evals)
+	if len(got) != len(RequiredActions) {
+		t.Fatalf("collectResults: got %d results, want %d", len(got), len(RequiredActions))
+	}
+	want := map[string]string{
+		"rds:DescribeDBInstances":       "allowed",
+		"secretsmanager:GetSecretValue": "implicitDeny",
+		"secretsmanager:CreateSecret":   "implicitDeny",
+		"rds-db:connect":                "explicitDeny",
+	}
+	for i, r := range got {
+		if r.Action != RequiredActions[i] {
+			t.Errorf("collectResults[%d]: action %q, want %q", i, r.Action, RequiredActions[i])
+		}
+		if r.Decision != want[r.Action] {
+			t.Errorf("collectResults %s: decision %q, want %q", r.Action, r.Decision, want[r.Action])
+		}
+	}
+}